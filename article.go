@@ -0,0 +1,78 @@
+package newsapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// truncationMarker matches the "[+1234 chars]" suffix the API appends to the content of an article
+// when it has been cut off, together with the ellipsis which usually precedes it.
+var truncationMarker = regexp.MustCompile(`(…|\.\.\.)?\s*\[\+(\d+) chars\]\s*$`)
+
+// ID returns a stable identifier for the article. It's the hex encoded SHA-256 hash of the article's URL
+// (or of the source name, title and publishing date if the URL is missing), so the same article always
+// gets the same ID regardless of when or through which route it has been fetched.
+func (a Article) ID() string {
+	key := a.URL
+	if key == "" {
+		key = a.Source.Name + "\n" + a.Title + "\n" + a.PublishedAt.UTC().Format(time.RFC3339)
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Host returns the lower cased host name of the article's URL without the port. An empty string is
+// returned if the URL cannot be parsed.
+func (a Article) Host() string {
+	u, err := url.Parse(a.URL)
+	if err != nil {
+		return ""
+	}
+
+	return strings.ToLower(u.Hostname())
+}
+
+// Age returns how long before now the article has been published. It returns 0 if the article
+// doesn't have a publishing date.
+func (a Article) Age(now time.Time) time.Duration {
+	if a.PublishedAt.IsZero() {
+		return 0
+	}
+
+	return now.Sub(a.PublishedAt)
+}
+
+// ContentTruncated reports whether the content of the article has been cut off by the API. The free
+// plans of the API only return the first ~200 characters of an article.
+func (a Article) ContentTruncated() bool {
+	return truncationMarker.MatchString(a.Content)
+}
+
+// RemainingChars returns the number of characters which have been cut off from the content of the article
+// according to the "[+1234 chars]" marker at the end of it. It returns 0 if the content isn't truncated.
+func (a Article) RemainingChars() int {
+	m := truncationMarker.FindStringSubmatch(a.Content)
+	if m == nil {
+		return 0
+	}
+
+	n, err := strconv.Atoi(m[2])
+	if err != nil {
+		return 0
+	}
+
+	return n
+}
+
+// ContentText returns the content of the article without the "[+1234 chars]" marker and the ellipsis in
+// front of it, so it can be processed as plain text. The content is returned unchanged apart from
+// surrounding whitespace if it isn't truncated.
+func (a Article) ContentText() string {
+	return strings.TrimSpace(truncationMarker.ReplaceAllString(a.Content, ""))
+}
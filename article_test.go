@@ -0,0 +1,106 @@
+package newsapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestArticleID(t *testing.T) {
+	a := Article{URL: "https://example.com/a"}
+	b := Article{URL: "https://example.com/b"}
+
+	if a.ID() != a.ID() {
+		t.Error("Expected the ID of an article to be stable")
+	}
+
+	if a.ID() == b.ID() {
+		t.Error("Expected articles with different URLs to have different IDs")
+	}
+
+	if len(a.ID()) != 64 {
+		t.Errorf("Expected a hex encoded SHA-256 hash but got %s", a.ID())
+	}
+}
+
+func TestArticleHost(t *testing.T) {
+	cases := []struct {
+		url, host string
+	}{
+		{"https://www.BBC.co.uk/news/world-123", "www.bbc.co.uk"},
+		{"http://localhost:3000/a", "localhost"},
+		{"", ""},
+		{"%zz", ""},
+	}
+
+	for _, i := range cases {
+		host := Article{URL: i.url}.Host()
+		if host != i.host {
+			t.Errorf("Expected %s but got %s when case=%v", i.host, host, i.url)
+		}
+	}
+}
+
+func TestArticleAge(t *testing.T) {
+	now := time.Date(2020, 6, 18, 12, 0, 0, 0, time.UTC)
+
+	a := Article{PublishedAt: now.Add(-3 * time.Hour)}
+	if age := a.Age(now); age != 3*time.Hour {
+		t.Errorf("Expected 3h but got %v", age)
+	}
+
+	if age := (Article{}).Age(now); age != 0 {
+		t.Errorf("Expected 0 for an article without a publishing date but got %v", age)
+	}
+}
+
+func TestArticleTruncation(t *testing.T) {
+	cases := []struct {
+		content   string
+		truncated bool
+		remaining int
+		text      string
+	}{
+		{"The quick brown fox jumps over the... [+1234 chars]", true, 1234, "The quick brown fox jumps over the"},
+		{"The quick brown fox jumps over the… [+5 chars] ", true, 5, "The quick brown fox jumps over the"},
+		{"The quick brown fox jumps over the [+5 chars]", true, 5, "The quick brown fox jumps over the"},
+		{"The quick brown fox jumps over the lazy dog...", false, 0, "The quick brown fox jumps over the lazy dog..."},
+		{"[+12 chars] in the middle of the content", false, 0, "[+12 chars] in the middle of the content"},
+		{"", false, 0, ""},
+	}
+
+	for _, i := range cases {
+		a := Article{Content: i.content}
+
+		if a.ContentTruncated() != i.truncated {
+			t.Errorf("Expected %v but got %v when case=%v", i.truncated, a.ContentTruncated(), i.content)
+		}
+
+		if a.RemainingChars() != i.remaining {
+			t.Errorf("Expected %d but got %d when case=%v", i.remaining, a.RemainingChars(), i.content)
+		}
+
+		if a.ContentText() != i.text {
+			t.Errorf("Expected %q but got %q when case=%v", i.text, a.ContentText(), i.content)
+		}
+	}
+}
+
+// the exported types have to keep the exact json shape of the API
+func TestArticleJSON(t *testing.T) {
+	raw := `{"source":{"id":"bbc-news","name":"BBC News"},"author":"BBC","title":"Title","description":"Description","url":"https://www.bbc.co.uk/news","urlToImage":"https://www.bbc.co.uk/a.jpg","publishedAt":"2020-06-18T12:00:00Z","content":"Content"}`
+
+	var a Article
+	if err := json.Unmarshal([]byte(raw), &a); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != raw {
+		t.Errorf("Expected %s but got %s", raw, b)
+	}
+}
@@ -0,0 +1,296 @@
+package newsapi
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Author represents a single author parsed from the free-form Author field of an article.
+type Author struct {
+	Name string `json:"name"`
+	// Role is the role of the author if it's mentioned, e.g. "Senior Correspondent".
+	Role string `json:"role,omitempty"`
+	// Affiliation is the outlet or agency the author writes for if it's mentioned, e.g. "Reuters".
+	Affiliation string `json:"affiliation,omitempty"`
+	// URL is the URL of the author's page if the field contained one.
+	URL string `json:"url,omitempty"`
+}
+
+var (
+	authorPrefix    = regexp.MustCompile(`(?i)^\s*(written\s+by|posted\s+by|by|von|par|por|door|av|af)\b[\s:]*`)
+	authorEmail     = regexp.MustCompile(`\(?<?[\w.+-]+@[\w-]+(\.[\w-]+)+>?\)?`)
+	authorHandle    = regexp.MustCompile(`\(?@\w+\)?`)
+	authorURL       = regexp.MustCompile(`https?://\S+`)
+	authorParens    = regexp.MustCompile(`\s*\(([^)]*)\)`)
+	authorStaff     = regexp.MustCompile(`(?i)\s+(staff|wire|editors|newsroom|desk|reporters)$`)
+	authorPage      = regexp.MustCompile(`(?i)/(author|authors|autor|auteur|people|profile|journalist|staff|by)/$`)
+	authorFor       = regexp.MustCompile(`(?i)^(.+?)\s+(?:for|für|pour|para)\s+(.+)$`)
+	authorSeparator = regexp.MustCompile(`(?i)\s*(?:,|;|\||\s-\s|\s–\s|\s—\s|\s&\s|\sand\s|\sund\s|\swith\s)\s*`)
+	// conjunctions which are also part of compound surnames like "José Ortega y Gasset"
+	authorConjunction = regexp.MustCompile(`(?i)\s+(?:et|y|e)\s+`)
+
+	// agencies and outlets which often show up in the author field
+	authorOutlets = map[string]bool{
+		"reuters": true, "associated press": true, "ap": true, "the associated press": true, "afp": true,
+		"agence france-presse": true, "dpa": true, "bloomberg": true, "bloomberg news": true, "upi": true,
+		"pa": true, "pa media": true, "press association": true, "efe": true, "ansa": true, "xinhua": true,
+		"cnn": true, "bbc": true, "bbc news": true, "cnbc": true, "abc news": true, "nbc news": true, "cbs news": true,
+		"fox news": true, "npr": true, "al jazeera": true, "sky news": true, "the guardian": true, "the new york times": true,
+	}
+
+	// words which make a segment the role of the author before it
+	authorRoles = regexp.MustCompile(`(?i)\b(correspondent|reporter|editor|writer|columnist|contributor|analyst|journalist|staff|producer|photographer|anchor|host|chief|bureau|redakteur|redakteurin|korrespondent|korrespondentin|journaliste|rédacteur|periodista|corresponsal|redactor)\b`)
+
+	// authors which aren't people
+	authorPlaceholders = map[string]bool{
+		"staff": true, "admin": true, "editor": true, "editors": true, "unknown": true, "anonymous": true,
+		"news desk": true, "newsdesk": true, "web desk": true, "staff reporter": true, "staff writer": true,
+		"redaktion": true, "la rédaction": true, "redacción": true,
+	}
+)
+
+// ParseAuthors parses the free-form Author field of an article. "By" prefixes, email addresses, social
+// media handles and outlet names are removed and several authors are split up, e.g.
+// "By Jane Doe and John Smith, Reuters" becomes two authors affiliated with Reuters. outlets contains
+// additional names of outlets, like the source name of the article, which are recognized as affiliations.
+func ParseAuthors(s string, outlets ...string) []Author {
+	isOutlet := func(seg string) bool {
+		lower := strings.ToLower(seg)
+		if authorOutlets[lower] {
+			return true
+		}
+		for _, o := range outlets {
+			if o != "" && strings.EqualFold(o, seg) {
+				return true
+			}
+		}
+		return false
+	}
+
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+
+	// a field which only consists of a URL, usually the page of the author
+	if u := authorURL.FindString(s); u == s {
+		name := nameFromURL(u)
+		if name == "" {
+			return nil
+		}
+		return []Author{{Name: name, URL: u}}
+	}
+
+	var link string
+	if u := authorURL.FindString(s); u != "" {
+		link = u
+		s = strings.Replace(s, u, "", 1)
+	}
+
+	// a field which only consists of an email address
+	if m := authorEmail.FindString(s); strings.TrimSpace(m) == s {
+		local := strings.Trim(s, "()<>")
+		local = local[:strings.Index(local, "@")]
+		if !strings.ContainsAny(local, "._") {
+			return nil
+		}
+		s = strings.NewReplacer(".", " ", "_", " ").Replace(local)
+	}
+
+	s = authorEmail.ReplaceAllString(s, "")
+	s = authorHandle.ReplaceAllString(s, "")
+
+	// parentheses often contain the affiliation, e.g. "Jane Doe (Reuters)", otherwise they're treated like a comma
+	var affiliation string
+	s = authorParens.ReplaceAllStringFunc(s, func(m string) string {
+		inner := strings.TrimSpace(authorParens.FindStringSubmatch(m)[1])
+		if isOutlet(inner) {
+			affiliation = inner
+			return ""
+		}
+		return ", " + inner
+	})
+
+	s = authorPrefix.ReplaceAllString(s, "")
+
+	var (
+		authors []Author
+		pending int // the index of the first author without an affiliation
+	)
+
+	for _, seg := range splitAuthors(s) {
+		seg = strings.TrimSpace(authorPrefix.ReplaceAllString(strings.TrimSpace(seg), ""))
+		seg = strings.Trim(seg, " .:-")
+		if seg == "" {
+			continue
+		}
+
+		// "Jane Doe for CNN"
+		if m := authorFor.FindStringSubmatch(seg); m != nil && !authorRoles.MatchString(m[1]) {
+			seg, affiliation = m[1], m[2]
+		}
+
+		switch {
+		case isOutlet(seg), isOutlet(authorStaff.ReplaceAllString(seg, "")):
+			// "Reuters Staff" is only the outlet as well
+			seg = authorStaff.ReplaceAllString(seg, "")
+			for i := pending; i < len(authors); i++ {
+				authors[i].Affiliation = seg
+			}
+			pending = len(authors)
+		case authorRoles.MatchString(seg) && len(authors) > 0 && authors[len(authors)-1].Role == "":
+			authors[len(authors)-1].Role = seg
+		case authorPlaceholders[strings.ToLower(seg)]:
+		case strings.IndexFunc(seg, unicode.IsLetter) < 0:
+		default:
+			authors = append(authors, Author{Name: normalizeName(seg)})
+		}
+
+		if affiliation != "" {
+			for i := pending; i < len(authors); i++ {
+				authors[i].Affiliation = affiliation
+			}
+			pending = len(authors)
+			affiliation = ""
+		}
+	}
+
+	if link != "" && len(authors) == 1 {
+		authors[0].URL = link
+	}
+
+	// the same author is sometimes mentioned twice, e.g. in the name and the email address
+	var unique []Author
+	seen := make(map[string]bool)
+	for _, a := range authors {
+		if k := a.Key(); !seen[k] {
+			seen[k] = true
+			unique = append(unique, a)
+		}
+	}
+
+	return unique
+}
+
+// splitAuthors splits the field into segments which each contain a single author, role or outlet.
+// Segments are only split at conjunctions like " y " if every part is a complete name of at least
+// two words since they're also part of compound surnames.
+func splitAuthors(s string) []string {
+	var segs []string
+	for _, seg := range authorSeparator.Split(s, -1) {
+		parts := authorConjunction.Split(seg, -1)
+		for _, p := range parts {
+			if len(strings.Fields(p)) < 2 {
+				parts = []string{seg}
+				break
+			}
+		}
+		segs = append(segs, parts...)
+	}
+
+	return segs
+}
+
+// Authors parses the Author field of the article with ParseAuthors. The source name of the article
+// is recognized as an affiliation.
+func (a Article) Authors() []Author {
+	return ParseAuthors(a.Author, a.Source.Name)
+}
+
+// Key returns an identity key for the author which stays the same however the name has been written,
+// e.g. "Jane M. Doe", "JANE DOE" and "Jane Döe" all have the key "jane doe". It can be used to group
+// articles by journalist across sources.
+func (a Author) Key() string {
+	loadNFCPrimaries()
+
+	var words []string
+	for _, w := range strings.Fields(strings.ToLower(a.Name)) {
+		w = foldAccents(strings.Trim(w, ".,'’"))
+		// middle initials are left out since they are used inconsistently
+		if len([]rune(w)) <= 1 && len(words) > 0 {
+			continue
+		}
+		if w != "" {
+			words = append(words, w)
+		}
+	}
+
+	return strings.Join(words, " ")
+}
+
+// foldAccents removes the accents of the letters of s.
+func foldAccents(s string) string {
+	var b strings.Builder
+
+	for _, r := range s {
+		switch r {
+		case 'ß':
+			b.WriteString("ss")
+			continue
+		case 'ø':
+			r = 'o'
+		case 'ł':
+			r = 'l'
+		case 'æ':
+			b.WriteString("ae")
+			continue
+		}
+
+		for _, d := range decomposeRune(nil, r) {
+			if !unicode.Is(unicode.Mn, d) {
+				b.WriteRune(d)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// normalizeName collapses the whitespace of the name and title-cases it if it's written in all upper or all lower case.
+func normalizeName(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	if name != strings.ToUpper(name) && name != strings.ToLower(name) {
+		return name
+	}
+
+	words := strings.Fields(strings.ToLower(name))
+	for i, w := range words {
+		runes := []rune(w)
+		runes[0] = unicode.ToUpper(runes[0])
+		// keep hyphenated names like "Smith-Jones" capitalized
+		for j := 1; j < len(runes); j++ {
+			if runes[j-1] == '-' || runes[j-1] == '\'' {
+				runes[j] = unicode.ToUpper(runes[j])
+			}
+		}
+		words[i] = string(runes)
+	}
+
+	return strings.Join(words, " ")
+}
+
+// nameFromURL derives the name of an author from the URL of the author's page, e.g.
+// "https://example.com/author/jane-doe/" becomes "Jane Doe".
+func nameFromURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	dir, base := path.Split(strings.TrimSuffix(u.Path, "/"))
+	if !authorPage.MatchString(dir) {
+		return ""
+	}
+
+	words := strings.FieldsFunc(base, func(r rune) bool {
+		return r == '-' || r == '_' || r == '.' || r == '+'
+	})
+	if len(words) < 2 {
+		return ""
+	}
+
+	return normalizeName(strings.Join(words, " "))
+}
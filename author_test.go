@@ -0,0 +1,75 @@
+package newsapi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAuthors(t *testing.T) {
+	cases := []struct {
+		author   string
+		outlets  []string
+		expected []Author
+	}{
+		{"", nil, nil},
+		{"Jane Doe", nil, []Author{{Name: "Jane Doe"}}},
+		{"By Jane Doe and John Smith, Reuters", nil, []Author{{Name: "Jane Doe", Affiliation: "Reuters"}, {Name: "John Smith", Affiliation: "Reuters"}}},
+		{"Jane Doe, John Smith, Max Mustermann", nil, []Author{{Name: "Jane Doe"}, {Name: "John Smith"}, {Name: "Max Mustermann"}}},
+		{"Jane Doe, Senior Correspondent", nil, []Author{{Name: "Jane Doe", Role: "Senior Correspondent"}}},
+		{"Jane Doe (Reuters)", nil, []Author{{Name: "Jane Doe", Affiliation: "Reuters"}}},
+		{"Jane Doe for CNN", nil, []Author{{Name: "Jane Doe", Affiliation: "CNN"}}},
+		{"Jane Doe | The Daily Planet", []string{"The Daily Planet"}, []Author{{Name: "Jane Doe", Affiliation: "The Daily Planet"}}},
+		{"BBC News", nil, nil},
+		{"Reuters Staff", nil, nil},
+		{"jane.doe@example.com", nil, []Author{{Name: "Jane Doe"}}},
+		{"Jane Doe (jane.doe@example.com)", nil, []Author{{Name: "Jane Doe"}}},
+		{"Jane Doe @janedoe", nil, []Author{{Name: "Jane Doe"}}},
+		{"https://www.example.com/author/jane-doe/", nil, []Author{{Name: "Jane Doe", URL: "https://www.example.com/author/jane-doe/"}}},
+		{"https://www.facebook.com/bbcnews", nil, nil},
+		{"Von Max Mustermann und Erika Musterfrau", nil, []Author{{Name: "Max Mustermann"}, {Name: "Erika Musterfrau"}}},
+		{"Por José Ortega y Gasset", nil, []Author{{Name: "José Ortega y Gasset"}}},
+		{"Maria Souza e Silva", nil, []Author{{Name: "Maria Souza e Silva"}}},
+		{"María López y Juan Pérez", nil, []Author{{Name: "María López"}, {Name: "Juan Pérez"}}},
+		{"Par Jean Dupont et Marie Curie", nil, []Author{{Name: "Jean Dupont"}, {Name: "Marie Curie"}}},
+		{"Di Stefano", nil, []Author{{Name: "Di Stefano"}}},
+		{"Alfredo Di Stefano", nil, []Author{{Name: "Alfredo Di Stefano"}}},
+		{"JANE DOE", nil, []Author{{Name: "Jane Doe"}}},
+		{"Jane Doe, Jane  Doe", nil, []Author{{Name: "Jane Doe"}}},
+	}
+
+	for _, i := range cases {
+		authors := ParseAuthors(i.author, i.outlets...)
+		if !reflect.DeepEqual(authors, i.expected) {
+			t.Errorf("Expected %+v but got %+v when case=%q", i.expected, authors, i.author)
+		}
+	}
+}
+
+func TestArticleAuthors(t *testing.T) {
+	a := Article{Author: "Jane Doe, Example News", Source: ArticleSource{Name: "Example News"}}
+
+	expected := []Author{{Name: "Jane Doe", Affiliation: "Example News"}}
+	if authors := a.Authors(); !reflect.DeepEqual(authors, expected) {
+		t.Errorf("Expected %+v but got %+v", expected, authors)
+	}
+}
+
+func TestAuthorKey(t *testing.T) {
+	cases := []struct {
+		name string
+		key  string
+	}{
+		{"Jane Doe", "jane doe"},
+		{"JANE DOE", "jane doe"},
+		{"Jane M. Doe", "jane doe"},
+		{"Jane Döe", "jane doe"},
+		{"Jürgen Straße", "jurgen strasse"},
+		{"J. Doe", "j doe"},
+	}
+
+	for _, i := range cases {
+		if key := (Author{Name: i.name}).Key(); key != i.key {
+			t.Errorf("Expected %v but got %v when case=%v", i.key, key, i.name)
+		}
+	}
+}
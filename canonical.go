@@ -0,0 +1,256 @@
+package newsapi
+
+import (
+	"net/url"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// URLRules defines how CanonicalURL normalizes the URL of an article. Different URLs which point
+// to the same story should end up with the same canonical URL.
+type URLRules struct {
+	// StripParams lists the query parameters which are removed from the URL. A trailing "*" matches
+	// every parameter with the given prefix, e.g. "utm_*".
+	StripParams []string
+	// StripSubdomains lists the leading subdomains which are removed from the host, e.g. "www" or "m".
+	StripSubdomains []string
+	// ForceHTTPS rewrites http URLs to https.
+	ForceHTTPS bool
+	// StripAMP removes AMP markers like a trailing "/amp" path segment, an ".amp" or ".amp.html" suffix
+	// and the "amp" query parameter.
+	StripAMP bool
+	// StripTrailingSlash removes the trailing slash of the path.
+	StripTrailingSlash bool
+	// StripFragment removes the fragment of the URL.
+	StripFragment bool
+	// SortParams sorts the remaining query parameters so their order doesn't matter.
+	SortParams bool
+}
+
+// DefaultURLRules are the rules used by the CanonicalURL function.
+var DefaultURLRules = URLRules{
+	StripParams: []string{
+		"utm_*", "fbclid", "gclid", "dclid", "msclkid", "mc_cid", "mc_eid",
+		"igshid", "ocid", "cmpid", "ns_campaign", "ns_mchannel", "ns_source", "ns_linkname", "ns_fee",
+	},
+	StripSubdomains:    []string{"www", "m", "mobile", "amp"},
+	ForceHTTPS:         true,
+	StripAMP:           true,
+	StripTrailingSlash: true,
+	StripFragment:      true,
+	SortParams:         true,
+}
+
+// CanonicalURL returns the URL of the article normalized with the DefaultURLRules.
+func CanonicalURL(a Article) string {
+	return DefaultURLRules.Canonical(a.URL)
+}
+
+// Canonical normalizes rawURL according to the rules. The scheme and host are always lower cased and
+// the default port of the scheme is removed. If rawURL cannot be parsed it's returned unchanged.
+func (r URLRules) Canonical(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+
+	// the port is only the default one of the scheme the URL has been given with
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if u.Scheme == "http" && port == "80" || u.Scheme == "https" && port == "443" {
+		port = ""
+	}
+
+	if r.ForceHTTPS && u.Scheme == "http" {
+		u.Scheme = "https"
+	}
+	for _, sub := range r.StripSubdomains {
+		// never strip the subdomain if nothing but the top level domain would be left
+		if strings.HasPrefix(host, sub+".") && strings.Count(host, ".") > 1 {
+			host = strings.TrimPrefix(host, sub+".")
+			break
+		}
+	}
+	u.Host = host
+	if port != "" {
+		u.Host += ":" + port
+	}
+
+	q := u.Query()
+	for key := range q {
+		if r.stripParam(key) || r.StripAMP && strings.EqualFold(key, "amp") {
+			q.Del(key)
+		}
+	}
+
+	p := u.EscapedPath()
+	if r.StripAMP {
+		p = stripAMPPath(p)
+	}
+	if r.StripTrailingSlash && p != "/" {
+		p = strings.TrimSuffix(p, "/")
+	}
+	if p == "/" {
+		p = ""
+	}
+
+	buf := strings.Builder{}
+	buf.WriteString(u.Scheme + "://" + u.Host + p)
+
+	if len(q) > 0 {
+		buf.WriteString("?")
+		if r.SortParams {
+			// Encode sorts by key
+			buf.WriteString(q.Encode())
+		} else {
+			buf.WriteString(keepParams(u.RawQuery, q))
+		}
+	}
+
+	if !r.StripFragment && u.Fragment != "" {
+		buf.WriteString("#" + u.EscapedFragment())
+	}
+
+	return buf.String()
+}
+
+func (r URLRules) stripParam(key string) bool {
+	key = strings.ToLower(key)
+
+	for _, p := range r.StripParams {
+		p = strings.ToLower(p)
+		if strings.HasSuffix(p, "*") && strings.HasPrefix(key, strings.TrimSuffix(p, "*")) || p == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// keepParams returns the parameters of rawQuery which are still part of q in their original order.
+func keepParams(rawQuery string, q url.Values) string {
+	var kept []string
+
+	for _, pair := range strings.Split(rawQuery, "&") {
+		key := pair
+		if i := strings.Index(pair, "="); i >= 0 {
+			key = pair[:i]
+		}
+
+		if k, err := url.QueryUnescape(key); err == nil {
+			if _, ok := q[k]; ok {
+				kept = append(kept, pair)
+			}
+		}
+	}
+
+	return strings.Join(kept, "&")
+}
+
+func stripAMPPath(p string) string {
+	trimmed := strings.TrimSuffix(p, "/")
+
+	switch {
+	case path.Base(trimmed) == "amp":
+		return path.Dir(trimmed)
+	case strings.HasSuffix(trimmed, ".amp.html"):
+		return strings.TrimSuffix(trimmed, ".amp.html") + ".html"
+	case strings.HasSuffix(trimmed, ".amp"):
+		return strings.TrimSuffix(trimmed, ".amp")
+	case strings.HasPrefix(trimmed, "/amp/"):
+		return strings.TrimPrefix(trimmed, "/amp")
+	}
+
+	return p
+}
+
+// DedupeStrategy returns the key articles are grouped by when they are being de-duplicated.
+// Articles with the same key are considered to be the same story.
+type DedupeStrategy func(Article) string
+
+var (
+	// DedupeByURL only merges articles with exactly the same URL.
+	DedupeByURL DedupeStrategy = func(a Article) string { return a.URL }
+	// DedupeByCanonicalURL merges articles with the same canonical URL according to the DefaultURLRules.
+	// Articles whose URL has no host are only merged if they have the same ID.
+	DedupeByCanonicalURL DedupeStrategy = func(a Article) string { return DefaultURLRules.dedupeKey(a) }
+)
+
+// Strategy returns a DedupeStrategy which merges articles with the same canonical URL according to the rules.
+// Articles whose URL has no host are only merged if they have the same ID.
+func (r URLRules) Strategy() DedupeStrategy {
+	return r.dedupeKey
+}
+
+// dedupeKey returns the canonical URL of the article or, if its URL has no host, "id:" followed by the ID
+// of the article, so articles without a URL aren't all merged into one.
+func (r URLRules) dedupeKey(a Article) string {
+	if u, err := url.Parse(strings.TrimSpace(a.URL)); err != nil || u.Host == "" {
+		return "id:" + a.ID()
+	}
+
+	return r.Canonical(a.URL)
+}
+
+// DedupedArticle is the best copy of a story together with the copies which have been merged into it.
+type DedupedArticle struct {
+	Article
+	Key        string    `json:"key"`
+	Alternates []Article `json:"alternates,omitempty"`
+}
+
+// Dedupe merges the articles which have the same key according to the strategy. Of every group of articles
+// the one with the longest content is kept, preferring articles with an image if the content is equally long.
+// The other articles of the group are recorded as alternates. The order of the first occurrence of every
+// key is kept. If strategy is nil DedupeByCanonicalURL is used.
+func Dedupe(articles []Article, strategy DedupeStrategy) []DedupedArticle {
+	if strategy == nil {
+		strategy = DedupeByCanonicalURL
+	}
+
+	var (
+		deduped []DedupedArticle
+		seen    = make(map[string]int)
+	)
+
+	for _, a := range articles {
+		key := strategy(a)
+
+		i, ok := seen[key]
+		if !ok {
+			seen[key] = len(deduped)
+			deduped = append(deduped, DedupedArticle{Article: a, Key: key})
+			continue
+		}
+
+		d := &deduped[i]
+		if BetterCopy(a, d.Article) {
+			d.Alternates = append(d.Alternates, d.Article)
+			d.Article = a
+		} else {
+			d.Alternates = append(d.Alternates, a)
+		}
+	}
+
+	return deduped
+}
+
+// BetterCopy reports whether a is a better copy of a story than b. The copy with the longer content,
+// including the characters which have been cut off by the API, is better. If both are equally long, the
+// copy with an image is better.
+func BetterCopy(a, b Article) bool {
+	la, lb := contentLen(a), contentLen(b)
+	if la != lb {
+		return la > lb
+	}
+
+	return a.URLToImage != "" && b.URLToImage == ""
+}
+
+// contentLen returns the full length of the article's content in characters, including
+// the characters which have been cut off by the API.
+func contentLen(a Article) int {
+	return utf8.RuneCountInString(a.ContentText()) + a.RemainingChars()
+}
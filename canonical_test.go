@@ -0,0 +1,100 @@
+package newsapi
+
+import "testing"
+
+func TestCanonicalURL(t *testing.T) {
+	cases := []struct {
+		url, expected string
+	}{
+		{"https://www.bbc.co.uk/news/world-123", "https://bbc.co.uk/news/world-123"},
+		{"http://bbc.co.uk/news/world-123/", "https://bbc.co.uk/news/world-123"},
+		{"https://m.bbc.co.uk/news/world-123?utm_source=twitter&utm_medium=social", "https://bbc.co.uk/news/world-123"},
+		{"https://bbc.co.uk/news/world-123?fbclid=abc#comments", "https://bbc.co.uk/news/world-123"},
+		{"https://bbc.co.uk/news/world-123/amp", "https://bbc.co.uk/news/world-123"},
+		{"https://bbc.co.uk/amp/news/world-123", "https://bbc.co.uk/news/world-123"},
+		{"https://bbc.co.uk/news/world-123.amp.html", "https://bbc.co.uk/news/world-123.html"},
+		{"https://bbc.co.uk/news/world-123?amp=1", "https://bbc.co.uk/news/world-123"},
+		{"https://BBC.co.uk:443/news?b=2&a=1", "https://bbc.co.uk/news?a=1&b=2"},
+		{"http://bbc.co.uk:80/news", "https://bbc.co.uk/news"},
+		{"https://bbc.co.uk:80/news", "https://bbc.co.uk:80/news"},
+		{"http://bbc.co.uk:443/news", "https://bbc.co.uk:443/news"},
+		{"https://www.com/news", "https://www.com/news"},
+		{"https://bbc.co.uk/", "https://bbc.co.uk"},
+		{"not a url", "not a url"},
+		{"", ""},
+	}
+
+	for _, i := range cases {
+		u := CanonicalURL(Article{URL: i.url})
+		if u != i.expected {
+			t.Errorf("Expected %s but got %s when case=%v", i.expected, u, i.url)
+		}
+	}
+}
+
+func TestURLRulesCanonical(t *testing.T) {
+	rules := URLRules{StripParams: []string{"ref"}}
+
+	cases := []struct {
+		url, expected string
+	}{
+		{"http://www.bbc.co.uk/news/?b=2&ref=home&a=1#top", "http://www.bbc.co.uk/news/?b=2&a=1#top"},
+		{"https://bbc.co.uk/news/amp?utm_source=twitter", "https://bbc.co.uk/news/amp?utm_source=twitter"},
+		{"http://bbc.co.uk:80/news", "http://bbc.co.uk/news"},
+		{"http://bbc.co.uk:443/news", "http://bbc.co.uk:443/news"},
+		{"https://bbc.co.uk:80/news", "https://bbc.co.uk:80/news"},
+		{"https://bbc.co.uk:443/news", "https://bbc.co.uk/news"},
+	}
+
+	for _, i := range cases {
+		u := rules.Canonical(i.url)
+		if u != i.expected {
+			t.Errorf("Expected %s but got %s when case=%v", i.expected, u, i.url)
+		}
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	articles := []Article{
+		{URL: "https://www.bbc.co.uk/news/a?utm_source=twitter", Content: "Short"},
+		{URL: "https://bbc.co.uk/news/b", Content: "Another story"},
+		{URL: "http://m.bbc.co.uk/news/a/", Content: "Short... [+1000 chars]"},
+		{URL: "https://bbc.co.uk/news/a/amp", Content: "Short... [+1000 chars]", URLToImage: "https://bbc.co.uk/a.jpg"},
+	}
+
+	deduped := Dedupe(articles, nil)
+	if len(deduped) != 2 {
+		t.Fatalf("Expected 2 articles but got %d", len(deduped))
+	}
+
+	a := deduped[0]
+	if a.URL != articles[3].URL {
+		t.Errorf("Expected the copy with the longest content and an image to be kept but got %s", a.URL)
+	}
+
+	if a.Key != "https://bbc.co.uk/news/a" {
+		t.Errorf("Expected the canonical URL as key but got %s", a.Key)
+	}
+
+	if len(a.Alternates) != 2 {
+		t.Errorf("Expected 2 alternates but got %d", len(a.Alternates))
+	}
+
+	if len(Dedupe(articles, DedupeByURL)) != 4 {
+		t.Error("Expected DedupeByURL to only merge articles with exactly the same URL")
+	}
+
+	// articles without a URL aren't merged into one
+	noURL := []Article{
+		{Title: "First", Source: ArticleSource{Name: "Example"}},
+		{Title: "Second", Source: ArticleSource{Name: "Example"}},
+		{Title: "First", Source: ArticleSource{Name: "Example"}},
+		{URL: "/relative", Title: "Third"},
+	}
+	for _, strategy := range []DedupeStrategy{nil, DefaultURLRules.Strategy()} {
+		deduped := Dedupe(noURL, strategy)
+		if len(deduped) != 3 || deduped[0].Key != "id:"+noURL[0].ID() || len(deduped[0].Alternates) != 1 {
+			t.Errorf("Expected the articles without a URL to be grouped by their ID but got %+v", deduped)
+		}
+	}
+}
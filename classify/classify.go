@@ -0,0 +1,422 @@
+/*
+Package classify labels articles with the categories of the Category option. Articles returned by the
+/top-headlines route can be filtered by category, but articles returned by the /everything route can't.
+The package trains a multinomial naive Bayes classifier on top headlines fetched per category and uses it
+to label any other article:
+
+	examples, err := classify.FetchExamples(ctx, &client, []string{"us", "gb"}, newsapi.TopHeadlinesMultiOpts{PageSize: 100})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	train, test := classify.Split(examples, 0.2, 1)
+
+	var c classify.Classifier
+	c.Train(train...)
+	fmt.Println(c.Evaluate(test).Accuracy)
+
+	if err := c.SaveFile("classifier.json"); err != nil {
+		log.Fatal(err)
+	}
+
+	r, err := client.Everything(ctx, newsapi.EverythingOpts{Q: "bitcoin"})
+	...
+	for _, p := range c.PredictAll(r.Articles) {
+		fmt.Println(p.Category, p.Probability)
+	}
+*/
+package classify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+	"github.com/richarddes/newsapi-golang/keyphrase"
+)
+
+// the version of the format written by Save
+const formatVersion = 1
+
+// Example is an article with a known category.
+type Example struct {
+	Article  newsapi.Article `json:"article"`
+	Category string          `json:"category"`
+}
+
+// ExamplesFromHeadlines turns the articles of a TopHeadlines response which has been fetched with the
+// Category option into examples of that category.
+func ExamplesFromHeadlines(category string, r newsapi.TopHeadlinesResp) []Example {
+	examples := make([]Example, len(r.Articles))
+	for i, a := range r.Articles {
+		examples[i] = Example{Article: a, Category: category}
+	}
+
+	return examples
+}
+
+// ExamplesFromMulti turns the articles of a TopHeadlinesMulti response into examples. Articles which have been
+// returned for more than one category are skipped since it's unclear which category they belong to.
+func ExamplesFromMulti(r newsapi.TopHeadlinesMultiResp) []Example {
+	var examples []Example
+
+	for _, a := range r.Articles {
+		category := ""
+		for _, t := range a.Tags {
+			if t.Category == "" || category != "" && t.Category != category {
+				category = ""
+				break
+			}
+			category = t.Category
+		}
+
+		if category != "" {
+			examples = append(examples, Example{Article: a.Article, Category: category})
+		}
+	}
+
+	return examples
+}
+
+// FetchExamples fetches the top headlines of every category in the countries with TopHeadlinesMulti and turns
+// them into examples. It makes newsapi.TopHeadlinesMultiCost(countries, newsapi.Categories()) requests.
+func FetchExamples(ctx context.Context, c *newsapi.Client, countries []string, opts newsapi.TopHeadlinesMultiOpts) ([]Example, error) {
+	r, err := c.TopHeadlinesMulti(ctx, countries, newsapi.Categories(), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return ExamplesFromMulti(r), nil
+}
+
+// Split shuffles the examples and splits them into a training and a test set. testRatio is the share of the
+// examples which end up in the test set. The split only depends on the seed, so it can be reproduced.
+func Split(examples []Example, testRatio float64, seed int64) (train, test []Example) {
+	shuffled := append([]Example(nil), examples...)
+	rand.New(rand.NewSource(seed)).Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+
+	n := int(math.Round(float64(len(shuffled)) * testRatio))
+	if n < 0 {
+		n = 0
+	} else if n > len(shuffled) {
+		n = len(shuffled)
+	}
+
+	return shuffled[n:], shuffled[:n]
+}
+
+// Prediction represents the category a classifier has assigned to an article.
+type Prediction struct {
+	Category string `json:"category"`
+	// Probability is the probability of the category according to the classifier.
+	Probability float64 `json:"probability"`
+	// Probabilities contains the probability of every category the classifier knows.
+	Probabilities map[string]float64 `json:"probabilities"`
+}
+
+// Classifier is a multinomial naive Bayes classifier. The zero value is an untrained classifier which is
+// ready to be trained. A Classifier is safe for concurrent use.
+type Classifier struct {
+	mu sync.RWMutex
+	m  model
+}
+
+// model is the state of a classifier as it's written by Save.
+type model struct {
+	Version int `json:"version"`
+	// Docs is the number of training examples per category.
+	Docs map[string]int `json:"docs"`
+	// Words is the number of times every word occurs in the examples of a category.
+	Words map[string]map[string]int `json:"words"`
+	// Totals is the number of words of all examples of a category.
+	Totals map[string]int `json:"totals"`
+	// Vocabulary is the number of distinct words of all examples.
+	Vocabulary int `json:"vocabulary"`
+}
+
+// Train adds the examples to the classifier. A classifier can be trained several times, e.g. every day with
+// the latest headlines.
+func (c *Classifier) Train(examples ...Example) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.m.Docs == nil {
+		c.m = model{
+			Version: formatVersion,
+			Docs:    make(map[string]int),
+			Words:   make(map[string]map[string]int),
+			Totals:  make(map[string]int),
+		}
+	}
+
+	for _, ex := range examples {
+		if ex.Category == "" {
+			continue
+		}
+
+		words := c.m.Words[ex.Category]
+		if words == nil {
+			words = make(map[string]int)
+			c.m.Words[ex.Category] = words
+		}
+
+		for _, w := range features(ex.Article) {
+			if !c.known(w) {
+				c.m.Vocabulary++
+			}
+			words[w]++
+			c.m.Totals[ex.Category]++
+		}
+		c.m.Docs[ex.Category]++
+	}
+}
+
+func (c *Classifier) known(word string) bool {
+	for _, words := range c.m.Words {
+		if words[word] > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Categories returns the categories the classifier has been trained with.
+func (c *Classifier) Categories() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.categories()
+}
+
+func (c *Classifier) categories() []string {
+	categories := make([]string, 0, len(c.m.Docs))
+	for cat := range c.m.Docs {
+		categories = append(categories, cat)
+	}
+	sort.Strings(categories)
+
+	return categories
+}
+
+// Predict returns the most probable category of the article. The category is empty if the classifier hasn't
+// been trained yet.
+func (c *Classifier) Predict(a newsapi.Article) Prediction {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	categories := c.categories()
+	if len(categories) == 0 {
+		return Prediction{}
+	}
+
+	var docs int
+	for _, n := range c.m.Docs {
+		docs += n
+	}
+
+	words := features(a)
+	logs := make([]float64, len(categories))
+	max := math.Inf(-1)
+
+	for i, cat := range categories {
+		// laplace smoothing so that words which haven't been seen with a category don't rule it out
+		lp := math.Log(float64(c.m.Docs[cat]) / float64(docs))
+		denom := float64(c.m.Totals[cat] + c.m.Vocabulary + 1)
+		for _, w := range words {
+			lp += math.Log(float64(c.m.Words[cat][w]+1) / denom)
+		}
+
+		logs[i] = lp
+		if lp > max {
+			max = lp
+		}
+	}
+
+	// the probabilities are normalized in log space since the products underflow for long texts
+	var sum float64
+	for _, lp := range logs {
+		sum += math.Exp(lp - max)
+	}
+
+	p := Prediction{Probabilities: make(map[string]float64, len(categories))}
+	for i, cat := range categories {
+		prob := math.Exp(logs[i]-max) / sum
+		p.Probabilities[cat] = prob
+
+		if prob > p.Probability {
+			p.Category, p.Probability = cat, prob
+		}
+	}
+
+	return p
+}
+
+// PredictAll predicts the category of every article.
+func (c *Classifier) PredictAll(articles []newsapi.Article) []Prediction {
+	predictions := make([]Prediction, len(articles))
+	for i, a := range articles {
+		predictions[i] = c.Predict(a)
+	}
+
+	return predictions
+}
+
+// CategoryMetrics contains the metrics of a single category.
+type CategoryMetrics struct {
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	F1        float64 `json:"f1"`
+	// Support is the number of examples of the category.
+	Support int `json:"support"`
+}
+
+// Metrics contains the results of an evaluation.
+type Metrics struct {
+	Total    int     `json:"total"`
+	Correct  int     `json:"correct"`
+	Accuracy float64 `json:"accuracy"`
+	// MacroF1 is the mean F1 score of all categories.
+	MacroF1    float64                    `json:"macroF1"`
+	Categories map[string]CategoryMetrics `json:"categories"`
+	// Confusion maps the actual category of the examples to the number of times each category has been predicted.
+	Confusion map[string]map[string]int `json:"confusion"`
+}
+
+// Evaluate predicts the category of every example and compares it with the actual one. The examples
+// should be held out from training, e.g. with Split.
+func (c *Classifier) Evaluate(examples []Example) Metrics {
+	m := Metrics{
+		Categories: make(map[string]CategoryMetrics),
+		Confusion:  make(map[string]map[string]int),
+	}
+
+	predicted := make(map[string]int)
+	correct := make(map[string]int)
+
+	for _, ex := range examples {
+		p := c.Predict(ex.Article)
+
+		if m.Confusion[ex.Category] == nil {
+			m.Confusion[ex.Category] = make(map[string]int)
+		}
+		m.Confusion[ex.Category][p.Category]++
+
+		cm := m.Categories[ex.Category]
+		cm.Support++
+		m.Categories[ex.Category] = cm
+
+		predicted[p.Category]++
+		if p.Category == ex.Category {
+			correct[ex.Category]++
+			m.Correct++
+		}
+		m.Total++
+	}
+
+	if m.Total > 0 {
+		m.Accuracy = float64(m.Correct) / float64(m.Total)
+	}
+
+	for cat, cm := range m.Categories {
+		if predicted[cat] > 0 {
+			cm.Precision = float64(correct[cat]) / float64(predicted[cat])
+		}
+		cm.Recall = float64(correct[cat]) / float64(cm.Support)
+		if cm.Precision+cm.Recall > 0 {
+			cm.F1 = 2 * cm.Precision * cm.Recall / (cm.Precision + cm.Recall)
+		}
+
+		m.Categories[cat] = cm
+		m.MacroF1 += cm.F1 / float64(len(m.Categories))
+	}
+
+	return m
+}
+
+// Save writes the trained classifier as JSON to w.
+func (c *Classifier) Save(w io.Writer) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	m := c.m
+	m.Version = formatVersion
+
+	return json.NewEncoder(w).Encode(m)
+}
+
+// SaveFile writes the trained classifier to the file at path. An existing file is replaced.
+func (c *Classifier) SaveFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := c.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// Load reads a classifier which has been written by Save.
+func Load(r io.Reader) (*Classifier, error) {
+	var m model
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, err
+	}
+
+	if m.Version != formatVersion {
+		return nil, errors.New("The classifier has been saved in an unsupported format")
+	}
+
+	if m.Docs == nil || m.Words == nil || m.Totals == nil {
+		return nil, errors.New("The classifier is incomplete")
+	}
+
+	return &Classifier{m: m}, nil
+}
+
+// LoadFile reads a classifier from the file at path.
+func LoadFile(path string) (*Classifier, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Load(f)
+}
+
+// features returns the lowercased words of the title, description and content of the article without
+// stopwords and numbers. The title is counted twice since it's the most telling part of an article.
+func features(a newsapi.Article) []string {
+	text := a.Title + " " + a.Title + " " + a.Description + " " + a.ContentText()
+
+	lang, _ := newsapi.DetectLanguage(text)
+	stopwords := keyphrase.Stopwords(lang)
+
+	var words []string
+	for _, f := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if stopwords[f] || len([]rune(f)) < 2 || strings.IndexFunc(f, unicode.IsLetter) < 0 {
+			continue
+		}
+		words = append(words, f)
+	}
+
+	return words
+}
@@ -0,0 +1,149 @@
+package classify
+
+import (
+	"bytes"
+	"math"
+	"path/filepath"
+	"testing"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+var examples = []Example{
+	{newsapi.Article{Title: "Stocks fall as investors weigh interest rates", Description: "Shares and bonds slid on Wall Street."}, "business"},
+	{newsapi.Article{Title: "Central bank raises interest rates", Description: "Investors expect higher borrowing costs for companies."}, "business"},
+	{newsapi.Article{Title: "Company profits beat expectations", Description: "The company's shares rose after quarterly earnings."}, "business"},
+	{newsapi.Article{Title: "Oil prices climb as markets rally", Description: "Investors bought shares of energy companies."}, "business"},
+	{newsapi.Article{Title: "Striker scores twice as club wins the league", Description: "The football club celebrated the title with fans."}, "sports"},
+	{newsapi.Article{Title: "Tennis champion wins the final", Description: "The champion won the match in three sets."}, "sports"},
+	{newsapi.Article{Title: "Coach praises team after victory", Description: "The team won the match thanks to a late goal."}, "sports"},
+	{newsapi.Article{Title: "Football club signs new goalkeeper", Description: "The club confirmed the transfer before the match."}, "sports"},
+	{newsapi.Article{Title: "New smartphone features faster chip", Description: "The device runs the latest software update."}, "technology"},
+	{newsapi.Article{Title: "Software update fixes security flaw", Description: "Users should install the update on every device."}, "technology"},
+	{newsapi.Article{Title: "Startup unveils artificial intelligence chip", Description: "The chip runs software for machine learning."}, "technology"},
+	{newsapi.Article{Title: "App developers embrace new software tools", Description: "The smartphone app now supports the latest device."}, "technology"},
+}
+
+func TestExamplesFromMulti(t *testing.T) {
+	r := newsapi.TopHeadlinesMultiResp{
+		Articles: []newsapi.TaggedArticle{
+			{Article: newsapi.Article{URL: "a"}, Tags: []newsapi.HeadlineTag{{Country: "us", Category: "sports"}, {Country: "gb", Category: "sports"}}},
+			{Article: newsapi.Article{URL: "b"}, Tags: []newsapi.HeadlineTag{{Country: "us", Category: "sports"}, {Country: "us", Category: "health"}}},
+			{Article: newsapi.Article{URL: "c"}, Tags: []newsapi.HeadlineTag{{Country: "us"}}},
+		},
+	}
+
+	ex := ExamplesFromMulti(r)
+	if len(ex) != 1 || ex[0].Article.URL != "a" || ex[0].Category != "sports" {
+		t.Errorf("Expected only the unambiguous article to be an example but got %v", ex)
+	}
+
+	headlines := ExamplesFromHeadlines("health", newsapi.TopHeadlinesResp{Articles: []newsapi.Article{{URL: "d"}}})
+	if len(headlines) != 1 || headlines[0].Category != "health" {
+		t.Errorf("Unexpected examples %v", headlines)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	train, test := Split(examples, 0.25, 1)
+	if len(train) != 9 || len(test) != 3 {
+		t.Errorf("Expected 9 training and 3 test examples but got %d and %d", len(train), len(test))
+	}
+
+	again, _ := Split(examples, 0.25, 1)
+	for i := range train {
+		if train[i].Article.Title != again[i].Article.Title {
+			t.Fatal("Expected the split to be reproducible with the same seed")
+		}
+	}
+}
+
+func TestPredict(t *testing.T) {
+	var c Classifier
+
+	if p := c.Predict(examples[0].Article); p.Category != "" {
+		t.Errorf("Expected no category from an untrained classifier but got %q", p.Category)
+	}
+
+	c.Train(examples...)
+
+	cases := []struct {
+		article  newsapi.Article
+		category string
+	}{
+		{newsapi.Article{Title: "Shares rally as investors cheer profits"}, "business"},
+		{newsapi.Article{Title: "Club wins the match with a late goal"}, "sports"},
+		{newsapi.Article{Title: "The new chip makes every device faster", Content: "The software… [+1200 chars]"}, "technology"},
+	}
+
+	for _, i := range cases {
+		p := c.Predict(i.article)
+		if p.Category != i.category {
+			t.Errorf("Expected %v but got %v when case=%v", i.category, p.Category, i.article.Title)
+		}
+
+		var sum float64
+		for _, prob := range p.Probabilities {
+			sum += prob
+		}
+		if math.Abs(sum-1) > 1e-9 || p.Probability != p.Probabilities[p.Category] {
+			t.Errorf("Expected valid probabilities but got %v", p.Probabilities)
+		}
+	}
+
+	if cats := c.Categories(); len(cats) != 3 || cats[0] != "business" {
+		t.Errorf("Unexpected categories %v", cats)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	var c Classifier
+	c.Train(examples...)
+
+	m := c.Evaluate(examples)
+	if m.Total != len(examples) || m.Accuracy != 1 || m.MacroF1 != 1 {
+		t.Errorf("Expected a perfect score on the training data but got %+v", m)
+	}
+
+	wrong := []Example{{examples[0].Article, "sports"}, {examples[4].Article, "sports"}}
+	m = c.Evaluate(wrong)
+	if m.Accuracy != 0.5 || m.Confusion["sports"]["business"] != 1 {
+		t.Errorf("Unexpected metrics %+v", m)
+	}
+
+	if s := m.Categories["sports"]; s.Support != 2 || s.Precision != 1 || s.Recall != 0.5 {
+		t.Errorf("Unexpected metrics for sports %+v", s)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	var c Classifier
+	c.Train(examples...)
+
+	var buf bytes.Buffer
+	if err := c.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := newsapi.Article{Title: "Investors sell shares"}
+	if p, q := c.Predict(a), loaded.Predict(a); p.Category != q.Category || p.Probability != q.Probability {
+		t.Errorf("Expected the loaded classifier to predict %v but got %v", p, q)
+	}
+
+	path := filepath.Join(t.TempDir(), "classifier.json")
+	if err := c.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(path); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := Load(bytes.NewBufferString(`{"version": 99}`)); err == nil {
+		t.Error("Expected an error for an unsupported version")
+	}
+}
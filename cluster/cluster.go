@@ -0,0 +1,272 @@
+/*
+Package cluster groups near-duplicate articles into stories. Wire stories which are being syndicated by
+agencies like AP or Reuters often show up dozens of times in a single response, each time with a slightly
+different headline. The package fingerprints the title, description and content of every article with
+SimHash and puts articles whose fingerprints only differ in a few bits into the same Story.
+
+Everything runs in-process. A Clusterer can be fed incrementally, e.g. with every page of an Everything call:
+
+	var c cluster.Clusterer
+
+	for page := uint16(1); page <= 5; page++ {
+		r, err := client.Everything(ctx, newsapi.EverythingOpts{Q: "election", Page: page})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		c.Add(r.Articles...)
+	}
+
+	for _, s := range c.Stories() {
+		fmt.Println(s.Representative.Title, len(s.Members), s.Sources)
+	}
+*/
+package cluster
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+// DefaultMaxDistance is the maximum number of differing fingerprint bits of two articles in the same story
+// if Clusterer.MaxDistance isn't set. It has been chosen so that re-worded headlines of the same wire story
+// still end up together while unrelated articles about the same topic don't.
+const DefaultMaxDistance = 8
+
+// Story represents a group of near-duplicate articles.
+type Story struct {
+	// Representative is the member with the longest content, preferring members with an image.
+	Representative newsapi.Article `json:"representative"`
+	// Members contains every article of the story in the order they've been added, including the representative.
+	Members []newsapi.Article `json:"members"`
+	// Sources contains the sorted names of all sources which have published the story.
+	Sources []string `json:"sources"`
+	// Fingerprint is the fingerprint of the article which started the story.
+	Fingerprint uint64 `json:"fingerprint"`
+}
+
+// Clusterer incrementally groups articles into stories. The zero value is ready to use.
+// A Clusterer is safe for concurrent use.
+type Clusterer struct {
+	// MaxDistance is the maximum hamming distance between the fingerprints of two articles of the same story.
+	// DefaultMaxDistance is used if it's smaller than 1.
+	MaxDistance int
+
+	mu      sync.Mutex
+	stories []Story
+	prints  [][]uint64 // the fingerprints of the members of every story
+	bands   []map[uint64][]int
+}
+
+// Cluster groups the articles into stories using a new Clusterer with the given maximum distance.
+func Cluster(articles []newsapi.Article, maxDistance int) []Story {
+	c := Clusterer{MaxDistance: maxDistance}
+	c.Add(articles...)
+
+	return c.Stories()
+}
+
+// Add puts every article into the story with the closest fingerprint within the maximum distance or
+// starts a new story if there isn't one.
+func (c *Clusterer) Add(articles ...newsapi.Article) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.reindex()
+
+	for _, a := range articles {
+		fp := Fingerprint(a)
+
+		i := c.closest(fp)
+		if i < 0 {
+			i = len(c.stories)
+			c.stories = append(c.stories, Story{Fingerprint: fp, Representative: a})
+			c.prints = append(c.prints, nil)
+		} else if newsapi.BetterCopy(a, c.stories[i].Representative) {
+			c.stories[i].Representative = a
+		}
+
+		s := &c.stories[i]
+		s.Members = append(s.Members, a)
+		s.Sources = addSource(s.Sources, a.Source)
+
+		c.prints[i] = append(c.prints[i], fp)
+		c.index(fp, i)
+	}
+}
+
+// Stories returns a copy of the current stories in the order they've been started.
+func (c *Clusterer) Stories() []Story {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stories := make([]Story, len(c.stories))
+	for i, s := range c.stories {
+		s.Members = append([]newsapi.Article(nil), s.Members...)
+		s.Sources = append([]string(nil), s.Sources...)
+		stories[i] = s
+	}
+
+	return stories
+}
+
+func (c *Clusterer) maxDistance() int {
+	if c.MaxDistance < 1 {
+		return DefaultMaxDistance
+	}
+	if c.MaxDistance > 63 {
+		return 63
+	}
+
+	return c.MaxDistance
+}
+
+// The fingerprints are split into maxDistance+1 bands. If two fingerprints differ in at most maxDistance
+// bits, at least one of their bands has to be identical, so only the stories which share a band with a
+// fingerprint have to be compared with it instead of all of them.
+
+func band(fp uint64, b, n int) uint64 {
+	lo, hi := b*64/n, (b+1)*64/n
+
+	// the band number is stored in the upper bits so equal values of different bands don't collide
+	return uint64(b)<<56 | (fp>>uint(lo))&(1<<uint(hi-lo)-1)
+}
+
+// reindex rebuilds the bands if MaxDistance has been changed since they've been built.
+func (c *Clusterer) reindex() {
+	n := c.maxDistance() + 1
+	if len(c.bands) == n {
+		return
+	}
+
+	c.bands = make([]map[uint64][]int, n)
+	for b := range c.bands {
+		c.bands[b] = make(map[uint64][]int)
+	}
+
+	for i, prints := range c.prints {
+		for _, fp := range prints {
+			c.index(fp, i)
+		}
+	}
+}
+
+func (c *Clusterer) index(fp uint64, story int) {
+	for b := range c.bands {
+		key := band(fp, b, len(c.bands))
+
+		ids := c.bands[b][key]
+		if len(ids) == 0 || ids[len(ids)-1] != story {
+			c.bands[b][key] = append(ids, story)
+		}
+	}
+}
+
+// closest returns the index of the story with the closest member to fp or -1 if no member is close enough.
+func (c *Clusterer) closest(fp uint64) int {
+	var (
+		best     = -1
+		bestDist = c.maxDistance() + 1
+		seen     = make(map[int]bool)
+	)
+
+	for b := range c.bands {
+		for _, i := range c.bands[b][band(fp, b, len(c.bands))] {
+			if seen[i] {
+				continue
+			}
+			seen[i] = true
+
+			for _, p := range c.prints[i] {
+				if d := Distance(fp, p); d < bestDist || d == bestDist && i < best {
+					best, bestDist = i, d
+				}
+			}
+		}
+	}
+
+	return best
+}
+
+// Fingerprint returns the 64 bit SimHash of the article's title, description and content. The words and
+// pairs of consecutive words of the text are used as features and the words of the title are weighted
+// twice as much since the title is usually what differs the most between near-duplicates.
+func Fingerprint(a newsapi.Article) uint64 {
+	var weights [64]int
+
+	add := func(text string, weight int) {
+		words := tokenize(text)
+		for i, w := range words {
+			addFeature(&weights, w, weight)
+			if i > 0 {
+				addFeature(&weights, words[i-1]+" "+w, weight)
+			}
+		}
+	}
+
+	add(a.Title, 2)
+	add(a.Description, 1)
+	add(a.ContentText(), 1)
+
+	var fp uint64
+	for i, w := range weights {
+		if w > 0 {
+			fp |= 1 << uint(i)
+		}
+	}
+
+	return fp
+}
+
+// Distance returns the number of bits two fingerprints differ in.
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+func addFeature(weights *[64]int, feature string, weight int) {
+	h := fnv.New64a()
+	h.Write([]byte(feature))
+	sum := h.Sum64()
+
+	for i := range weights {
+		if sum&(1<<uint(i)) != 0 {
+			weights[i] += weight
+		} else {
+			weights[i] -= weight
+		}
+	}
+}
+
+// tokenize splits the text into lower cased words and drops the punctuation.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+func addSource(sources []string, src newsapi.ArticleSource) []string {
+	name := src.Name
+	if name == "" {
+		name = src.ID
+	}
+	if name == "" {
+		return sources
+	}
+
+	i := sort.SearchStrings(sources, name)
+	if i < len(sources) && sources[i] == name {
+		return sources
+	}
+
+	sources = append(sources, "")
+	copy(sources[i+1:], sources[i:])
+	sources[i] = name
+
+	return sources
+}
@@ -0,0 +1,137 @@
+package cluster
+
+import (
+	"testing"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+var (
+	wireA = newsapi.Article{
+		Source:      newsapi.ArticleSource{Name: "Reuters"},
+		Title:       "Central bank raises interest rates for the third time this year",
+		Description: "The central bank raised its benchmark interest rate by a quarter point on Wednesday, citing persistent inflation and a strong labour market.",
+		Content:     "The central bank raised its benchmark interest rate by a quarter point on Wednesday, citing persistent inflation and a strong labour market. Policymakers voted… [+2300 chars]",
+		URL:         "https://reuters.com/a",
+	}
+	wireB = newsapi.Article{
+		Source:      newsapi.ArticleSource{Name: "Yahoo News"},
+		Title:       "Central bank raises interest rates for third time this year",
+		Description: "The central bank raised its benchmark interest rate by a quarter point on Wednesday, citing persistent inflation and a strong labour market.",
+		Content:     "The central bank raised its benchmark interest rate by a quarter point on Wednesday, citing persistent inflation and a strong labour market. Policymakers voted… [+2412 chars]",
+		URL:         "https://news.yahoo.com/a",
+		URLToImage:  "https://news.yahoo.com/a.jpg",
+	}
+	wireC = newsapi.Article{
+		Source:      newsapi.ArticleSource{Name: "ABC News"},
+		Title:       "Central bank raises interest rates again, third hike this year",
+		Description: "The central bank raised its benchmark interest rate by a quarter point on Wednesday, citing persistent inflation and a strong labour market.",
+		Content:     "The central bank raised its benchmark interest rate by a quarter point on Wednesday, citing persistent inflation and a strong labour market. Policymakers voted… [+2300 chars]",
+		URL:         "https://abcnews.go.com/a",
+	}
+	other = newsapi.Article{
+		Source:      newsapi.ArticleSource{Name: "BBC News"},
+		Title:       "Football club signs striker in record transfer deal",
+		Description: "The club confirmed the signing of the forward on a five year contract after weeks of negotiations with his former team.",
+		Content:     "The club confirmed the signing of the forward on a five year contract after weeks of negotiations with his former team. The fee… [+1800 chars]",
+		URL:         "https://bbc.co.uk/sport/a",
+	}
+)
+
+func TestDistance(t *testing.T) {
+	cases := []struct {
+		a, b uint64
+		d    int
+	}{
+		{0, 0, 0},
+		{0, 1, 1},
+		{0xff, 0x0f, 4},
+		{0, ^uint64(0), 64},
+	}
+
+	for _, i := range cases {
+		if d := Distance(i.a, i.b); d != i.d {
+			t.Errorf("Expected %d but got %d when case=%v", i.d, d, i)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	if Fingerprint(wireA) != Fingerprint(wireA) {
+		t.Error("Expected the fingerprint to be stable")
+	}
+
+	near := Distance(Fingerprint(wireA), Fingerprint(wireB))
+	far := Distance(Fingerprint(wireA), Fingerprint(other))
+
+	if near > DefaultMaxDistance {
+		t.Errorf("Expected near-duplicates to be at most %d bits apart but got %d", DefaultMaxDistance, near)
+	}
+
+	if far <= DefaultMaxDistance {
+		t.Errorf("Expected unrelated articles to be more than %d bits apart but got %d", DefaultMaxDistance, far)
+	}
+}
+
+func TestCluster(t *testing.T) {
+	stories := Cluster([]newsapi.Article{wireA, other, wireB, wireC}, 0)
+	if len(stories) != 2 {
+		t.Fatalf("Expected 2 stories but got %d", len(stories))
+	}
+
+	s := stories[0]
+	if len(s.Members) != 3 {
+		t.Errorf("Expected 3 members but got %d", len(s.Members))
+	}
+
+	if s.Representative.URL != wireB.URL {
+		t.Errorf("Expected %s to be the representative but got %s", wireB.URL, s.Representative.URL)
+	}
+
+	expected := []string{"ABC News", "Reuters", "Yahoo News"}
+	if len(s.Sources) != len(expected) {
+		t.Fatalf("Expected sources %v but got %v", expected, s.Sources)
+	}
+	for i := range expected {
+		if s.Sources[i] != expected[i] {
+			t.Errorf("Expected sources %v but got %v", expected, s.Sources)
+		}
+	}
+}
+
+func TestClustererIncremental(t *testing.T) {
+	var c Clusterer
+
+	c.Add(wireA, other)
+	if n := len(c.Stories()); n != 2 {
+		t.Fatalf("Expected 2 stories but got %d", n)
+	}
+
+	// a later page returns another copy of the wire story
+	c.Add(wireC)
+
+	stories := c.Stories()
+	if len(stories) != 2 {
+		t.Fatalf("Expected 2 stories but got %d", len(stories))
+	}
+
+	if len(stories[0].Members) != 2 || len(stories[1].Members) != 1 {
+		t.Errorf("Expected the new article to join the first story but got %d and %d members", len(stories[0].Members), len(stories[1].Members))
+	}
+}
+
+func TestClustererMaxDistanceChanged(t *testing.T) {
+	c := Clusterer{MaxDistance: 4}
+	c.Add(wireA, other)
+
+	// the bands are rebuilt for the new distance instead of being indexed out of range
+	c.MaxDistance = 20
+	c.Add(wireC)
+
+	if n := len(c.Stories()); n < 2 {
+		t.Errorf("Expected at least 2 stories but got %d", n)
+	}
+
+	c.MaxDistance = 2
+	c.Add(wireA)
+}
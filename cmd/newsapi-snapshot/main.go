@@ -0,0 +1,130 @@
+/*
+Command newsapi-snapshot verifies and seals the daily archives of the snapshot package.
+
+Usage:
+
+	newsapi-snapshot verify [-key-file file] archive...
+	newsapi-snapshot seal [-key-file file] [-before 2006-01-02] dir
+
+The verify command checks every archive against its manifest and the .sha256 file next to it. It prints
+the problems it finds and exits with status 1 if there are any. The seal command seals the responses staged
+in the directory before the given day, by default before today in UTC, e.g. when it's run by cron shortly
+after midnight. The key file contains the key the manifests are signed with.
+*/
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/richarddes/newsapi-golang/snapshot"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+const usage = `usage:
+	newsapi-snapshot verify [-key-file file] archive...
+	newsapi-snapshot seal [-key-file file] [-before 2006-01-02] dir
+`
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	keyFile := fs.String("key-file", "", "file containing the key the manifests are signed with")
+	before := fs.String("before", "", "seal the days before this day instead of before today")
+
+	switch args[0] {
+	case "verify", "seal":
+	default:
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	var key []byte
+	if *keyFile != "" {
+		b, err := os.ReadFile(*keyFile)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		key = bytes.TrimRight(b, "\r\n")
+	}
+
+	if args[0] == "seal" {
+		return seal(fs.Args(), key, *before, stdout, stderr)
+	}
+
+	return verify(fs.Args(), key, stdout, stderr)
+}
+
+func verify(archives []string, key []byte, stdout, stderr io.Writer) int {
+	if len(archives) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	status := 0
+	for _, name := range archives {
+		rep, err := snapshot.VerifyFile(name, key)
+		if err != nil {
+			fmt.Fprintf(stderr, "%v: %v\n", name, err)
+			status = 1
+			continue
+		}
+
+		if rep.OK() {
+			fmt.Fprintf(stdout, "%v: OK, %d responses\n", name, len(rep.Manifest.Entries))
+			continue
+		}
+
+		status = 1
+		for _, p := range rep.Problems {
+			fmt.Fprintf(stdout, "%v: %v\n", name, p)
+		}
+	}
+
+	return status
+}
+
+func seal(args []string, key []byte, before string, stdout, stderr io.Writer) int {
+	if len(args) != 1 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	t := time.Now()
+	if before != "" {
+		var err error
+		if t, err = time.Parse("2006-01-02", before); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+	}
+
+	a := &snapshot.Archiver{Dir: args[0], Key: key}
+	paths, err := a.SealBefore(t)
+	for _, p := range paths {
+		fmt.Fprintln(stdout, p)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	return 0
+}
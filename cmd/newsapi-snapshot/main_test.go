@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+	"github.com/richarddes/newsapi-golang/snapshot"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+
+	keyFile := filepath.Join(dir, "key")
+	if err := os.WriteFile(keyFile, []byte("key\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	a := &snapshot.Archiver{Dir: dir}
+	r := newsapi.RawResponse{Route: "/v2/sources", Body: []byte(`{"status":"ok"}`), RespondedAt: time.Date(2020, 5, 1, 8, 0, 0, 0, time.UTC)}
+	if err := a.Record(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := filepath.Join(dir, snapshot.ArchiveName("2020-05-01"))
+
+	cases := []struct {
+		args   []string
+		status int
+		output string
+	}{
+		{nil, 2, ""},
+		{[]string{"unknown"}, 2, ""},
+		{[]string{"verify"}, 2, ""},
+		{[]string{"seal", "-key-file", keyFile, "-before", "2020-05-02", dir}, 0, archive + "\n"},
+		{[]string{"verify", "-key-file", keyFile, archive}, 0, archive + ": OK, 1 responses\n"},
+		{[]string{"verify", "-key-file", filepath.Join(dir, "missing"), archive}, 1, ""},
+		{[]string{"verify", filepath.Join(dir, "missing.tar.gz")}, 1, ""},
+	}
+
+	for _, i := range cases {
+		var stdout, stderr bytes.Buffer
+		if status := run(i.args, &stdout, &stderr); status != i.status || stdout.String() != i.output {
+			t.Errorf("Expected %v and %q but got %v and %q when case=%v", i.status, i.output, status, stdout.String(), i.args)
+		}
+	}
+
+	// an archive signed with a different key
+	if err := os.WriteFile(keyFile, []byte("other key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	if status := run([]string{"verify", "-key-file", keyFile, archive}, &stdout, &bytes.Buffer{}); status != 1 || !strings.Contains(stdout.String(), "invalid signature") {
+		t.Errorf("Expected an invalid signature but got %v and %q", status, stdout.String())
+	}
+}
@@ -0,0 +1,249 @@
+/*
+Package digest renders sets of articles as a digest, e.g. a morning newsletter built from several
+TopHeadlines calls. Articles are grouped into sections by category, source or story and rendered as
+Markdown, as a responsive HTML email or as plain text:
+
+	r, err := c.TopHeadlinesMulti(ctx, []string{"us"}, []string{"business", "technology"}, newsapi.TopHeadlinesMultiOpts{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	d := digest.Digest{Title: "Morning digest", Date: time.Now(), Sections: digest.ByCategory(r)}
+
+	var rd digest.Renderer
+	if err := rd.Render(os.Stdout, digest.HTML, d); err != nil {
+		log.Fatal(err)
+	}
+
+The built-in templates can be replaced with custom text/template or html/template templates. Templates
+created with the functions returned by Renderer.Funcs can use the same helpers as the built-in ones:
+
+	tmpl := template.Must(template.New("digest").Funcs(rd.Funcs()).Parse(`{{range .Sections}}...{{end}}`))
+	rd.Templates = map[digest.Format]digest.Template{digest.Markdown: tmpl}
+
+The helpers are:
+
+	ago t           the time relative to Renderer.Now, e.g. "3 hours ago"
+	date t          the date in Renderer.Location, e.g. "Mon, 2 Jan 2006"
+	clock t         the time of day in Renderer.Location, e.g. "15:04"
+	truncate n s    s cut off after at most n characters at a word boundary
+	wrap n i s      s wrapped after at most n characters per line and indented with i, for plain text
+	content a       the content of the article without the "[+123 chars]" marker
+	thumbnail s     the URL of the thumbnail of an image URL or "" if it isn't an http(s) URL
+	md s            s with the characters escaped which Markdown would interpret
+	link s          s escaped as the destination of a Markdown link or "" if it isn't an http(s) URL
+*/
+package digest
+
+import (
+	"embed"
+	"errors"
+	htmltemplate "html/template"
+	"io"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+	"github.com/richarddes/newsapi-golang/cluster"
+)
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+// Format is an output format of a digest.
+type Format string
+
+// The supported formats.
+const (
+	Markdown Format = "markdown"
+	HTML     Format = "html"
+	Text     Format = "text"
+)
+
+var errUnsupported = errors.New("The format isn't supported")
+
+var templateNames = map[Format]string{
+	Markdown: "templates/digest.md.tmpl",
+	HTML:     "templates/digest.html.tmpl",
+	Text:     "templates/digest.txt.tmpl",
+}
+
+// Section is a group of articles in a digest.
+type Section struct {
+	Title    string            `json:"title"`
+	Articles []newsapi.Article `json:"articles"`
+}
+
+// Digest is the data the templates are rendered with.
+type Digest struct {
+	Title string `json:"title"`
+	// Intro is an optional text shown below the title.
+	Intro    string    `json:"intro,omitempty"`
+	Date     time.Time `json:"date"`
+	Sections []Section `json:"sections"`
+}
+
+// Template is implemented by *text/template.Template and *html/template.Template.
+type Template interface {
+	Execute(w io.Writer, data interface{}) error
+}
+
+const (
+	// DefaultSummaryLength is the number of characters the descriptions are truncated to by the built-in
+	// templates if Renderer.SummaryLength isn't set.
+	DefaultSummaryLength = 240
+	// DefaultWidth is the line width of the plain text template.
+	DefaultWidth = 72
+)
+
+// Renderer renders digests. The zero value uses the built-in templates.
+type Renderer struct {
+	// Templates replaces the built-in templates of the formats. The Markdown and plain text templates
+	// should be text/template templates, the HTML template an html/template template.
+	Templates map[Format]Template
+	// Now is the time relative times are calculated to. The current time is used if it's zero.
+	Now time.Time
+	// Location is the time zone dates are shown in. UTC is used if it's nil.
+	Location *time.Location
+	// SummaryLength is the number of characters the built-in templates truncate descriptions to.
+	SummaryLength int
+	// ThumbnailURL rewrites the URL of an image, e.g. to let an image proxy scale it down. The image
+	// is used as it is if it's nil.
+	ThumbnailURL func(imageURL string) string
+}
+
+// view is passed to the templates.
+type view struct {
+	Digest
+	SummaryLength int
+	Width         int
+}
+
+// Render renders the digest in the format to w.
+func (r Renderer) Render(w io.Writer, format Format, d Digest) error {
+	tmpl, err := r.template(format)
+	if err != nil {
+		return err
+	}
+
+	summary := r.SummaryLength
+	if summary <= 0 {
+		summary = DefaultSummaryLength
+	}
+
+	return tmpl.Execute(w, view{Digest: d, SummaryLength: summary, Width: DefaultWidth})
+}
+
+func (r Renderer) template(format Format) (Template, error) {
+	if t, ok := r.Templates[format]; ok && t != nil {
+		return t, nil
+	}
+
+	name, ok := templateNames[format]
+	if !ok {
+		return nil, errUnsupported
+	}
+
+	b, err := templateFiles.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == HTML {
+		return htmltemplate.New(name).Funcs(r.Funcs()).Parse(string(b))
+	}
+
+	return texttemplate.New(name).Funcs(r.Funcs()).Parse(string(b))
+}
+
+// ByCategory groups the articles of a TopHeadlinesMulti response by their category. An article which has
+// been returned for several categories is only shown in the first one. The sections are ordered by
+// category, articles without a category come last.
+func ByCategory(r newsapi.TopHeadlinesMultiResp) []Section {
+	var (
+		sections []Section
+		index    = make(map[string]int)
+	)
+
+	for _, a := range r.Articles {
+		category := ""
+		if len(a.Tags) > 0 {
+			category = a.Tags[0].Category
+		}
+
+		i, ok := index[category]
+		if !ok {
+			i = len(sections)
+			index[category] = i
+			sections = append(sections, Section{Title: categoryTitle(category)})
+		}
+		sections[i].Articles = append(sections[i].Articles, a.Article)
+	}
+
+	sort.SliceStable(sections, func(i, j int) bool {
+		if (sections[i].Title == "Other") != (sections[j].Title == "Other") {
+			return sections[j].Title == "Other"
+		}
+		return sections[i].Title < sections[j].Title
+	})
+
+	return sections
+}
+
+func categoryTitle(category string) string {
+	if category == "" {
+		return "Other"
+	}
+
+	return strings.ToUpper(category[:1]) + category[1:]
+}
+
+// BySource groups the articles by the name of their source. The sections are ordered by the number of
+// articles, the articles keep their order.
+func BySource(articles []newsapi.Article) []Section {
+	var (
+		sections []Section
+		index    = make(map[string]int)
+	)
+
+	for _, a := range articles {
+		name := a.Source.Name
+		if name == "" {
+			name = "Other"
+		}
+
+		i, ok := index[name]
+		if !ok {
+			i = len(sections)
+			index[name] = i
+			sections = append(sections, Section{Title: name})
+		}
+		sections[i].Articles = append(sections[i].Articles, a)
+	}
+
+	sort.SliceStable(sections, func(i, j int) bool {
+		return len(sections[i].Articles) > len(sections[j].Articles)
+	})
+
+	return sections
+}
+
+// ByStory turns every story into a section titled with the title of its representative. The
+// representative is the first article of a section, the other members follow.
+func ByStory(stories []cluster.Story) []Section {
+	sections := make([]Section, 0, len(stories))
+
+	for _, s := range stories {
+		sec := Section{Title: s.Representative.Title, Articles: []newsapi.Article{s.Representative}}
+		for _, m := range s.Members {
+			if m.URL != s.Representative.URL || m.Title != s.Representative.Title {
+				sec.Articles = append(sec.Articles, m)
+			}
+		}
+		sections = append(sections, sec)
+	}
+
+	return sections
+}
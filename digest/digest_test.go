@@ -0,0 +1,214 @@
+package digest
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"reflect"
+	"strings"
+	"testing"
+	texttemplate "text/template"
+	"time"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+	"github.com/richarddes/newsapi-golang/cluster"
+)
+
+var now = time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func testDigest() Digest {
+	return Digest{
+		Title: "Morning digest",
+		Date:  now,
+		Sections: []Section{{
+			Title: "Business",
+			Articles: []newsapi.Article{
+				{
+					Source:      newsapi.ArticleSource{Name: "Example News"},
+					Title:       "Markets <rally> on *Friday*",
+					URL:         "https://example.com/markets",
+					URLToImage:  "https://example.com/markets.jpg",
+					PublishedAt: now.Add(-3 * time.Hour),
+					Description: "Stocks rose sharply on Friday as investors cheered better than expected earnings.",
+				},
+				{Title: "No image", URL: "javascript:alert(1)", URLToImage: "javascript:alert(1)"},
+			},
+		}},
+	}
+}
+
+func TestRender(t *testing.T) {
+	cases := []struct {
+		format   Format
+		contains []string
+		excludes []string
+	}{
+		{
+			Markdown,
+			[]string{
+				"# Morning digest",
+				"_Fri, 1 May 2020_",
+				"## Business",
+				`### [Markets \<rally\> on \*Friday\*](https://example.com/markets)`,
+				"![](https://example.com/markets.jpg)",
+				"*Example News · 3 hours ago*",
+				"Stocks rose sharply on Friday as investors…",
+				"### No image",
+			},
+			[]string{"javascript"},
+		},
+		{
+			HTML,
+			[]string{
+				`<meta name="viewport"`,
+				"@media only screen",
+				`<h1 style="margin:0;font-size:24px;line-height:32px;">Morning digest</h1>`,
+				`<a href="https://example.com/markets"`,
+				"Markets &lt;rally&gt; on *Friday*",
+				`<img src="https://example.com/markets.jpg"`,
+				"Example News · 3 hours ago",
+			},
+			[]string{"javascript", "<rally>"},
+		},
+		{
+			Text,
+			[]string{
+				"Morning digest\nFri, 1 May 2020\n",
+				"== Business ==",
+				"* Markets <rally> on *Friday*\n  Example News, 3 hours ago\n  Stocks rose sharply on Friday as investors…\n  https://example.com/markets\n",
+				"* No image\n",
+			},
+			[]string{"javascript"},
+		},
+	}
+
+	r := Renderer{Now: now, SummaryLength: 45}
+	for _, i := range cases {
+		var buf bytes.Buffer
+		if err := r.Render(&buf, i.format, testDigest()); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, s := range i.contains {
+			if !strings.Contains(buf.String(), s) {
+				t.Errorf("Expected %q in the output but got %v when case=%v", s, buf.String(), i.format)
+			}
+		}
+		for _, s := range i.excludes {
+			if strings.Contains(buf.String(), s) {
+				t.Errorf("Expected no %q in the output but got %v when case=%v", s, buf.String(), i.format)
+			}
+		}
+	}
+
+	if err := r.Render(&bytes.Buffer{}, "pdf", testDigest()); err == nil {
+		t.Error("Expected an error for an unsupported format")
+	}
+}
+
+func TestCustomTemplates(t *testing.T) {
+	r := Renderer{
+		Now: now,
+		ThumbnailURL: func(image string) string {
+			return "https://images.example.com/120/" + image
+		},
+	}
+
+	text := texttemplate.Must(texttemplate.New("text").Funcs(r.Funcs()).Parse(
+		`{{range .Sections}}{{range .Articles}}{{.Title}} ({{ago .PublishedAt}}){{end}}{{end}}`))
+	html := htmltemplate.Must(htmltemplate.New("html").Funcs(r.Funcs()).Parse(
+		`{{range .Sections}}{{range .Articles}}{{with thumbnail .URLToImage}}<img src="{{.}}">{{end}}{{end}}{{end}}`))
+	r.Templates = map[Format]Template{Text: text, HTML: html}
+
+	cases := []struct {
+		format   Format
+		expected string
+	}{
+		{Text, "Markets <rally> on *Friday* (3 hours ago)No image (" + Ago(time.Time{}, now) + ")"},
+		{HTML, `<img src="https://images.example.com/120/https://example.com/markets.jpg">`},
+	}
+
+	for _, i := range cases {
+		var buf bytes.Buffer
+		if err := r.Render(&buf, i.format, testDigest()); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != i.expected {
+			t.Errorf("Expected %v but got %v when case=%v", i.expected, buf.String(), i.format)
+		}
+	}
+}
+
+func titles(sections []Section) map[string][]string {
+	m := make(map[string][]string)
+	for _, s := range sections {
+		for _, a := range s.Articles {
+			m[s.Title] = append(m[s.Title], a.Title)
+		}
+	}
+	return m
+}
+
+func TestByCategory(t *testing.T) {
+	tag := func(category string) []newsapi.HeadlineTag {
+		return []newsapi.HeadlineTag{{Country: "us", Category: category}}
+	}
+
+	r := newsapi.TopHeadlinesMultiResp{Articles: []newsapi.TaggedArticle{
+		{Article: newsapi.Article{Title: "a"}, Tags: tag("technology")},
+		{Article: newsapi.Article{Title: "b"}},
+		{Article: newsapi.Article{Title: "c"}, Tags: append(tag("business"), tag("technology")...)},
+		{Article: newsapi.Article{Title: "d"}, Tags: tag("technology")},
+	}}
+
+	sections := ByCategory(r)
+
+	var order []string
+	for _, s := range sections {
+		order = append(order, s.Title)
+	}
+
+	expected := []string{"Business", "Technology", "Other"}
+	if !reflect.DeepEqual(order, expected) {
+		t.Errorf("Expected %v but got %v", expected, order)
+	}
+
+	expectedTitles := map[string][]string{"Business": {"c"}, "Technology": {"a", "d"}, "Other": {"b"}}
+	if got := titles(sections); !reflect.DeepEqual(got, expectedTitles) {
+		t.Errorf("Expected %v but got %v", expectedTitles, got)
+	}
+}
+
+func TestBySource(t *testing.T) {
+	source := func(name string) newsapi.ArticleSource {
+		return newsapi.ArticleSource{Name: name}
+	}
+
+	sections := BySource([]newsapi.Article{
+		{Title: "a", Source: source("BBC News")},
+		{Title: "b", Source: source("Reuters")},
+		{Title: "c", Source: source("Reuters")},
+		{Title: "d"},
+	})
+
+	if sections[0].Title != "Reuters" {
+		t.Errorf("Expected the source with the most articles first but got %v", sections[0].Title)
+	}
+
+	expected := map[string][]string{"Reuters": {"b", "c"}, "BBC News": {"a"}, "Other": {"d"}}
+	if got := titles(sections); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v but got %v", expected, got)
+	}
+}
+
+func TestByStory(t *testing.T) {
+	rep := newsapi.Article{Title: "Markets rally", URL: "https://example.com/a"}
+	stories := []cluster.Story{
+		{Representative: rep, Members: []newsapi.Article{{Title: "Stocks rise", URL: "https://example.org/b"}, rep}},
+		{Representative: newsapi.Article{Title: "Storm"}, Members: []newsapi.Article{{Title: "Storm"}}},
+	}
+
+	expected := map[string][]string{"Markets rally": {"Markets rally", "Stocks rise"}, "Storm": {"Storm"}}
+	if got := titles(ByStory(stories)); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v but got %v", expected, got)
+	}
+}
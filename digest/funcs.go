@@ -0,0 +1,169 @@
+package digest
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+// Funcs returns the helpers of the built-in templates. The result can be passed to the Funcs method of
+// text/template and html/template templates.
+func (r Renderer) Funcs() map[string]interface{} {
+	now := r.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	loc := r.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	return map[string]interface{}{
+		"ago": func(t time.Time) string {
+			return Ago(t, now)
+		},
+		"date": func(t time.Time) string {
+			return t.In(loc).Format("Mon, 2 Jan 2006")
+		},
+		"clock": func(t time.Time) string {
+			return t.In(loc).Format("15:04")
+		},
+		"truncate":  Truncate,
+		"wrap":      Wrap,
+		"content":   newsapi.Article.ContentText,
+		"thumbnail": r.thumbnail,
+		"md":        EscapeMarkdown,
+		"link":      EscapeLinkURL,
+	}
+}
+
+// isWebURL reports whether s is an absolute http(s) URL.
+func isWebURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func (r Renderer) thumbnail(image string) string {
+	if !isWebURL(image) {
+		return ""
+	}
+
+	if r.ThumbnailURL != nil {
+		return r.ThumbnailURL(image)
+	}
+
+	return image
+}
+
+// Ago describes t relative to now, e.g. "5 minutes ago" or "yesterday". Times more than a week ago are
+// shown as a date.
+func Ago(t, now time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return plural(int(d/time.Minute), "minute") + " ago"
+	case d < 24*time.Hour:
+		return plural(int(d/time.Hour), "hour") + " ago"
+	case d < 48*time.Hour:
+		return "yesterday"
+	case d < 7*24*time.Hour:
+		return plural(int(d/(24*time.Hour)), "day") + " ago"
+	}
+
+	return t.In(now.Location()).Format("2 Jan 2006")
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// Truncate cuts s off after at most n characters. It cuts at the last space if there is one in the second
+// half and appends "…". The "[+123 chars]" marker of the API is removed first.
+func Truncate(n int, s string) string {
+	s = newsapi.Article{Content: s}.ContentText()
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+
+	runes := []rune(s)
+	cut := string(runes[:n])
+	if i := strings.LastIndexByte(cut, ' '); i > len(cut)/2 {
+		cut = cut[:i]
+	}
+
+	return strings.TrimRight(cut, " ,;:.-") + "…"
+}
+
+// Wrap breaks s into lines of at most width characters. Words longer than width, e.g. URLs, get a line of
+// their own. Every line but the first is prefixed with indent.
+func Wrap(width int, indent, s string) string {
+	var (
+		b    strings.Builder
+		line int
+	)
+
+	for i, w := range strings.Fields(s) {
+		n := utf8.RuneCountInString(w)
+		switch {
+		case i == 0:
+		case line+1+n > width:
+			b.WriteString("\n" + indent)
+			line = utf8.RuneCountInString(indent)
+		default:
+			b.WriteByte(' ')
+			line++
+		}
+
+		b.WriteString(w)
+		line += n
+	}
+
+	return b.String()
+}
+
+var markdownSpecial = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`, "!", `\!`,
+)
+
+// list markers at the start of a line, e.g. "- a", "+ a" or "1. a"
+var markdownLineStart = regexp.MustCompile(`(?m)^([ \t]*)([-+]|[0-9]+[.)])`)
+
+var linkSpecial = strings.NewReplacer(
+	" ", "%20", "(", "%28", ")", "%29", "<", "%3C", ">", "%3E", `\`, "%5C",
+)
+
+// EscapeLinkURL returns s percent-encoded so it can be used as the destination of a Markdown link or
+// image. It returns "" if s isn't an http(s) URL, so links like "javascript:..." are never rendered.
+func EscapeLinkURL(s string) string {
+	if !isWebURL(s) {
+		return ""
+	}
+
+	return linkSpecial.Replace(s)
+}
+
+// EscapeMarkdown escapes the characters of s which Markdown would interpret as formatting, including list
+// markers and headings at the start of a line.
+func EscapeMarkdown(s string) string {
+	return markdownLineStart.ReplaceAllStringFunc(markdownSpecial.Replace(s), func(m string) string {
+		// the punctuation of the marker is always its last character
+		return m[:len(m)-1] + `\` + m[len(m)-1:]
+	})
+}
@@ -0,0 +1,136 @@
+package digest
+
+import (
+	"testing"
+	"time"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+func TestAgo(t *testing.T) {
+	cases := []struct {
+		ago      time.Duration
+		expected string
+	}{
+		{-time.Hour, "just now"},
+		{30 * time.Second, "just now"},
+		{time.Minute, "1 minute ago"},
+		{59 * time.Minute, "59 minutes ago"},
+		{2 * time.Hour, "2 hours ago"},
+		{30 * time.Hour, "yesterday"},
+		{3 * 24 * time.Hour, "3 days ago"},
+		{30 * 24 * time.Hour, "1 Apr 2020"},
+	}
+
+	for _, i := range cases {
+		if got := Ago(now.Add(-i.ago), now); got != i.expected {
+			t.Errorf("Expected %v but got %v when case=%v", i.expected, got, i.ago)
+		}
+	}
+
+	if got := Ago(time.Time{}, now); got != "" {
+		t.Errorf("Expected an empty string for the zero time but got %v", got)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	cases := []struct {
+		n        int
+		s        string
+		expected string
+	}{
+		{10, "short", "short"},
+		{0, "no limit at all", "no limit at all"},
+		{16, "Markets rallied on Friday", "Markets rallied…"},
+		{17, "Markets rallied, stocks rose", "Markets rallied…"},
+		{14, "Markets rallied on Friday", "Markets rallie…"},
+		{5, "Abcdefghij", "Abcde…"},
+		{8, "Äöüßäöüßäöü", "Äöüßäöüß…"},
+		{100, "The market rallied… [+1234 chars]", "The market rallied"},
+	}
+
+	for _, i := range cases {
+		if got := Truncate(i.n, i.s); got != i.expected {
+			t.Errorf("Expected %v but got %v when case=%v", i.expected, got, i)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	cases := []struct {
+		width    int
+		s        string
+		expected string
+	}{
+		{20, "short", "short"},
+		{20, "Markets rallied on Friday after a long week", "Markets rallied on\n  Friday after a\n  long week"},
+		{10, "see https://example.com/a/long/url", "see\n  https://example.com/a/long/url"},
+		{20, "  spaces   everywhere  ", "spaces everywhere"},
+	}
+
+	for _, i := range cases {
+		if got := Wrap(i.width, "  ", i.s); got != i.expected {
+			t.Errorf("Expected %q but got %q when case=%v", i.expected, got, i)
+		}
+	}
+}
+
+func TestFuncs(t *testing.T) {
+	berlin := time.FixedZone("CEST", 2*60*60)
+	funcs := Renderer{Now: now, Location: berlin}.Funcs()
+
+	late := time.Date(2020, 5, 1, 23, 30, 0, 0, time.UTC)
+	if got := funcs["date"].(func(time.Time) string)(late); got != "Sat, 2 May 2020" {
+		t.Errorf("Expected the date in the location but got %v", got)
+	}
+	if got := funcs["clock"].(func(time.Time) string)(late); got != "01:30" {
+		t.Errorf("Expected the time in the location but got %v", got)
+	}
+
+	content := funcs["content"].(func(newsapi.Article) string)
+	if got := content(newsapi.Article{Content: "Stocks rose... [+512 chars]"}); got != "Stocks rose" {
+		t.Errorf("Expected the content without the marker but got %v", got)
+	}
+
+	thumbnail := funcs["thumbnail"].(func(string) string)
+	cases := map[string]string{
+		"https://example.com/a.jpg": "https://example.com/a.jpg",
+		"http://example.com/a.jpg":  "http://example.com/a.jpg",
+		"javascript:alert(1)":       "",
+		"/relative.jpg":             "",
+		"":                          "",
+	}
+	for image, expected := range cases {
+		if got := thumbnail(image); got != expected {
+			t.Errorf("Expected %v but got %v when case=%v", expected, got, image)
+		}
+	}
+
+	links := map[string]string{
+		"https://example.com/a":                    "https://example.com/a",
+		"https://example.com/a_(b)":                "https://example.com/a_%28b%29",
+		"https://example.com/a) [x](javascript:1)": "https://example.com/a%29%20[x]%28javascript:1%29",
+		"javascript:alert(1)":                      "",
+		"data:text/html,<script>":                  "",
+		"":                                         "",
+	}
+	for u, expected := range links {
+		if got := EscapeLinkURL(u); got != expected {
+			t.Errorf("Expected %v but got %v when case=%v", expected, got, u)
+		}
+	}
+
+	markdown := map[string]string{
+		"*a* [b](c) #1":       `\*a\* \[b\](c) \#1`,
+		"# Breaking":          `\# Breaking`,
+		"- a\n+ b\n  1. c":    "\\- a\n\\+ b\n  1\\. c",
+		"2020) was a year":    `2020\) was a year`,
+		"a - b + c 1. d":      "a - b + c 1. d",
+		"Covid-19 cases rise": "Covid-19 cases rise",
+	}
+	for md, expected := range markdown {
+		if got := EscapeMarkdown(md); got != expected {
+			t.Errorf("Expected %v but got %v when case=%q", expected, got, md)
+		}
+	}
+}
@@ -0,0 +1,649 @@
+/*
+Package entity finds the people, organizations and places an article mentions without an NLP model.
+Names are looked up in gazetteers, i.e. lists of known names, and a few rules recognize people
+("President Macron", "Angela Merkel said") and organizations ("Bank of England", "Acme Corp").
+
+Gazetteers for the countries of the Country option, major cities and the news outlets of the Sources route
+are built in. Additional names can be added with Extractor.Add or loaded from a file with Extractor.Load:
+
+	var e entity.Extractor
+
+	for _, a := range r.Articles {
+		for _, s := range e.ExtractArticle(a) {
+			fmt.Println(s.Type, s.ID, s.Text)
+		}
+	}
+
+Gazetteer names are matched case-sensitively unless an entry says otherwise and the longest match wins,
+so "New York Times" is an outlet even though "New York" is a city.
+*/
+package entity
+
+import (
+	"bufio"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+// Type is the type of an entity.
+type Type string
+
+const (
+	Person       Type = "person"
+	Organization Type = "organization"
+	Country      Type = "country"
+	City         Type = "city"
+	Outlet       Type = "outlet"
+)
+
+//go:embed gazetteers/*.tsv
+var gazetteers embed.FS
+
+// Entry is a gazetteer entry.
+type Entry struct {
+	// ID is the normalized ID of the entity. If it's empty, it's derived from the type and the first name,
+	// e.g. "person:jane-doe".
+	ID   string
+	Type Type
+	// Names contains every way the entity can be written. The first name is the canonical one.
+	Names []string
+	// Country is the country code of the entity, if any. It's set for the built-in countries and cities.
+	Country string
+	// IgnoreCase makes the names match regardless of their case.
+	IgnoreCase bool
+}
+
+// Span represents a mention of an entity in a text.
+type Span struct {
+	Type Type   `json:"type"`
+	ID   string `json:"id"`
+	// Name is the canonical name of the entity and Text the way it's written in the text.
+	Name string `json:"name"`
+	Text string `json:"text"`
+	// Field is the field of the article the entity has been found in: "title", "description" or "content".
+	// It's empty for spans returned by Extractor.Extract.
+	Field string `json:"field,omitempty"`
+	// Start and End are the byte offsets of the mention in the field, i.e. Text is field[Start:End].
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+	Country string `json:"country,omitempty"`
+}
+
+// Entity represents an entity which has been mentioned one or more times.
+type Entity struct {
+	Type    Type   `json:"type"`
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Country string `json:"country,omitempty"`
+	Count   int    `json:"count"`
+}
+
+// Entities groups the spans by their ID. The entities are sorted by how often they are mentioned.
+func Entities(spans []Span) []Entity {
+	var (
+		entities []Entity
+		index    = make(map[string]int)
+	)
+
+	for _, s := range spans {
+		i, ok := index[s.ID]
+		if !ok {
+			i = len(entities)
+			index[s.ID] = i
+			entities = append(entities, Entity{Type: s.Type, ID: s.ID, Name: s.Name, Country: s.Country})
+		}
+		entities[i].Count++
+	}
+
+	sort.SliceStable(entities, func(i, j int) bool {
+		return entities[i].Count > entities[j].Count
+	})
+
+	return entities
+}
+
+// Extractor finds entities in texts. The zero value is ready to use and comes with the built-in gazetteers.
+// An Extractor is safe for concurrent use.
+type Extractor struct {
+	// SkipBuiltin disables the built-in gazetteers so that only added entries are found.
+	SkipBuiltin bool
+	// SkipRules disables the rules which find people and organizations that aren't part of a gazetteer.
+	SkipRules bool
+
+	mu      sync.RWMutex
+	loaded  bool
+	entries []Entry
+	root    *node
+}
+
+// node is a node of a trie of lowercased tokens.
+type node struct {
+	children map[string]*node
+	names    []name
+}
+
+type name struct {
+	entry  int
+	tokens []token
+}
+
+// Add adds entries to the gazetteer of the extractor.
+func (e *Extractor) Add(entries ...Entry) error {
+	for _, en := range entries {
+		if en.Type == "" {
+			return errors.New("The type of an entry cannot be empty")
+		}
+		if len(en.Names) == 0 {
+			return errors.New("An entry must have at least one name")
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.load()
+	for _, en := range entries {
+		e.add(en)
+	}
+
+	return nil
+}
+
+// Load reads entries of the type t from r. Every line consists of the ID of the entity followed by its names,
+// separated by tabs. Empty lines and lines starting with "#" are ignored.
+func (e *Extractor) Load(t Type, r io.Reader) error {
+	entries, err := parseGazetteer(t, r, false)
+	if err != nil {
+		return err
+	}
+
+	return e.Add(entries...)
+}
+
+func parseGazetteer(t Type, r io.Reader, withCountry bool) ([]Entry, error) {
+	var entries []Entry
+
+	minFields := 2
+	if withCountry {
+		minFields = 3
+	}
+
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, "\t")
+		if len(fields) < minFields {
+			return nil, fmt.Errorf("Line %d of the gazetteer doesn't contain a name", line)
+		}
+
+		en := Entry{ID: fields[0], Type: t, Names: fields[1:]}
+		if withCountry {
+			en.Country = fields[1]
+			en.Names = fields[2:]
+		}
+		entries = append(entries, en)
+	}
+
+	return entries, s.Err()
+}
+
+// load adds the built-in gazetteers. It has to be called with the write lock held.
+func (e *Extractor) load() {
+	if e.loaded {
+		return
+	}
+	e.loaded = true
+	e.root = &node{}
+
+	if e.SkipBuiltin {
+		return
+	}
+
+	builtin := []struct {
+		file        string
+		t           Type
+		withCountry bool
+	}{
+		{"countries", Country, false},
+		{"cities", City, true},
+		{"outlets", Outlet, false},
+	}
+
+	for _, b := range builtin {
+		f, err := gazetteers.Open("gazetteers/" + b.file + ".tsv")
+		if err != nil {
+			panic(err)
+		}
+
+		entries, err := parseGazetteer(b.t, f, b.withCountry)
+		f.Close()
+		if err != nil {
+			// the built-in gazetteers are checked by the tests
+			panic(err)
+		}
+
+		for _, en := range entries {
+			en.ID = string(b.t) + ":" + en.ID
+			if b.t == Country {
+				en.Country = strings.TrimPrefix(en.ID, "country:")
+			}
+			e.add(en)
+		}
+	}
+}
+
+func (e *Extractor) add(en Entry) {
+	if en.ID == "" {
+		en.ID = string(en.Type) + ":" + slug(en.Names[0])
+	}
+
+	e.entries = append(e.entries, en)
+	idx := len(e.entries) - 1
+
+	for _, n := range en.Names {
+		tokens := tokenize(n)
+		if len(tokens) == 0 {
+			continue
+		}
+
+		cur := e.root
+		for _, t := range tokens {
+			key := strings.ToLower(t.text)
+			next := cur.children[key]
+			if next == nil {
+				next = &node{}
+				if cur.children == nil {
+					cur.children = make(map[string]*node)
+				}
+				cur.children[key] = next
+			}
+			cur = next
+		}
+		cur.names = append(cur.names, name{entry: idx, tokens: tokens})
+	}
+}
+
+// Extract returns the entities mentioned in the text ordered by their position.
+func (e *Extractor) Extract(text string) []Span {
+	e.mu.RLock()
+	if !e.loaded {
+		e.mu.RUnlock()
+		e.mu.Lock()
+		e.load()
+		e.mu.Unlock()
+		e.mu.RLock()
+	}
+	defer e.mu.RUnlock()
+
+	tokens := tokenize(text)
+
+	candidates := e.gazetteerMatches(tokens)
+	if !e.SkipRules {
+		candidates = append(candidates, ruleMatches(tokens)...)
+	}
+
+	// longer mentions win over shorter ones and gazetteer entries over rules
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.to-a.from != b.to-b.from {
+			return a.to-a.from > b.to-b.from
+		}
+		if a.rule != b.rule {
+			return !a.rule
+		}
+		return a.from < b.from
+	})
+
+	used := make([]bool, len(tokens))
+	var spans []Span
+
+candidates:
+	for _, c := range candidates {
+		for i := c.from; i < c.to; i++ {
+			if used[i] {
+				continue candidates
+			}
+		}
+		for i := c.from; i < c.to; i++ {
+			used[i] = true
+		}
+
+		start, end := tokens[c.from].start, tokens[c.to-1].end
+		s := Span{
+			Type:  c.t,
+			Text:  text[start:end],
+			Start: start,
+			End:   end,
+		}
+
+		if c.rule {
+			s.Name = joinTokens(tokens[c.from:c.to])
+			s.ID = string(c.t) + ":" + slug(s.Name)
+		} else {
+			en := e.entries[c.entry]
+			s.ID, s.Name, s.Country = en.ID, en.Names[0], en.Country
+		}
+
+		spans = append(spans, s)
+	}
+
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].Start < spans[j].Start
+	})
+
+	return spans
+}
+
+// ExtractArticle returns the entities mentioned in the title, description and content of the article.
+// The truncation marker at the end of the content is ignored.
+func (e *Extractor) ExtractArticle(a newsapi.Article) []Span {
+	var spans []Span
+
+	// the offsets have to refer to the content field, so only the end of it is cut off
+	content := a.ContentText()
+	content = a.Content[:strings.Index(a.Content, content)+len(content)]
+
+	fields := []struct {
+		name, text string
+	}{
+		{"title", a.Title},
+		{"description", a.Description},
+		{"content", content},
+	}
+
+	for _, f := range fields {
+		for _, s := range e.Extract(f.text) {
+			s.Field = f.name
+			spans = append(spans, s)
+		}
+	}
+
+	return spans
+}
+
+type candidate struct {
+	from, to int
+	t        Type
+	entry    int
+	rule     bool
+}
+
+func (e *Extractor) gazetteerMatches(tokens []token) []candidate {
+	var candidates []candidate
+
+	for i := range tokens {
+		cur := e.root
+		for j := i; j < len(tokens); j++ {
+			cur = cur.children[strings.ToLower(tokens[j].text)]
+			if cur == nil {
+				break
+			}
+
+			for _, n := range cur.names {
+				en := e.entries[n.entry]
+				if matches(tokens[i:j+1], n.tokens, en.IgnoreCase) {
+					candidates = append(candidates, candidate{from: i, to: j + 1, t: en.Type, entry: n.entry})
+				}
+			}
+		}
+	}
+
+	return candidates
+}
+
+// matches reports whether the tokens of the text match the tokens of a name. Apart from the case,
+// the punctuation between the tokens has to match as well so that names don't span sentences.
+func matches(text, name []token, ignoreCase bool) bool {
+	for i := range text {
+		if i > 0 && text[i].punct != name[i].punct {
+			return false
+		}
+		if !ignoreCase && text[i].text != name[i].text {
+			return false
+		}
+	}
+
+	return true
+}
+
+// titles which are followed by the name of a person
+var titles = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true, "sir": true, "dame": true, "lord": true,
+	"president": true, "senator": true, "sen": true, "rep": true, "governor": true, "gov": true, "mayor": true,
+	"minister": true, "chancellor": true, "secretary": true, "pope": true, "king": true, "queen": true,
+	"prince": true, "princess": true, "judge": true, "ceo": true, "coach": true,
+	"herr": true, "frau": true, "präsident": true, "präsidentin": true, "kanzler": true, "kanzlerin": true,
+	"bundeskanzler": true, "bundeskanzlerin": true, "ministerin": true,
+	"monsieur": true, "madame": true, "mme": true, "président": true, "présidente": true, "ministre": true,
+	"señor": true, "señora": true, "presidente": true, "presidenta": true, "ministro": true, "ministra": true,
+}
+
+// verbs which are often preceded by the name of a person
+var speechVerbs = map[string]bool{
+	"said": true, "says": true, "told": true, "added": true, "sagte": true, "sagt": true, "erklärte": true,
+}
+
+// words which make a sequence of capitalized words an organization
+var orgWords = map[string]bool{
+	"inc": true, "corp": true, "corporation": true, "ltd": true, "llc": true, "plc": true, "group": true,
+	"bank": true, "university": true, "ministry": true, "party": true, "association": true, "council": true,
+	"committee": true, "commission": true, "agency": true, "company": true, "foundation": true, "institute": true,
+	"organization": true, "organisation": true, "union": true, "court": true, "parliament": true, "senate": true,
+	"congress": true, "department": true, "federation": true, "league": true, "airlines": true, "motors": true,
+	"technologies": true, "holdings": true, "ag": true, "gmbh": true, "se": true, "sa": true,
+	"partei": true, "universität": true, "ministerium": true, "gruppe": true, "université": true, "universidad": true,
+}
+
+// lowercase words which may connect the capitalized words of an organization
+var orgConnectors = map[string]bool{
+	"of": true, "for": true, "and": true, "&": true, "de": true, "du": true, "des": true, "der": true, "für": true, "del": true,
+}
+
+// determiners which aren't part of a name even though they are capitalized at the start of a sentence
+var determiners = map[string]bool{
+	"the": true, "a": true, "an": true, "der": true, "die": true, "das": true, "le": true, "la": true, "les": true,
+	"el": true, "los": true, "las": true, "this": true, "that": true, "in": true, "on": true, "at": true,
+}
+
+func ruleMatches(tokens []token) []candidate {
+	var candidates []candidate
+
+	for i := 0; i < len(tokens); i++ {
+		lower := strings.ToLower(tokens[i].text)
+		name := capitalized(tokens[i]) && !determiners[lower]
+
+		if name {
+			// the longest run of capitalized words and connectors which ends with a capitalized word
+			end, last := i+1, i+1
+			for end < len(tokens) && tokens[end].punct == "" {
+				if capitalized(tokens[end]) {
+					last = end + 1
+				} else if !orgConnectors[strings.ToLower(tokens[end].text)] {
+					break
+				}
+				end++
+			}
+
+			org := -1
+			for j := i; j < last; j++ {
+				if orgWords[strings.ToLower(tokens[j].text)] {
+					org = j
+				}
+			}
+
+			// the organization ends with its last organization word unless it's followed by a connector,
+			// e.g. in "Bank of England", but a title after it starts a new name
+			if org >= 0 {
+				// a connector before the organization word only belongs to the name if it follows another
+				// organization word, e.g. in "Bank of America Corp" but not in "Shares of Acme Corp"
+				from := i
+				for j := org - 1; j > i; j-- {
+					if orgConnectors[strings.ToLower(tokens[j].text)] && !orgWords[strings.ToLower(tokens[j-1].text)] {
+						from = j + 1
+						break
+					}
+				}
+
+				to := org + 1
+				if to+1 < last && orgConnectors[strings.ToLower(tokens[to].text)] && capitalized(tokens[to+1]) {
+					to += 2
+					for to < last && capitalized(tokens[to]) && !titles[strings.ToLower(tokens[to].text)] {
+						to++
+					}
+				}
+
+				candidates = append(candidates, candidate{from: from, to: to, t: Organization, rule: true})
+				i = to - 1
+				continue
+			}
+		}
+
+		// a title followed by up to three capitalized words, e.g. "Mr. Smith" or "President Joe Biden"
+		if titles[lower] {
+			from := i + 1
+			to := from
+			for to < len(tokens) && to-from < 3 && capitalized(tokens[to]) && (tokens[to].punct == "" || to == from && tokens[to].punct == ".") {
+				to++
+			}
+			if to > from {
+				candidates = append(candidates, candidate{from: from, to: to, t: Person, rule: true})
+				i = to - 1
+				continue
+			}
+		}
+
+		if !name {
+			continue
+		}
+
+		// two or three capitalized words followed by a verb of speech, e.g. "Angela Merkel said"
+		run := i + 1
+		for run < len(tokens) && run-i < 4 && capitalized(tokens[run]) && tokens[run].punct == "" {
+			run++
+		}
+		if n := run - i; n >= 2 && n <= 3 && run < len(tokens) && tokens[run].punct == "" && speechVerbs[tokens[run].text] {
+			candidates = append(candidates, candidate{from: i, to: run, t: Person, rule: true})
+			i = run - 1
+		}
+	}
+
+	return candidates
+}
+
+func capitalized(t token) bool {
+	r, _ := utf8.DecodeRuneInString(t.text)
+	return unicode.IsUpper(r)
+}
+
+type token struct {
+	text       string
+	start, end int
+	// punct contains the characters other than whitespace between the previous token and this one
+	punct string
+}
+
+// tokenize splits the text into words. Dots, hyphens, apostrophes, ampersands and slashes are part of
+// a word if they are surrounded by letters or digits, e.g. in "U.S" or "AT&T", and a possessive "'s" is
+// dropped. Chinese characters are treated as words on their own.
+func tokenize(text string) []token {
+	var (
+		tokens []token
+		start  = -1
+		prev   int
+	)
+
+	isWord := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+	}
+
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+
+		t := token{text: text[start:end], start: start, end: end}
+		for _, suffix := range []string{"'s", "’s"} {
+			if strings.HasSuffix(t.text, suffix) && len(t.text) > len(suffix) {
+				t.end -= len(suffix)
+				t.text = t.text[:len(t.text)-len(suffix)]
+			}
+		}
+
+		var punct []rune
+		for _, r := range text[prev:start] {
+			if !unicode.IsSpace(r) {
+				punct = append(punct, r)
+			}
+		}
+		t.punct = string(punct)
+
+		tokens = append(tokens, t)
+		prev = t.end
+		start = -1
+	}
+
+	for i, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flush(i)
+			start = i
+			flush(i + utf8.RuneLen(r))
+		case isWord(r):
+			if start < 0 {
+				start = i
+			}
+		case start >= 0 && strings.ContainsRune(".-'’&/", r):
+			next, _ := utf8.DecodeRuneInString(text[i+utf8.RuneLen(r):])
+			if !isWord(next) || unicode.Is(unicode.Han, next) {
+				flush(i)
+			}
+		default:
+			flush(i)
+		}
+	}
+	flush(len(text))
+
+	return tokens
+}
+
+func joinTokens(tokens []token) string {
+	texts := make([]string, len(tokens))
+	for i, t := range tokens {
+		texts[i] = t.text
+	}
+
+	return strings.Join(texts, " ")
+}
+
+// slug lowercases the name and replaces every run of characters other than letters and digits with a hyphen.
+func slug(name string) string {
+	var b strings.Builder
+
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+
+	return b.String()
+}
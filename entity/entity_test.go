@@ -0,0 +1,186 @@
+package entity
+
+import (
+	"strings"
+	"testing"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+func TestBuiltinGazetteers(t *testing.T) {
+	countries := []string{
+		"ae", "ar", "at", "au", "be", "bg", "br", "ca", "ch", "cn", "co", "cu", "cz", "de", "eg", "fr", "gb", "gr",
+		"hk", "hu", "id", "ie", "il", "in", "it", "jp", "kr", "lt", "lv", "ma", "mx", "my", "ng", "nl", "no", "nz",
+		"ph", "pl", "pt", "ro", "rs", "ru", "sa", "se", "sg", "si", "sk", "th", "tr", "tw", "ua", "us", "ve", "za",
+	}
+
+	var e Extractor
+	e.Extract("")
+
+	ids := make(map[string]bool)
+	for _, en := range e.entries {
+		if ids[en.ID] {
+			t.Errorf("Expected the ID %s to be unique", en.ID)
+		}
+		ids[en.ID] = true
+
+		if en.Type == City && en.Country == "" {
+			t.Errorf("Expected the city %s to have a country", en.ID)
+		}
+	}
+
+	for _, c := range countries {
+		if !ids["country:"+c] {
+			t.Errorf("Expected a gazetteer entry for the country %s", c)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	cases := []struct {
+		text   string
+		tokens []string
+	}{
+		{"The U.S. and AT&T's deal", []string{"The", "U.S", "and", "AT&T", "deal"}},
+		{"Washington, D.C. - a well-known city", []string{"Washington", "D.C", "a", "well-known", "city"}},
+		{"中国的经济", []string{"中", "国", "的", "经", "济"}},
+		{"", nil},
+	}
+
+	for _, i := range cases {
+		var texts []string
+		for _, tok := range tokenize(i.text) {
+			if i.text[tok.start:tok.end] != tok.text {
+				t.Errorf("Expected the offsets of %q to be correct when case=%q", tok.text, i.text)
+			}
+			texts = append(texts, tok.text)
+		}
+
+		if strings.Join(texts, "|") != strings.Join(i.tokens, "|") {
+			t.Errorf("Expected %q but got %q when case=%q", i.tokens, texts, i.text)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	var e Extractor
+
+	cases := []struct {
+		text     string
+		expected []string // type:id:text
+	}{
+		{"Germany and France agreed on a deal.", []string{"country:country:de:Germany", "country:country:fr:France"}},
+		{"Die Regierung in Deutschland", []string{"country:country:de:Deutschland"}},
+		{"The US economy grew, but not for all of us.", []string{"country:country:us:US"}},
+		{"New York Magazine reported from New York.", []string{"outlet:outlet:new-york-magazine:New York Magazine", "city:city:new-york:New York"}},
+		{"Talks in London. Paris reacted.", []string{"city:city:london:London", "city:city:paris:Paris"}},
+		{"President Joe Biden met Mr. Smith in Berlin.", []string{"person:person:joe-biden:Joe Biden", "person:person:smith:Smith", "city:city:berlin:Berlin"}},
+		{"Angela Merkel said the Bank of England had acted.", []string{"person:person:angela-merkel:Angela Merkel", "organization:organization:bank-of-england:Bank of England"}},
+		{"European Central Bank President Christine Lagarde spoke", []string{"organization:organization:european-central-bank:European Central Bank", "person:person:christine-lagarde:Christine Lagarde"}},
+		{"Shares of Acme Corp fell, Reuters reported.", []string{"organization:organization:acme-corp:Acme Corp", "outlet:outlet:reuters:Reuters"}},
+		{"中国的经济增长", []string{"country:country:cn:中国"}},
+		// the airline isn't the country
+		{"An Emirates flight to the United Arab Emirates", []string{"country:country:ae:United Arab Emirates"}},
+		{"the weather was nice", nil},
+	}
+
+	for _, i := range cases {
+		var got []string
+		for _, s := range e.Extract(i.text) {
+			if i.text[s.Start:s.End] != s.Text {
+				t.Errorf("Expected the offsets of %q to be correct when case=%q", s.Text, i.text)
+			}
+			got = append(got, string(s.Type)+":"+s.ID+":"+s.Text)
+		}
+
+		if strings.Join(got, "|") != strings.Join(i.expected, "|") {
+			t.Errorf("Expected %q but got %q when case=%q", i.expected, got, i.text)
+		}
+	}
+}
+
+func TestLongestMatch(t *testing.T) {
+	var e Extractor
+
+	spans := e.Extract("He told The Washington Post in Washington D.C.")
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans but got %v", spans)
+	}
+
+	if spans[0].ID != "outlet:the-washington-post" || spans[0].Name != "The Washington Post" {
+		t.Errorf("Expected the outlet to be found but got %v", spans[0])
+	}
+
+	if spans[1].ID != "city:washington" || spans[1].Text != "Washington D.C" || spans[1].Country != "us" {
+		t.Errorf("Expected the city to be found but got %v", spans[1])
+	}
+}
+
+func TestUserDictionary(t *testing.T) {
+	var e Extractor
+
+	err := e.Add(Entry{Type: Organization, Names: []string{"Acme Widgets", "ACME"}, IgnoreCase: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := e.Extract("acme widgets said Acme widgets and acme would grow")
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans but got %v", spans)
+	}
+	for _, s := range spans {
+		if s.ID != "organization:acme-widgets" || s.Name != "Acme Widgets" {
+			t.Errorf("Unexpected span %v", s)
+		}
+	}
+
+	if err := e.Load(Person, strings.NewReader("# id\tnames\nperson:jdoe\tJane Doe\tJ. Doe\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if spans := e.Extract("J. Doe arrived"); len(spans) != 1 || spans[0].ID != "person:jdoe" || spans[0].Name != "Jane Doe" {
+		t.Errorf("Expected the loaded entry to be found but got %v", spans)
+	}
+
+	if err := e.Load(Person, strings.NewReader("person:nobody\n")); err == nil {
+		t.Error("Expected an error for an entry without a name")
+	}
+
+	if err := e.Add(Entry{Names: []string{"Nobody"}}); err == nil {
+		t.Error("Expected an error for an entry without a type")
+	}
+
+	only := Extractor{SkipBuiltin: true, SkipRules: true}
+	only.Add(Entry{ID: "product:widget", Type: "product", Names: []string{"Widget"}})
+	if spans := only.Extract("Germany buys the Widget, Mr. Smith said"); len(spans) != 1 || spans[0].Type != "product" {
+		t.Errorf("Expected only the added entry to be found but got %v", spans)
+	}
+}
+
+func TestExtractArticle(t *testing.T) {
+	var e Extractor
+
+	a := newsapi.Article{
+		Title:       "Japan's exports rise",
+		Description: "Exports from Japan rose in May.",
+		Content:     "Tokyo - Exports rose in May, the ministry in Tokyo said… [+1500 chars]",
+	}
+
+	spans := e.ExtractArticle(a)
+
+	fields := map[string]string{"title": a.Title, "description": a.Description, "content": a.Content}
+	for _, s := range spans {
+		if fields[s.Field][s.Start:s.End] != s.Text {
+			t.Errorf("Expected the offsets of %q to refer to the %s", s.Text, s.Field)
+		}
+	}
+
+	entities := Entities(spans)
+	if len(entities) != 2 {
+		t.Fatalf("Expected 2 entities but got %v", entities)
+	}
+
+	if entities[0].ID != "country:jp" || entities[0].Count != 2 || entities[1].ID != "city:tokyo" || entities[1].Country != "jp" {
+		t.Errorf("Unexpected entities %v", entities)
+	}
+}
@@ -0,0 +1,531 @@
+/*
+Package epub turns collections of articles into EPUB 3 e-books, e.g. to read the coverage of a week on an
+e-reader. The metadata of the book is set on a Book, the articles are passed to one of its methods. The
+table of contents groups the articles by their source or by the day they were published:
+
+	r, err := c.Everything(ctx, newsapi.EverythingOpts{Q: "climate", From: weekAgo})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	b := epub.Book{Title: "Climate coverage", Author: "News Desk", GroupBy: epub.ByDay}
+	if err := b.WriteArticles(w, r.Articles); err != nil {
+		log.Fatal(err)
+	}
+
+The API only returns the beginning of the content of an article. The full text, e.g. extracted with the
+extract package, and images can be supplied with an Item:
+
+	items := []epub.Item{{Article: a, FullText: page.Text, Image: &epub.Image{Data: jpeg}}}
+	err := b.Write(w, items)
+
+The package never makes any requests. Images which aren't supplied by the caller aren't part of the book
+and images exceeding the size limits of the Book are left out, since many e-readers struggle with them.
+*/
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	_ "image/gif"  // register the decoder for the size limits
+	_ "image/jpeg" // register the decoder for the size limits
+	_ "image/png"  // register the decoder for the size limits
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+//go:embed templates
+var templateFiles embed.FS
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"x":         escape,
+	"timestamp": func(t time.Time) string { return t.UTC().Format("2006-01-02T15:04:05Z") },
+}).ParseFS(templateFiles, "templates/*"))
+
+const (
+	// DefaultMaxImageSize is the size of the largest image in bytes which is added to a book if
+	// Book.MaxImageSize isn't set.
+	DefaultMaxImageSize = 1 << 20
+	// DefaultMaxTotalImageSize is the total size of all images in bytes if Book.MaxTotalImageSize isn't
+	// set. Images which would exceed it are left out.
+	DefaultMaxTotalImageSize = 32 << 20
+	// DefaultMaxImagePixels is the number of pixels of the largest JPEG, PNG or GIF image if
+	// Book.MaxImagePixels isn't set.
+	DefaultMaxImagePixels = 4000000
+)
+
+// The media types of images which readers have to support.
+var imageTypes = map[string]string{
+	"image/jpeg":    ".jpg",
+	"image/png":     ".png",
+	"image/gif":     ".gif",
+	"image/webp":    ".webp",
+	"image/svg+xml": ".svg",
+}
+
+// Grouping is the way articles are grouped in the table of contents.
+type Grouping int
+
+// The supported groupings.
+const (
+	// BySource groups the articles by the name of their source.
+	BySource Grouping = iota
+	// ByDay groups the articles by the day they were published.
+	ByDay
+)
+
+// Image is an image which is embedded in a book.
+type Image struct {
+	Data []byte
+	// MediaType is the media type of the image, e.g. "image/jpeg". It's detected from the data if it's
+	// empty. JPEG, PNG, GIF, WebP and SVG images are supported.
+	MediaType string
+}
+
+// Item is an article with the content the API doesn't provide.
+type Item struct {
+	newsapi.Article
+	// FullText is the text of the article. Paragraphs are separated by empty lines or, if there are none,
+	// by line breaks. The content of the article is used if it's empty.
+	FullText string
+	// Image is shown above the text of the article.
+	Image *Image
+}
+
+// Book contains the metadata of an e-book.
+type Book struct {
+	Title string
+	// Author is the creator of the book, e.g. the name of a newsletter.
+	Author      string
+	Publisher   string
+	Description string
+	// Language is the language of the book as a BCP 47 tag. "en" is used if it's empty.
+	Language string
+	// Identifier is the unique identifier of the book. A UUID URN derived from the URLs of the articles is
+	// used if it's empty.
+	Identifier string
+	// Date is the date of publication and the modification date of the book. The current time is used if
+	// it's zero.
+	Date  time.Time
+	Cover *Image
+	// GroupBy is the grouping of the table of contents.
+	GroupBy Grouping
+	// Location is the time zone the articles are grouped into days and dates are shown in. UTC is used
+	// if it's nil.
+	Location *time.Location
+
+	// MaxImageSize, MaxTotalImageSize and MaxImagePixels limit the images. The defaults are used if they
+	// are zero.
+	MaxImageSize      int
+	MaxTotalImageSize int
+	MaxImagePixels    int
+}
+
+// WriteArticles writes a book containing the articles to w.
+func (b Book) WriteArticles(w io.Writer, articles []newsapi.Article) error {
+	items := make([]Item, len(articles))
+	for i, a := range articles {
+		items[i] = Item{Article: a}
+	}
+
+	return b.Write(w, items)
+}
+
+type imageFile struct {
+	ID         string
+	Href       string
+	MediaType  string
+	Properties string
+	data       []byte
+}
+
+type chapter struct {
+	ID    string
+	Href  string
+	Item  Item
+	Image *imageFile
+	// Paragraphs is the text split into paragraphs
+	Paragraphs []string
+	Byline     string
+}
+
+type section struct {
+	ID       string
+	Href     string
+	Title    string
+	Chapters []chapter
+}
+
+// pkg is passed to the templates
+type pkg struct {
+	Book
+	Identifier string
+	Language   string
+	Cover      *imageFile
+	Images     []*imageFile
+	Sections   []section
+}
+
+// Write writes a book containing the items to w. A book without any items isn't written since its table
+// of contents would be empty, which readers reject.
+func (b Book) Write(w io.Writer, items []Item) error {
+	if strings.TrimSpace(b.Title) == "" {
+		return errors.New("The book needs a title")
+	}
+
+	if len(items) == 0 {
+		return errors.New("The book needs at least one item")
+	}
+
+	p := pkg{Book: b, Identifier: b.Identifier, Language: b.Language}
+	if p.Language == "" {
+		p.Language = "en"
+	}
+	if p.Identifier == "" {
+		p.Identifier = identifier(items)
+	}
+	if p.Date.IsZero() {
+		p.Date = time.Now()
+	}
+	if p.Location == nil {
+		p.Location = time.UTC
+	}
+
+	limits := b.limits()
+	if b.Cover != nil {
+		p.Cover = limits.add(*b.Cover, "cover", "cover-image")
+		if p.Cover != nil {
+			p.Images = append(p.Images, p.Cover)
+		}
+	}
+
+	p.Sections = p.group(items)
+	n := 0
+	for i := range p.Sections {
+		s := &p.Sections[i]
+		s.ID = fmt.Sprintf("section-%03d", i+1)
+		s.Href = "text/" + s.ID + ".xhtml"
+
+		for j := range s.Chapters {
+			n++
+			c := &s.Chapters[j]
+			c.ID = fmt.Sprintf("article-%04d", n)
+			c.Href = "text/" + c.ID + ".xhtml"
+			c.Paragraphs = paragraphs(c.Item)
+			c.Byline = p.byline(c.Item.Article)
+
+			if c.Item.Image != nil {
+				c.Image = limits.add(*c.Item.Image, fmt.Sprintf("image-%04d", n), "")
+				if c.Image != nil {
+					p.Images = append(p.Images, c.Image)
+				}
+			}
+		}
+	}
+
+	return p.write(w)
+}
+
+// FormattedDate is the date of the book for the title page.
+func (p pkg) FormattedDate() string {
+	return p.Date.In(p.Location).Format("2 January 2006")
+}
+
+func (p pkg) write(w io.Writer) error {
+	z := zip.NewWriter(w)
+
+	// the mimetype has to be the first file and must neither be compressed nor have extra fields
+	mimetype := []byte("application/epub+zip")
+	fw, err := z.CreateRaw(&zip.FileHeader{
+		Name:               "mimetype",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE(mimetype),
+		CompressedSize64:   uint64(len(mimetype)),
+		UncompressedSize64: uint64(len(mimetype)),
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := fw.Write(mimetype); err != nil {
+		return err
+	}
+
+	files := []struct {
+		name, tmpl string
+		data       interface{}
+	}{
+		{"META-INF/container.xml", "container.xml", nil},
+		{"EPUB/package.opf", "package.opf", p},
+		{"EPUB/nav.xhtml", "nav.xhtml", p},
+		{"EPUB/toc.ncx", "toc.ncx", p},
+		{"EPUB/cover.xhtml", "cover.xhtml", p},
+		{"EPUB/style.css", "style.css", nil},
+	}
+	for _, s := range p.Sections {
+		files = append(files, struct {
+			name, tmpl string
+			data       interface{}
+		}{"EPUB/" + s.Href, "section.xhtml", struct {
+			pkg
+			Section section
+		}{p, s}})
+
+		for _, c := range s.Chapters {
+			files = append(files, struct {
+				name, tmpl string
+				data       interface{}
+			}{"EPUB/" + c.Href, "article.xhtml", struct {
+				pkg
+				Chapter chapter
+			}{p, c}})
+		}
+	}
+
+	for _, f := range files {
+		fw, err := z.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if err := templates.ExecuteTemplate(fw, f.tmpl, f.data); err != nil {
+			return err
+		}
+	}
+
+	for _, img := range p.Images {
+		// images are already compressed
+		fw, err := z.CreateHeader(&zip.FileHeader{Name: "EPUB/" + img.Href, Method: zip.Store})
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(img.data); err != nil {
+			return err
+		}
+	}
+
+	return z.Close()
+}
+
+func (p pkg) group(items []Item) []section {
+	type group struct {
+		key   string
+		title string
+		items []Item
+	}
+
+	var (
+		groups []*group
+		index  = make(map[string]*group)
+	)
+
+	for _, it := range items {
+		key, title := it.Source.Name, it.Source.Name
+		if p.GroupBy == ByDay {
+			key, title = "~", "Undated"
+			if !it.PublishedAt.IsZero() {
+				t := it.PublishedAt.In(p.Location)
+				key, title = t.Format("2006-01-02"), t.Format("Monday, 2 January 2006")
+			}
+		} else if key == "" {
+			key, title = "~", "Other sources"
+		}
+
+		g, ok := index[key]
+		if !ok {
+			g = &group{key: key, title: title}
+			index[key] = g
+			groups = append(groups, g)
+		}
+		g.items = append(g.items, it)
+	}
+
+	// "~" sorts after letters and digits, so undated articles and unknown sources come last
+	sort.SliceStable(groups, func(i, j int) bool {
+		if p.GroupBy == BySource {
+			return strings.ToLower(groups[i].key) < strings.ToLower(groups[j].key)
+		}
+		return groups[i].key < groups[j].key
+	})
+
+	sections := make([]section, len(groups))
+	for i, g := range groups {
+		sort.SliceStable(g.items, func(i, j int) bool {
+			return g.items[i].PublishedAt.Before(g.items[j].PublishedAt)
+		})
+
+		sections[i].Title = g.title
+		for _, it := range g.items {
+			sections[i].Chapters = append(sections[i].Chapters, chapter{Item: it})
+		}
+	}
+
+	return sections
+}
+
+func (p pkg) byline(a newsapi.Article) string {
+	var parts []string
+	var names []string
+	for _, au := range a.Authors() {
+		names = append(names, au.Name)
+	}
+	if len(names) > 0 {
+		parts = append(parts, strings.Join(names, ", "))
+	}
+	if a.Source.Name != "" && p.GroupBy != BySource {
+		parts = append(parts, a.Source.Name)
+	}
+	if !a.PublishedAt.IsZero() {
+		parts = append(parts, a.PublishedAt.In(p.Location).Format("2 January 2006, 15:04"))
+	}
+
+	return strings.Join(parts, " · ")
+}
+
+var (
+	blankLines = regexp.MustCompile(`\n\s*\n`)
+	spaces     = regexp.MustCompile(`\s+`)
+)
+
+// paragraphs splits the text of an item into paragraphs. The whitespace inside a paragraph is collapsed.
+func paragraphs(it Item) []string {
+	text := strings.ReplaceAll(it.FullText, "\r\n", "\n")
+	if strings.TrimSpace(text) == "" {
+		text = it.ContentText()
+	}
+
+	var parts []string
+	if blankLines.MatchString(text) {
+		parts = blankLines.Split(text, -1)
+	} else {
+		parts = strings.Split(text, "\n")
+	}
+
+	var ps []string
+	for _, s := range parts {
+		if s = strings.TrimSpace(spaces.ReplaceAllString(s, " ")); s != "" {
+			ps = append(ps, s)
+		}
+	}
+
+	return ps
+}
+
+// identifier derives a UUID URN from the URLs of the items, so the same articles always result in the
+// same identifier.
+func identifier(items []Item) string {
+	h := sha256.New()
+	for _, it := range items {
+		io.WriteString(h, it.URL)
+		h.Write([]byte{0})
+	}
+
+	u := h.Sum(nil)[:16]
+	// mark it as a name-based UUID of version 5
+	u[6] = u[6]&0x0f | 0x50
+	u[8] = u[8]&0x3f | 0x80
+
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+type imageLimits struct {
+	size, total, pixels int
+	used                int
+}
+
+func (b Book) limits() *imageLimits {
+	l := &imageLimits{size: b.MaxImageSize, total: b.MaxTotalImageSize, pixels: b.MaxImagePixels}
+	if l.size <= 0 {
+		l.size = DefaultMaxImageSize
+	}
+	if l.total <= 0 {
+		l.total = DefaultMaxTotalImageSize
+	}
+	if l.pixels <= 0 {
+		l.pixels = DefaultMaxImagePixels
+	}
+
+	return l
+}
+
+// add returns the file of the image or nil if the image isn't supported or exceeds the limits.
+func (l *imageLimits) add(img Image, id, properties string) *imageFile {
+	if len(img.Data) == 0 || len(img.Data) > l.size || l.used+len(img.Data) > l.total {
+		return nil
+	}
+
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(img.MediaType, ";")[0]))
+	if mediaType == "" {
+		mediaType = detectImage(img.Data)
+	}
+
+	ext, ok := imageTypes[mediaType]
+	if !ok {
+		return nil
+	}
+
+	if mediaType != "image/webp" && mediaType != "image/svg+xml" {
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(img.Data))
+		if err != nil || cfg.Width*cfg.Height > l.pixels {
+			return nil
+		}
+	}
+
+	l.used += len(img.Data)
+	return &imageFile{ID: id, Href: "images/" + id + ext, MediaType: mediaType, Properties: properties, data: img.Data}
+}
+
+func detectImage(b []byte) string {
+	mediaType := http.DetectContentType(b)
+	if mediaType == "text/xml; charset=utf-8" || mediaType == "text/plain; charset=utf-8" {
+		if bytes.Contains(b[:min(len(b), 1024)], []byte("<svg")) {
+			return "image/svg+xml"
+		}
+	}
+
+	return mediaType
+}
+
+// escape escapes s for XML. Characters which aren't allowed in XML are replaced with U+FFFD.
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// link returns the URL if it can be linked from a book.
+func link(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+
+	return raw
+}
+
+// Link is the link to the original article or "" if its URL isn't an http(s) URL.
+func (c chapter) Link() string {
+	return link(c.Item.URL)
+}
+
+// Title is the title of the article or a replacement if it doesn't have one.
+func (c chapter) Title() string {
+	if t := strings.TrimSpace(c.Item.Title); t != "" {
+		return t
+	}
+
+	return "Untitled"
+}
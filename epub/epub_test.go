@@ -0,0 +1,274 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"image"
+	"image/png"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+var published = time.Date(2020, 5, 1, 22, 30, 0, 0, time.UTC)
+
+func pngImage(t *testing.T, w, h int) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// readBook returns the files of a book and checks that every XML file is well-formed.
+func readBook(t *testing.T, b []byte) map[string]string {
+	// the mimetype has to be the first file, stored without compression and extra fields
+	if string(b[30:38]) != "mimetype" || string(b[38:58]) != "application/epub+zip" {
+		t.Fatalf("Expected the mimetype at the start of the archive but got %q", b[:58])
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f := r.File[0]; f.Method != zip.Store || len(f.Extra) != 0 {
+		t.Errorf("Expected the mimetype to be stored without extra fields but got method %d", f.Method)
+	}
+
+	files := make(map[string]string)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(data)
+
+		if strings.HasSuffix(f.Name, ".xhtml") || strings.HasSuffix(f.Name, ".opf") || strings.HasSuffix(f.Name, ".ncx") || strings.HasSuffix(f.Name, ".xml") {
+			d := xml.NewDecoder(bytes.NewReader(data))
+			for {
+				_, err := d.Token()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Expected %v to be well-formed but got %v", f.Name, err)
+				}
+			}
+		}
+	}
+
+	return files
+}
+
+func write(t *testing.T, b Book, items []Item) map[string]string {
+	var buf bytes.Buffer
+	if err := b.Write(&buf, items); err != nil {
+		t.Fatal(err)
+	}
+	return readBook(t, buf.Bytes())
+}
+
+func TestWrite(t *testing.T) {
+	items := []Item{
+		{
+			Article: newsapi.Article{
+				Source:      newsapi.ArticleSource{Name: "Reuters"},
+				Author:      "Jane Doe",
+				Title:       "Markets <rally> & stocks rise",
+				URL:         "https://example.com/markets?a=1&b=2",
+				PublishedAt: published,
+				Description: "Stocks rose\u0001 sharply.",
+				Content:     "Stocks rose sharply on Friday… [+1234 chars]",
+			},
+			FullText: "First paragraph\nstill the first.\n\n\nSecond paragraph.",
+			Image:    &Image{Data: pngImage(t, 10, 10)},
+		},
+		{Article: newsapi.Article{Source: newsapi.ArticleSource{Name: "BBC News"}, Title: "Storm", URL: "javascript:alert(1)", Content: "It rained. [+10 chars]"}},
+	}
+
+	b := Book{Title: "Week 18", Author: "News Desk", Date: published, Cover: &Image{Data: pngImage(t, 20, 30), MediaType: "image/png"}}
+	files := write(t, b, items)
+
+	opf := files["EPUB/package.opf"]
+	for _, s := range []string{
+		"<dc:title>Week 18</dc:title>",
+		"<dc:creator>News Desk</dc:creator>",
+		"<dc:language>en</dc:language>",
+		`<meta property="dcterms:modified">2020-05-01T22:30:00Z</meta>`,
+		`<item id="cover" href="images/cover.png" media-type="image/png" properties="cover-image"/>`,
+		`<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>`,
+	} {
+		if !strings.Contains(opf, s) {
+			t.Errorf("Expected %v in the package document but got %v", s, opf)
+		}
+	}
+
+	// every file has to be in the manifest and every item of the manifest has to exist
+	var manifest []string
+	for _, m := range regexp.MustCompile(`href="([^"]+)"`).FindAllStringSubmatch(opf, -1) {
+		manifest = append(manifest, "EPUB/"+m[1])
+		if _, ok := files["EPUB/"+m[1]]; !ok {
+			t.Errorf("Expected the file %v of the manifest to exist", m[1])
+		}
+	}
+	var content []string
+	for name := range files {
+		if strings.HasPrefix(name, "EPUB/") && name != "EPUB/package.opf" {
+			content = append(content, name)
+		}
+	}
+	sort.Strings(manifest)
+	sort.Strings(content)
+	if !reflect.DeepEqual(manifest, content) {
+		t.Errorf("Expected %v but got %v", content, manifest)
+	}
+
+	// BBC News comes before Reuters
+	article := files["EPUB/text/article-0002.xhtml"]
+	for _, s := range []string{
+		"<h1>Markets &lt;rally&gt; &amp; stocks rise</h1>",
+		`<p class="byline">Jane Doe · 1 May 2020, 22:30</p>`,
+		`<img src="../images/image-0002.png" alt=""/>`,
+		"<p class=\"lead\">Stocks rose� sharply.</p>",
+		"<p>First paragraph still the first.</p>\n    <p>Second paragraph.</p>",
+		`<a href="https://example.com/markets?a=1&amp;b=2">`,
+	} {
+		if !strings.Contains(article, s) {
+			t.Errorf("Expected %v in the article but got %v", s, article)
+		}
+	}
+
+	storm := files["EPUB/text/article-0001.xhtml"]
+	if !strings.Contains(storm, "<p>It rained.</p>") || strings.Contains(storm, "javascript") {
+		t.Errorf("Expected the content without the marker and without the link but got %v", storm)
+	}
+
+	nav := files["EPUB/nav.xhtml"]
+	if strings.Index(nav, "BBC News") > strings.Index(nav, "Reuters") || !strings.Contains(nav, `<a href="text/article-0002.xhtml">Markets &lt;rally&gt; &amp; stocks rise</a>`) {
+		t.Errorf("Expected the sources in alphabetical order but got %v", nav)
+	}
+
+	if err := (Book{}).WriteArticles(&bytes.Buffer{}, nil); err == nil {
+		t.Error("Expected an error for a book without a title")
+	}
+
+	if err := (Book{Title: "Empty"}).WriteArticles(&bytes.Buffer{}, nil); err == nil {
+		t.Error("Expected an error for a book without any articles")
+	}
+}
+
+func TestGroupByDay(t *testing.T) {
+	articles := []newsapi.Article{
+		{Title: "c", PublishedAt: published.Add(2 * time.Hour)},
+		{Title: "undated"},
+		{Title: "b", PublishedAt: published.Add(time.Hour)},
+		{Title: "a", PublishedAt: published},
+	}
+
+	cases := []struct {
+		loc      *time.Location
+		expected map[string][]string
+	}{
+		{nil, map[string][]string{
+			"Friday, 1 May 2020":   {"a", "b"},
+			"Saturday, 2 May 2020": {"c"},
+			"Undated":              {"undated"},
+		}},
+		{time.FixedZone("EST", -5*60*60), map[string][]string{
+			"Friday, 1 May 2020": {"a", "b", "c"},
+			"Undated":            {"undated"},
+		}},
+	}
+
+	for _, i := range cases {
+		items := make([]Item, len(articles))
+		for j, a := range articles {
+			items[j] = Item{Article: a}
+		}
+
+		p := pkg{Book: Book{GroupBy: ByDay, Location: i.loc}}
+		if p.Location == nil {
+			p.Location = time.UTC
+		}
+
+		got := make(map[string][]string)
+		var order []string
+		for _, s := range p.group(items) {
+			order = append(order, s.Title)
+			for _, c := range s.Chapters {
+				got[s.Title] = append(got[s.Title], c.Item.Title)
+			}
+		}
+
+		if !reflect.DeepEqual(got, i.expected) {
+			t.Errorf("Expected %v but got %v when case=%v", i.expected, got, i.loc)
+		}
+		if order[len(order)-1] != "Undated" {
+			t.Errorf("Expected undated articles last but got %v when case=%v", order, i.loc)
+		}
+	}
+}
+
+func TestImageLimits(t *testing.T) {
+	small := pngImage(t, 10, 10)
+
+	cases := []struct {
+		img      Image
+		book     Book
+		expected bool
+	}{
+		{Image{Data: small}, Book{}, true},
+		{Image{Data: small, MediaType: "IMAGE/PNG; charset=binary"}, Book{}, true},
+		{Image{Data: small}, Book{MaxImageSize: len(small) - 1}, false},
+		{Image{Data: pngImage(t, 200, 100)}, Book{MaxImagePixels: 10000}, false},
+		{Image{Data: []byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>")}, Book{}, true},
+		{Image{Data: []byte("%PDF-1.4")}, Book{}, false},
+		{Image{Data: []byte("not a png"), MediaType: "image/png"}, Book{}, false},
+		{Image{}, Book{}, false},
+	}
+
+	for _, i := range cases {
+		if got := i.book.limits().add(i.img, "image", "") != nil; got != i.expected {
+			t.Errorf("Expected %v but got %v when case=%v/%v", i.expected, got, i.img.MediaType, len(i.img.Data))
+		}
+	}
+
+	l := Book{MaxTotalImageSize: len(small) * 2}.limits()
+	var added int
+	for j := 0; j < 3; j++ {
+		if l.add(Image{Data: small}, "image", "") != nil {
+			added++
+		}
+	}
+	if added != 2 {
+		t.Errorf("Expected 2 images within the total limit but got %d", added)
+	}
+}
+
+func TestIdentifier(t *testing.T) {
+	items := []Item{{Article: newsapi.Article{URL: "https://example.com/a"}}}
+
+	id := identifier(items)
+	if !regexp.MustCompile(`^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(id) {
+		t.Errorf("Expected a UUID URN but got %v", id)
+	}
+	if identifier(items) != id {
+		t.Error("Expected the same identifier for the same articles")
+	}
+	if identifier(append(items, Item{})) == id {
+		t.Error("Expected a different identifier for different articles")
+	}
+}
@@ -54,12 +54,21 @@ func (c *Client) Everything(ctx context.Context, opts EverythingOpts) (Everythin
 		}
 	}
 
-	body, err := fetchGetRoute(ctx, "https://newsapi.org/v2/everything", c.APIKey, opts)
+	body, err := fetchGetRoute(ctx, "https://newsapi.org/v2/everything", c.APIKey, opts, c.Recorder)
 	if err != nil {
 		return EverythingResp{}, err
 	}
 
 	// we need this kind of type conversation because fetchGetRoute can only be parsed to the articleResp type
 	// otherwise there will be an error
-	return EverythingResp(body.(articleResp)), nil
+	r := EverythingResp(body.(articleResp))
+	if c.Normalizer != nil {
+		c.Normalizer.NormalizeAll(r.Articles)
+	}
+
+	if err := c.enrich(ctx, (*articleResp)(&r)); err != nil {
+		return EverythingResp{}, err
+	}
+
+	return r, nil
 }
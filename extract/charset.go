@@ -0,0 +1,112 @@
+package extract
+
+import (
+	"fmt"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var metaCharset = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([a-z0-9_\-:.]+)`)
+
+// windows1252 maps the bytes 0x80 to 0x9f of Windows-1252 to their unicode code points. The other
+// bytes are the same as in ISO-8859-1.
+var windows1252 = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', '\u008d', 'Ž', '\u008f',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', '\u009d', 'ž', 'Ÿ',
+}
+
+// iso885915 contains the code points of ISO-8859-15 which differ from ISO-8859-1.
+var iso885915 = map[byte]rune{
+	0xa4: '€', 0xa6: 'Š', 0xa8: 'š', 0xb4: 'Ž', 0xb8: 'ž', 0xbc: 'Œ', 0xbd: 'œ', 0xbe: 'Ÿ',
+}
+
+// UnsupportedCharsetError is returned if a page declares a charset which can't be decoded, e.g. GBK or
+// Shift_JIS. Decoding such a page as windows-1252 would only produce garbage.
+type UnsupportedCharsetError struct {
+	Charset string
+}
+
+func (e *UnsupportedCharsetError) Error() string {
+	return fmt.Sprintf("The charset %s isn't supported", e.Charset)
+}
+
+// detectCharset determines the charset of a document by looking at the byte order mark, the charset
+// parameter of the Content-Type header and the <meta> tags of the document in that order. If none of them
+// declares a charset, it defaults to utf-8 if the document is valid UTF-8 and to windows-1252 otherwise,
+// which is what browsers do as well. A declared charset which isn't supported is returned as an error.
+func detectCharset(contentType string, body []byte) (string, error) {
+	if len(body) >= 3 && body[0] == 0xef && body[1] == 0xbb && body[2] == 0xbf {
+		return "utf-8", nil
+	}
+
+	if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+		return declaredCharset(params["charset"])
+	}
+
+	head := body
+	if len(head) > 4096 {
+		head = head[:4096]
+	}
+	if m := metaCharset.FindSubmatch(head); m != nil {
+		return declaredCharset(string(m[1]))
+	}
+
+	if utf8.Valid(body) {
+		return "utf-8", nil
+	}
+
+	return "windows-1252", nil
+}
+
+func declaredCharset(label string) (string, error) {
+	if cs := normalizeCharset(label); cs != "" {
+		return cs, nil
+	}
+
+	return "", &UnsupportedCharsetError{Charset: strings.ToLower(strings.TrimSpace(label))}
+}
+
+// normalizeCharset maps the labels of the supported charsets to their canonical names and
+// returns an empty string for unknown labels.
+func normalizeCharset(label string) string {
+	switch strings.ToLower(strings.TrimSpace(label)) {
+	case "utf-8", "utf8", "unicode-1-1-utf-8":
+		return "utf-8"
+	case "iso-8859-1", "iso8859-1", "latin1", "l1", "us-ascii", "ascii", "windows-1252", "cp1252", "x-cp1252":
+		// browsers treat all of them as windows-1252 since it's a superset
+		return "windows-1252"
+	case "iso-8859-15", "iso8859-15", "latin9", "l9":
+		return "iso-8859-15"
+	}
+
+	return ""
+}
+
+// decode converts the body from the charset to UTF-8. Invalid UTF-8 sequences are replaced
+// with the unicode replacement character.
+func decode(body []byte, charset string) string {
+	switch charset {
+	case "windows-1252", "iso-8859-15":
+		buf := strings.Builder{}
+		buf.Grow(len(body))
+
+		for _, b := range body {
+			r := rune(b)
+			if charset == "windows-1252" && b >= 0x80 && b <= 0x9f {
+				r = windows1252[b-0x80]
+			} else if charset == "iso-8859-15" {
+				if m, ok := iso885915[b]; ok {
+					r = m
+				}
+			}
+			buf.WriteRune(r)
+		}
+
+		return buf.String()
+	}
+
+	s := strings.TrimPrefix(string(body), "\ufeff")
+	return strings.ToValidUTF8(s, "\ufffd")
+}
@@ -0,0 +1,526 @@
+/*
+Package extract fetches the page behind an article's URL and extracts its main text. The content returned
+by the NewsAPI service is cut off after ~200 characters, so this package can be used if the full text of an
+article is needed, e.g. for summarization.
+
+The main text is found with readability-style heuristics: paragraphs are scored by their length and the
+number of commas they contain, the scores are propagated to their ancestors, and the ancestor with the
+highest score (adjusted by its link density and its class and id) is taken as the article body.
+
+	var e extract.Extractor
+
+	res, err := e.ExtractArticle(ctx, article)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(res.Title, res.Byline, res.Confidence)
+	fmt.Println(res.Text)
+*/
+package extract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+// DefaultMaxBytes is the maximum size of a page if Extractor.MaxBytes isn't set.
+const DefaultMaxBytes = 5 << 20
+
+// ErrNotHTML is returned if the page behind a URL isn't an HTML document.
+var ErrNotHTML = errors.New("The fetched page isn't an HTML document")
+
+var (
+	unlikelyCandidates = regexp.MustCompile(`ad-break|agegate|banner|breadcrumb|combx|comment|community|cookie|disqus|extra|footer|gdpr|header|legends|menu|modal|newsletter|outbrain|pager|pagination|popup|promo|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|taboola|tweet|twitter|widget`)
+	maybeCandidates    = regexp.MustCompile(`and|article|body|column|content|main|shadow|story`)
+	positiveClass      = regexp.MustCompile(`article|body|content|entry|hentry|main|page|post|story|text|blog`)
+	negativeClass      = regexp.MustCompile(`-ad-|ad-|caption|comment|com-|contact|footer|footnote|hidden|masthead|media|meta|promo|related|scroll|share|shoutbox|sidebar|skyscraper|social|sponsor|shopping|tags|widget`)
+	bylineClass        = regexp.MustCompile(`byline|author|writtenby|p-author`)
+	bylinePrefix       = regexp.MustCompile(`(?i)^\s*(by|von|par|por|di|door)\s+`)
+
+	// these elements never contain any part of the article body
+	removedTags = map[string]bool{
+		"script": true, "style": true, "noscript": true, "nav": true, "aside": true, "form": true,
+		"iframe": true, "svg": true, "button": true, "select": true, "textarea": true, "template": true,
+	}
+
+	// the text of these elements is used to score their ancestors
+	scoredTags = map[string]bool{
+		"p": true, "pre": true, "td": true, "blockquote": true, "h2": true, "h3": true, "li": true,
+	}
+
+	// these elements are joined with a blank line in the extracted text
+	blockTags = map[string]bool{
+		"p": true, "pre": true, "blockquote": true, "h2": true, "h3": true, "h4": true, "li": true,
+	}
+)
+
+// Extractor fetches pages and extracts their main content. The zero value is ready to use.
+type Extractor struct {
+	// Client is used to fetch the pages. It follows redirects according to its CheckRedirect policy.
+	// http.DefaultClient is used if it's nil.
+	Client *http.Client
+	// MaxBytes is the maximum number of bytes read from a page. DefaultMaxBytes is used if it's smaller than 1.
+	MaxBytes int64
+	// UserAgent is sent with every request if it's not empty. Some sites block requests without one.
+	UserAgent string
+}
+
+// Result represents the content extracted from a page.
+type Result struct {
+	// URL is the URL of the page after all redirects have been followed.
+	URL         string    `json:"url"`
+	Title       string    `json:"title"`
+	Byline      string    `json:"byline"`
+	PublishedAt time.Time `json:"publishedAt"`
+	LeadImage   string    `json:"leadImage"`
+	// Text is the main text of the page. Paragraphs are separated by a blank line.
+	Text string `json:"text"`
+	// Charset is the charset the page has been decoded from.
+	Charset string `json:"charset"`
+	// Confidence is a value between 0 and 1 telling how sure the extractor is that Text is
+	// the actual article body and not e.g. a cookie banner or a list of links.
+	Confidence float64 `json:"confidence"`
+}
+
+// Extract fetches the page at rawURL and extracts its content.
+func (e *Extractor) Extract(ctx context.Context, rawURL string) (Result, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return Result{}, err
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	if e.UserAgent != "" {
+		req.Header.Set("User-Agent", e.UserAgent)
+	}
+
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return Result{}, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return Result{}, fmt.Errorf("Fetching %s failed with status %s", rawURL, resp.Status)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil && mt != "text/html" && mt != "application/xhtml+xml" {
+		return Result{}, ErrNotHTML
+	}
+
+	max := e.MaxBytes
+	if max < 1 {
+		max = DefaultMaxBytes
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, max))
+	if err != nil {
+		return Result{}, err
+	}
+
+	return ExtractHTML(resp.Request.URL, contentType, body)
+}
+
+// ExtractArticle extracts the content of the page behind the article's URL. Fields which couldn't be
+// extracted from the page are filled in with the corresponding fields of the article.
+func (e *Extractor) ExtractArticle(ctx context.Context, a newsapi.Article) (Result, error) {
+	res, err := e.Extract(ctx, a.URL)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if res.Title == "" {
+		res.Title = a.Title
+	}
+	if res.Byline == "" {
+		res.Byline = a.Author
+	}
+	if res.PublishedAt.IsZero() {
+		res.PublishedAt = a.PublishedAt
+	}
+	if res.LeadImage == "" {
+		res.LeadImage = a.URLToImage
+	}
+
+	return res, nil
+}
+
+// ExtractHTML extracts the content of an already fetched page. base is used to resolve relative
+// image URLs and may be nil. contentType is the value of the Content-Type header and is used to
+// detect the charset of the page. An *UnsupportedCharsetError is returned if the page declares a charset
+// which can't be decoded.
+func ExtractHTML(base *url.URL, contentType string, body []byte) (Result, error) {
+	charset, err := detectCharset(contentType, body)
+	if err != nil {
+		return Result{}, err
+	}
+	doc := parseHTML(decode(body, charset))
+
+	res := Result{Charset: charset}
+	if base != nil {
+		res.URL = base.String()
+	}
+
+	meta := metaTags(doc)
+
+	res.Title = firstNonEmpty(meta["og:title"], meta["twitter:title"])
+	if res.Title == "" {
+		if t := doc.find("title"); t != nil {
+			res.Title = t.textContent()
+		}
+	}
+	if res.Title == "" {
+		if h := doc.find("h1"); h != nil {
+			res.Title = h.textContent()
+		}
+	}
+
+	res.Byline = firstNonEmpty(meta["author"], meta["article:author"], meta["parsely-author"], meta["dc.creator"])
+	if res.Byline == "" || strings.HasPrefix(res.Byline, "http") {
+		res.Byline = findByline(doc)
+	}
+	res.Byline = bylinePrefix.ReplaceAllString(res.Byline, "")
+
+	res.PublishedAt = parseDate(firstNonEmpty(
+		meta["article:published_time"], meta["og:published_time"], meta["datepublished"],
+		meta["date"], meta["dc.date"], meta["pubdate"], meta["publishdate"], meta["parsely-pub-date"],
+	))
+	if res.PublishedAt.IsZero() {
+		res.PublishedAt = findTime(doc)
+	}
+
+	image := firstNonEmpty(meta["og:image"], meta["og:image:url"], meta["twitter:image"], meta["twitter:image:src"])
+
+	for _, n := range collect(doc, func(n *node) bool { return removedTags[n.tag] || n.tag == "head" }) {
+		n.remove()
+	}
+
+	top, confidence := topCandidate(doc)
+	if top != nil {
+		res.Text = blockText(top)
+		res.Confidence = confidence
+
+		if image == "" {
+			if img := top.find("img"); img != nil {
+				image = firstNonEmpty(img.attrs["src"], img.attrs["data-src"])
+			}
+		}
+	}
+
+	res.LeadImage = resolve(base, image)
+
+	return res, nil
+}
+
+// metaTags returns the content of all <meta> tags by their lower cased name, property or itemprop attribute.
+// The first tag wins if there are several tags with the same name.
+func metaTags(doc *node) map[string]string {
+	meta := make(map[string]string)
+
+	doc.walk(func(n *node) bool {
+		if n.tag != "meta" {
+			return true
+		}
+
+		content := strings.TrimSpace(n.attrs["content"])
+		for _, attr := range []string{"property", "name", "itemprop"} {
+			key := strings.ToLower(n.attrs[attr])
+			if _, ok := meta[key]; key != "" && content != "" && !ok {
+				meta[key] = content
+			}
+		}
+
+		return false
+	})
+
+	return meta
+}
+
+func findByline(doc *node) string {
+	var byline string
+
+	doc.walk(func(n *node) bool {
+		if byline != "" {
+			return false
+		}
+		if n.tag == "" || n.tag == "meta" {
+			return true
+		}
+
+		if n.attrs["rel"] == "author" || n.attrs["itemprop"] == "author" || bylineClass.MatchString(n.classAndID()) {
+			if t := n.textContent(); t != "" && utf8.RuneCountInString(t) < 100 {
+				byline = t
+				return false
+			}
+		}
+
+		return true
+	})
+
+	return byline
+}
+
+func findTime(doc *node) time.Time {
+	var t time.Time
+
+	doc.walk(func(n *node) bool {
+		if !t.IsZero() {
+			return false
+		}
+		if n.tag == "time" {
+			t = parseDate(n.attrs["datetime"])
+		}
+		return true
+	})
+
+	return t
+}
+
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+func parseDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}
+	}
+
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// topCandidate scores the elements of the document and returns the one which most likely contains the
+// article body together with a confidence between 0 and 1.
+func topCandidate(doc *node) (*node, float64) {
+	for _, n := range collect(doc, func(n *node) bool {
+		if n.tag == "" || n.tag == "body" || n.tag == "html" || n.tag == "article" || n.tag == "main" {
+			return false
+		}
+		ci := n.classAndID()
+		return unlikelyCandidates.MatchString(ci) && !maybeCandidates.MatchString(ci)
+	}) {
+		n.remove()
+	}
+
+	scores := make(map[*node]float64)
+
+	for _, p := range collect(doc, func(n *node) bool { return scoredTags[n.tag] }) {
+		text := p.textContent()
+		if utf8.RuneCountInString(text) < 25 || p.parent == nil {
+			continue
+		}
+
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(utf8.RuneCountInString(text))/100, 3)
+
+		level := 0
+		for anc := p.parent; anc != nil && anc.tag != "#document" && level < 3; anc = anc.parent {
+			if _, ok := scores[anc]; !ok {
+				scores[anc] = initialScore(anc)
+			}
+
+			switch level {
+			case 0:
+				scores[anc] += score
+			case 1:
+				scores[anc] += score / 2
+			default:
+				scores[anc] += score / float64(level*3)
+			}
+			level++
+		}
+	}
+
+	if len(scores) == 0 {
+		return nil, 0
+	}
+
+	type candidate struct {
+		n     *node
+		score float64
+	}
+
+	candidates := make([]candidate, 0, len(scores))
+	for n, s := range scores {
+		candidates = append(candidates, candidate{n, s * (1 - linkDensity(n))})
+	}
+
+	// break ties by document order so the result doesn't depend on the map iteration order
+	order := make(map[*node]int)
+	i := 0
+	doc.walk(func(n *node) bool {
+		order[n] = i
+		i++
+		return true
+	})
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return order[candidates[i].n] < order[candidates[j].n]
+	})
+
+	top := candidates[0]
+	if top.score <= 0 {
+		return nil, 0
+	}
+
+	// the confidence is lower if there's another part of the page which scores almost as high,
+	// candidates nested in or around the top candidate don't count since they share its paragraphs
+	var second float64
+	for _, c := range candidates[1:] {
+		if !isAncestor(c.n, top.n) && !isAncestor(top.n, c.n) {
+			second = c.score
+			break
+		}
+	}
+
+	length := float64(utf8.RuneCountInString(top.n.textContent()))
+	confidence := 0.5*math.Min(length/1500, 1) + 0.3*(1-linkDensity(top.n)) + 0.2*(1-second/top.score)
+
+	return top.n, math.Max(0, math.Min(confidence, 1))
+}
+
+func initialScore(n *node) float64 {
+	var score float64
+
+	switch n.tag {
+	case "article":
+		score = 10
+	case "div", "main", "section":
+		score = 5
+	case "pre", "td", "blockquote":
+		score = 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score = -3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th", "header", "footer":
+		score = -5
+	}
+
+	ci := n.classAndID()
+	if negativeClass.MatchString(ci) {
+		score -= 25
+	}
+	if positiveClass.MatchString(ci) {
+		score += 25
+	}
+
+	return score
+}
+
+// linkDensity returns the share of the text of n which is part of a link.
+func linkDensity(n *node) float64 {
+	total := utf8.RuneCountInString(n.textContent())
+	if total == 0 {
+		return 0
+	}
+
+	var links int
+	for _, a := range collect(n, func(c *node) bool { return c.tag == "a" }) {
+		links += utf8.RuneCountInString(a.textContent())
+	}
+
+	return float64(links) / float64(total)
+}
+
+// blockText returns the text of n with a blank line between all block elements.
+func blockText(n *node) string {
+	var paragraphs []string
+
+	n.walk(func(c *node) bool {
+		if !blockTags[c.tag] {
+			return true
+		}
+
+		if t := c.textContent(); t != "" && linkDensity(c) < 0.5 {
+			paragraphs = append(paragraphs, t)
+		}
+		return false
+	})
+
+	// fall back to the whole text if the content isn't structured in paragraphs
+	if len(paragraphs) == 0 {
+		return n.textContent()
+	}
+
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// collect returns all elements below n (including n itself) for which match returns true.
+func collect(n *node, match func(*node) bool) []*node {
+	var found []*node
+
+	n.walk(func(c *node) bool {
+		if match(c) {
+			found = append(found, c)
+			return false
+		}
+		return true
+	})
+
+	return found
+}
+
+func isAncestor(anc, n *node) bool {
+	for p := n.parent; p != nil; p = p.parent {
+		if p == anc {
+			return true
+		}
+	}
+
+	return false
+}
+
+func resolve(base *url.URL, ref string) string {
+	if ref == "" || base == nil {
+		return ref
+	}
+
+	u, err := base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+
+	return u.String()
+}
+
+func firstNonEmpty(s ...string) string {
+	for _, v := range s {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
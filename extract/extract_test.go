@@ -0,0 +1,202 @@
+package extract
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+func fixtureServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		http.ServeFile(w, r, "testdata/article.html")
+	})
+	mux.HandleFunc("/latin1", func(w http.ResponseWriter, r *http.Request) {
+		// the charset is only declared in the document itself
+		w.Header().Set("Content-Type", "text/html")
+		http.ServeFile(w, r, "testdata/latin1.html")
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/article", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/image.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestExtract(t *testing.T) {
+	srv := fixtureServer(t)
+	e := Extractor{Client: srv.Client()}
+
+	res, err := e.Extract(context.Background(), srv.URL+"/moved")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.URL != srv.URL+"/article" {
+		t.Errorf("Expected the redirect to be followed but got %s", res.URL)
+	}
+
+	if res.Title != "Central bank raises rates again" {
+		t.Errorf("Unexpected title %q", res.Title)
+	}
+
+	if res.Byline != "Jane Doe" {
+		t.Errorf("Unexpected byline %q", res.Byline)
+	}
+
+	if !res.PublishedAt.Equal(time.Date(2020, 6, 18, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected publishing date %v", res.PublishedAt)
+	}
+
+	if res.LeadImage != srv.URL+"/images/lead.jpg" {
+		t.Errorf("Expected the lead image to be resolved but got %s", res.LeadImage)
+	}
+
+	paragraphs := strings.Split(res.Text, "\n\n")
+	if len(paragraphs) != 5 {
+		t.Fatalf("Expected 5 paragraphs but got %d: %q", len(paragraphs), res.Text)
+	}
+
+	if !strings.HasPrefix(paragraphs[0], "The central bank raised") {
+		t.Errorf("Unexpected first paragraph %q", paragraphs[0])
+	}
+
+	if !strings.Contains(res.Text, "manufacturing sector & in housing") {
+		t.Error("Expected the entities to be decoded")
+	}
+
+	for _, noise := range []string{"cookies", "Most read", "Copyright", "not part of the article", "color: red"} {
+		if strings.Contains(res.Text, noise) {
+			t.Errorf("Expected %q not to be part of the text", noise)
+		}
+	}
+
+	if res.Confidence < 0.5 || res.Confidence > 1 {
+		t.Errorf("Expected a high confidence but got %f", res.Confidence)
+	}
+}
+
+func TestExtractCharset(t *testing.T) {
+	srv := fixtureServer(t)
+	e := Extractor{Client: srv.Client()}
+
+	res, err := e.Extract(context.Background(), srv.URL+"/latin1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Charset != "windows-1252" {
+		t.Errorf("Expected windows-1252 but got %s", res.Charset)
+	}
+
+	if res.Title != "Café culture" {
+		t.Errorf("Unexpected title %q", res.Title)
+	}
+
+	if !strings.HasPrefix(res.Text, "Le café du coin a rouvert ses portes, après") {
+		t.Errorf("Unexpected text %q", res.Text)
+	}
+}
+
+func TestExtractErrors(t *testing.T) {
+	srv := fixtureServer(t)
+	e := Extractor{Client: srv.Client()}
+
+	if _, err := e.Extract(context.Background(), srv.URL+"/image.png"); err != ErrNotHTML {
+		t.Errorf("Expected %v but got %v", ErrNotHTML, err)
+	}
+
+	if _, err := e.Extract(context.Background(), srv.URL+"/missing"); err == nil {
+		t.Error("Expected an error for a missing page but got nil")
+	}
+}
+
+func TestExtractArticle(t *testing.T) {
+	srv := fixtureServer(t)
+	e := Extractor{Client: srv.Client()}
+
+	a := newsapi.Article{URL: srv.URL + "/latin1", Author: "Marie Dupont", URLToImage: "https://example.com/a.jpg"}
+
+	res, err := e.ExtractArticle(context.Background(), a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Byline != a.Author || res.LeadImage != a.URLToImage {
+		t.Errorf("Expected the missing fields to be taken from the article but got %q and %q", res.Byline, res.LeadImage)
+	}
+}
+
+func TestDetectCharset(t *testing.T) {
+	cases := []struct {
+		contentType string
+		body        string
+		charset     string
+		unsupported string
+	}{
+		{"text/html; charset=UTF-8", "", "utf-8", ""},
+		{"text/html; charset=latin1", "", "windows-1252", ""},
+		{"text/html", `<meta charset="ISO-8859-15">`, "iso-8859-15", ""},
+		{"text/html", "\xef\xbb\xbf<p>bom</p>", "utf-8", ""},
+		{"text/html", "caf\xe9", "windows-1252", ""},
+		{"", "café", "utf-8", ""},
+		{"text/html; charset=GBK", "\xd6\xd0\xce\xc4", "", "gbk"},
+		{"text/html", `<meta http-equiv="Content-Type" content="text/html; charset=Shift_JIS">`, "", "shift_jis"},
+		{"text/html; charset=windows-1251", `<meta charset="utf-8">`, "", "windows-1251"},
+	}
+
+	for _, i := range cases {
+		cs, err := detectCharset(i.contentType, []byte(i.body))
+		if cs != i.charset {
+			t.Errorf("Expected %s but got %s when case=%v", i.charset, cs, i)
+		}
+
+		var unsupported string
+		if e, ok := err.(*UnsupportedCharsetError); ok {
+			unsupported = e.Charset
+		}
+		if unsupported != i.unsupported {
+			t.Errorf("Expected %v but got %v when case=%v", i.unsupported, err, i)
+		}
+	}
+}
+
+func TestParseHTML(t *testing.T) {
+	doc := parseHTML(`<div class="a"><p>One<p>Two <b>bold</b><ul><li>x<li>y</ul><img src="a.jpg"><br/>Tail</div>`)
+
+	div := doc.find("div")
+	if div == nil {
+		t.Fatal("Expected a div element")
+	}
+
+	var ps, lis int
+	div.walk(func(n *node) bool {
+		switch n.tag {
+		case "p":
+			ps++
+		case "li":
+			lis++
+		}
+		return true
+	})
+
+	if ps != 2 || lis != 2 {
+		t.Errorf("Expected 2 paragraphs and 2 list items but got %d and %d", ps, lis)
+	}
+
+	if text := div.textContent(); text != "One Two bold x y Tail" {
+		t.Errorf("Unexpected text %q", text)
+	}
+}
@@ -0,0 +1,325 @@
+package extract
+
+import (
+	"html"
+	"strings"
+)
+
+// node is an element or a text node of a parsed HTML document. The parser is far from being spec compliant
+// but it's forgiving enough for the markup of news sites and saves us from pulling in a dependency.
+type node struct {
+	tag      string // empty for text nodes
+	attrs    map[string]string
+	text     string
+	parent   *node
+	children []*node
+}
+
+var (
+	voidTags = map[string]bool{
+		"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+		"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+	}
+
+	// the content of these elements isn't parsed as HTML
+	rawTextTags = map[string]bool{
+		"script": true, "style": true, "textarea": true, "title": true, "noscript": true,
+	}
+
+	// opening one of these elements implicitly closes an open <p> element
+	closesP = map[string]bool{
+		"address": true, "article": true, "aside": true, "blockquote": true, "div": true, "dl": true,
+		"fieldset": true, "figure": true, "footer": true, "form": true, "h1": true, "h2": true, "h3": true,
+		"h4": true, "h5": true, "h6": true, "header": true, "hr": true, "main": true, "nav": true, "ol": true,
+		"p": true, "pre": true, "section": true, "table": true, "ul": true,
+	}
+)
+
+// parseHTML builds a tree out of the document and returns its root node.
+func parseHTML(doc string) *node {
+	var (
+		root = &node{tag: "#document"}
+		cur  = root
+	)
+
+	appendText := func(text string) {
+		if text != "" {
+			cur.children = append(cur.children, &node{text: html.UnescapeString(text), parent: cur})
+		}
+	}
+
+	for len(doc) > 0 {
+		i := strings.IndexByte(doc, '<')
+		if i < 0 {
+			appendText(doc)
+			break
+		}
+
+		appendText(doc[:i])
+		doc = doc[i:]
+
+		switch {
+		case strings.HasPrefix(doc, "<!--"):
+			end := strings.Index(doc, "-->")
+			if end < 0 {
+				return root
+			}
+			doc = doc[end+3:]
+
+		case strings.HasPrefix(doc, "<!") || strings.HasPrefix(doc, "<?"):
+			end := strings.IndexByte(doc, '>')
+			if end < 0 {
+				return root
+			}
+			doc = doc[end+1:]
+
+		case strings.HasPrefix(doc, "</"):
+			end := strings.IndexByte(doc, '>')
+			if end < 0 {
+				return root
+			}
+			name := strings.ToLower(strings.TrimSpace(doc[2:end]))
+			doc = doc[end+1:]
+
+			// pop everything up to the matching element, stray end tags are ignored
+			for n := cur; n != root; n = n.parent {
+				if n.tag == name {
+					cur = n.parent
+					break
+				}
+			}
+
+		default:
+			name, attrs, selfClosing, rest, ok := parseTag(doc)
+			if !ok {
+				// a lone "<" which isn't the start of a tag
+				appendText("<")
+				doc = doc[1:]
+				continue
+			}
+			doc = rest
+
+			cur = implicitlyClose(cur, root, name)
+
+			n := &node{tag: name, attrs: attrs, parent: cur}
+			cur.children = append(cur.children, n)
+
+			if rawTextTags[name] {
+				end := indexFold(doc, "</"+name)
+				if end < 0 {
+					end = len(doc)
+				}
+				if name == "title" || name == "textarea" {
+					n.children = append(n.children, &node{text: html.UnescapeString(doc[:end]), parent: n})
+				} else {
+					n.children = append(n.children, &node{text: doc[:end], parent: n})
+				}
+				doc = doc[end:]
+				if gt := strings.IndexByte(doc, '>'); gt >= 0 {
+					doc = doc[gt+1:]
+				}
+				continue
+			}
+
+			if !voidTags[name] && !selfClosing {
+				cur = n
+			}
+		}
+	}
+
+	return root
+}
+
+// implicitlyClose closes the elements which cannot contain an element called name.
+func implicitlyClose(cur, root *node, name string) *node {
+	switch {
+	case closesP[name]:
+		for n := cur; n != root; n = n.parent {
+			if n.tag == "p" {
+				return n.parent
+			}
+			if closesP[n.tag] && n.tag != "p" {
+				break
+			}
+		}
+	case name == "li":
+		for n := cur; n != root && n.tag != "ul" && n.tag != "ol"; n = n.parent {
+			if n.tag == "li" {
+				return n.parent
+			}
+		}
+	case name == "tr" || name == "td" || name == "th":
+		for n := cur; n != root && n.tag != "table"; n = n.parent {
+			if n.tag == name || name != "tr" && (n.tag == "td" || n.tag == "th") {
+				return n.parent
+			}
+		}
+	}
+
+	return cur
+}
+
+// parseTag parses a start tag at the beginning of doc.
+func parseTag(doc string) (name string, attrs map[string]string, selfClosing bool, rest string, ok bool) {
+	i := 1
+	for i < len(doc) && isNameChar(doc[i]) {
+		i++
+	}
+	if i == 1 {
+		return "", nil, false, doc, false
+	}
+
+	name = strings.ToLower(doc[1:i])
+	attrs = make(map[string]string)
+
+	for i < len(doc) {
+		for i < len(doc) && isSpace(doc[i]) {
+			i++
+		}
+		if i >= len(doc) {
+			break
+		}
+
+		switch doc[i] {
+		case '>':
+			return name, attrs, selfClosing, doc[i+1:], true
+		case '/':
+			selfClosing = true
+			i++
+			continue
+		}
+		selfClosing = false
+
+		start := i
+		for i < len(doc) && !isSpace(doc[i]) && doc[i] != '=' && doc[i] != '>' && doc[i] != '/' {
+			i++
+		}
+		key := strings.ToLower(doc[start:i])
+		if key == "" {
+			i++
+			continue
+		}
+
+		for i < len(doc) && isSpace(doc[i]) {
+			i++
+		}
+		if i >= len(doc) || doc[i] != '=' {
+			attrs[key] = ""
+			continue
+		}
+		i++
+		for i < len(doc) && isSpace(doc[i]) {
+			i++
+		}
+		if i >= len(doc) {
+			break
+		}
+
+		var val string
+		if q := doc[i]; q == '"' || q == '\'' {
+			end := strings.IndexByte(doc[i+1:], q)
+			if end < 0 {
+				return name, attrs, false, "", true
+			}
+			val = doc[i+1 : i+1+end]
+			i += end + 2
+		} else {
+			start := i
+			for i < len(doc) && !isSpace(doc[i]) && doc[i] != '>' {
+				i++
+			}
+			val = doc[start:i]
+		}
+
+		if _, dup := attrs[key]; !dup {
+			attrs[key] = html.UnescapeString(val)
+		}
+	}
+
+	return name, attrs, selfClosing, "", true
+}
+
+func isNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == ':'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// indexFold is like strings.Index but ignores the case of ASCII letters.
+func indexFold(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// walk calls fn for n and all of its descendants in document order. The children of a node
+// aren't visited if fn returns false.
+func (n *node) walk(fn func(*node) bool) {
+	if !fn(n) {
+		return
+	}
+
+	for _, c := range n.children {
+		c.walk(fn)
+	}
+}
+
+// find returns the first element called tag or nil.
+func (n *node) find(tag string) *node {
+	var found *node
+
+	n.walk(func(c *node) bool {
+		if found != nil {
+			return false
+		}
+		if c.tag == tag {
+			found = c
+			return false
+		}
+		return true
+	})
+
+	return found
+}
+
+// textContent returns the concatenated text of all text nodes below n with collapsed whitespace.
+func (n *node) textContent() string {
+	buf := strings.Builder{}
+
+	n.walk(func(c *node) bool {
+		if c.tag == "" {
+			buf.WriteString(c.text)
+			buf.WriteByte(' ')
+		}
+		return c.tag != "script" && c.tag != "style"
+	})
+
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
+
+// remove detaches n from its parent.
+func (n *node) remove() {
+	if n.parent == nil {
+		return
+	}
+
+	siblings := n.parent.children
+	for i, c := range siblings {
+		if c == n {
+			n.parent.children = append(siblings[:i:i], siblings[i+1:]...)
+			break
+		}
+	}
+	n.parent = nil
+}
+
+// classAndID returns the lower cased class and id attributes of n separated by a space.
+func (n *node) classAndID() string {
+	return strings.ToLower(n.attrs["class"] + " " + n.attrs["id"])
+}
@@ -0,0 +1,493 @@
+/*
+Package feed turns articles into RSS 2.0, Atom 1.0 and JSON Feed 1.1 documents, e.g. to republish the top
+headlines in a feed reader. The metadata of the feed is set on a Feed, the articles are passed to one of
+its methods. The articles of an EverythingResp and a TopHeadlinesResp can be passed the same way:
+
+	r, err := c.TopHeadlines(ctx, newsapi.TopHeadlinesOpts{Country: "gb", Category: "technology"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	f := feed.Feed{
+		Title:   "Technology headlines",
+		Link:    "https://news.example.com/",
+		FeedURL: "https://news.example.com/technology.xml",
+	}
+
+	if err := f.WriteRSS(w, r.Articles); err != nil {
+		log.Fatal(err)
+	}
+
+The image of an article is added as an enclosure and as a Media RSS element, since feed readers support
+either one or the other.
+*/
+package feed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+const (
+	atomNS  = "http://www.w3.org/2005/Atom"
+	mediaNS = "http://search.yahoo.com/mrss/"
+	dcNS    = "http://purl.org/dc/elements/1.1/"
+
+	jsonFeedVersion = "https://jsonfeed.org/version/1.1"
+)
+
+// Format is a feed format.
+type Format string
+
+// The supported formats. Their values are the content types of the documents.
+const (
+	RSS      Format = "application/rss+xml"
+	Atom     Format = "application/atom+xml"
+	JSONFeed Format = "application/feed+json"
+)
+
+// Feed contains the metadata of a feed. Only Title and Link are required.
+type Feed struct {
+	Title string
+	// Link is the URL of the website the feed belongs to.
+	Link string
+	// FeedURL is the URL the feed itself is served at. Atom and JSON Feed documents should have one.
+	FeedURL     string
+	Description string
+	// Language is a language code like "en" or "en-GB".
+	Language string
+	// Author is the name of the author or publisher of the feed.
+	Author    string
+	Copyright string
+	// Image is the URL of a logo of the feed.
+	Image string
+	// ID identifies the feed in Atom documents. FeedURL or Link is used if it's empty.
+	ID string
+	// Updated is the time the feed has been updated. The newest publishing time of the articles is used
+	// if it's zero.
+	Updated time.Time
+	// Generator is the name of the program which has created the feed.
+	Generator string
+}
+
+// Write writes the articles to w in the given format.
+func (f Feed) Write(w io.Writer, format Format, articles []newsapi.Article) error {
+	switch format {
+	case RSS:
+		return f.WriteRSS(w, articles)
+	case Atom:
+		return f.WriteAtom(w, articles)
+	case JSONFeed:
+		return f.WriteJSON(w, articles)
+	}
+
+	return errors.New("The feed format isn't supported")
+}
+
+func (f Feed) check() error {
+	if f.Title == "" || f.Link == "" {
+		return errors.New("A feed needs a title and a link")
+	}
+
+	return nil
+}
+
+// updated returns the time the feed has been updated.
+func (f Feed) updated(articles []newsapi.Article) time.Time {
+	if !f.Updated.IsZero() {
+		return f.Updated
+	}
+
+	var t time.Time
+	for _, a := range articles {
+		if a.PublishedAt.After(t) {
+			t = a.PublishedAt
+		}
+	}
+
+	if t.IsZero() {
+		return time.Now()
+	}
+
+	return t
+}
+
+// imageType guesses the content type of an image from the extension of its URL.
+func imageType(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		if t := mime.TypeByExtension(strings.ToLower(path.Ext(u.Path))); strings.HasPrefix(t, "image/") {
+			return t
+		}
+	}
+
+	// most images of news sites are JPEGs
+	return "image/jpeg"
+}
+
+// guid returns a unique identifier for the article. It's the URL of the article if it has one.
+func guid(a newsapi.Article) (string, bool) {
+	if a.URL != "" {
+		return a.URL, true
+	}
+
+	return "urn:newsapi:article:" + a.ID(), false
+}
+
+type rssDoc struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	MediaNS string     `xml:"xmlns:media,attr"`
+	DCNS    string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Self          *atomLink `xml:"atom:link,omitempty"`
+	Language      string    `xml:"language,omitempty"`
+	Copyright     string    `xml:"copyright,omitempty"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Generator     string    `xml:"generator,omitempty"`
+	Image         *rssImage `xml:"image,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssImage struct {
+	URL   string `xml:"url"`
+	Title string `xml:"title"`
+	Link  string `xml:"link"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title,omitempty"`
+	Link        string        `xml:"link,omitempty"`
+	Description string        `xml:"description,omitempty"`
+	Creator     string        `xml:"dc:creator,omitempty"`
+	Category    string        `xml:"category,omitempty"`
+	GUID        rssGUID       `xml:"guid"`
+	PubDate     string        `xml:"pubDate,omitempty"`
+	Enclosure   *rssEnclosure `xml:"enclosure,omitempty"`
+	Media       *mediaContent `xml:"media:content,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL string `xml:"url,attr"`
+	// the length is required, but it isn't known without fetching the image so it's 0 as the spec suggests
+	Length int    `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type mediaContent struct {
+	URL    string `xml:"url,attr"`
+	Medium string `xml:"medium,attr"`
+	Type   string `xml:"type,attr,omitempty"`
+}
+
+// WriteRSS writes the articles to w as an RSS 2.0 document. The author of an article is written as a
+// dc:creator element since the author element of RSS has to be an email address.
+func (f Feed) WriteRSS(w io.Writer, articles []newsapi.Article) error {
+	if err := f.check(); err != nil {
+		return err
+	}
+
+	ch := rssChannel{
+		Title:         f.Title,
+		Link:          f.Link,
+		Description:   f.Description,
+		Language:      f.Language,
+		Copyright:     f.Copyright,
+		LastBuildDate: f.updated(articles).Format(time.RFC1123Z),
+		Generator:     f.Generator,
+	}
+
+	// the description is required
+	if ch.Description == "" {
+		ch.Description = f.Title
+	}
+	if f.FeedURL != "" {
+		ch.Self = &atomLink{Href: f.FeedURL, Rel: "self", Type: string(RSS)}
+	}
+	if f.Image != "" {
+		ch.Image = &rssImage{URL: f.Image, Title: f.Title, Link: f.Link}
+	}
+
+	for _, a := range articles {
+		id, permaLink := guid(a)
+		item := rssItem{
+			Title:       a.Title,
+			Link:        a.URL,
+			Description: a.Description,
+			Creator:     a.Author,
+			Category:    a.Source.Name,
+			GUID:        rssGUID{IsPermaLink: permaLink, Value: id},
+		}
+
+		// an item needs either a title or a description
+		if item.Title == "" && item.Description == "" {
+			item.Description = a.ContentText()
+		}
+		if !a.PublishedAt.IsZero() {
+			item.PubDate = a.PublishedAt.Format(time.RFC1123Z)
+		}
+		if a.URLToImage != "" {
+			t := imageType(a.URLToImage)
+			item.Enclosure = &rssEnclosure{URL: a.URLToImage, Type: t}
+			item.Media = &mediaContent{URL: a.URLToImage, Medium: "image", Type: t}
+		}
+
+		ch.Items = append(ch.Items, item)
+	}
+
+	return writeXML(w, rssDoc{Version: "2.0", AtomNS: atomNS, MediaNS: mediaNS, DCNS: dcNS, Channel: ch})
+}
+
+type atomFeed struct {
+	XMLName   xml.Name    `xml:"feed"`
+	NS        string      `xml:"xmlns,attr"`
+	MediaNS   string      `xml:"xmlns:media,attr"`
+	Lang      string      `xml:"xml:lang,attr,omitempty"`
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Subtitle  string      `xml:"subtitle,omitempty"`
+	Updated   string      `xml:"updated"`
+	Links     []atomLink  `xml:"link"`
+	Author    *atomPerson `xml:"author,omitempty"`
+	Rights    string      `xml:"rights,omitempty"`
+	Generator string      `xml:"generator,omitempty"`
+	Logo      string      `xml:"logo,omitempty"`
+	Entries   []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	ID        string          `xml:"id"`
+	Title     atomText        `xml:"title"`
+	Updated   string          `xml:"updated"`
+	Published string          `xml:"published,omitempty"`
+	Links     []atomLink      `xml:"link"`
+	Authors   []atomPerson    `xml:"author"`
+	Summary   *atomText       `xml:"summary,omitempty"`
+	Content   *atomText       `xml:"content,omitempty"`
+	Source    *atomSource     `xml:"source,omitempty"`
+	Thumbnail *mediaThumbnail `xml:"media:thumbnail,omitempty"`
+}
+
+type atomSource struct {
+	Title string `xml:"title"`
+}
+
+type mediaThumbnail struct {
+	URL string `xml:"url,attr"`
+}
+
+// WriteAtom writes the articles to w as an Atom 1.0 document. Atom requires an author for every entry, so
+// the Author of the feed or, if it's empty, the source of the article is used for articles without an author.
+func (f Feed) WriteAtom(w io.Writer, articles []newsapi.Article) error {
+	if err := f.check(); err != nil {
+		return err
+	}
+
+	updated := f.updated(articles)
+
+	doc := atomFeed{
+		NS:        atomNS,
+		MediaNS:   mediaNS,
+		Lang:      f.Language,
+		ID:        f.ID,
+		Title:     f.Title,
+		Subtitle:  f.Description,
+		Updated:   updated.Format(time.RFC3339),
+		Links:     []atomLink{{Href: f.Link, Rel: "alternate", Type: "text/html"}},
+		Rights:    f.Copyright,
+		Generator: f.Generator,
+		Logo:      f.Image,
+	}
+
+	if doc.ID == "" {
+		doc.ID = f.FeedURL
+	}
+	if doc.ID == "" {
+		doc.ID = f.Link
+	}
+	if f.FeedURL != "" {
+		doc.Links = append(doc.Links, atomLink{Href: f.FeedURL, Rel: "self", Type: string(Atom)})
+	}
+	if f.Author != "" {
+		doc.Author = &atomPerson{Name: f.Author}
+	}
+
+	for _, a := range articles {
+		id, _ := guid(a)
+		entry := atomEntry{
+			ID:      id,
+			Title:   atomText{Type: "text", Value: a.Title},
+			Updated: updated.Format(time.RFC3339),
+		}
+
+		if !a.PublishedAt.IsZero() {
+			entry.Updated = a.PublishedAt.Format(time.RFC3339)
+			entry.Published = entry.Updated
+		}
+		if a.URL != "" {
+			entry.Links = append(entry.Links, atomLink{Href: a.URL, Rel: "alternate", Type: "text/html"})
+		}
+		if a.URLToImage != "" {
+			entry.Links = append(entry.Links, atomLink{Href: a.URLToImage, Rel: "enclosure", Type: imageType(a.URLToImage)})
+			entry.Thumbnail = &mediaThumbnail{URL: a.URLToImage}
+		}
+
+		for _, author := range a.Authors() {
+			entry.Authors = append(entry.Authors, atomPerson{Name: author.Name})
+		}
+		if len(entry.Authors) == 0 && f.Author == "" {
+			name := a.Source.Name
+			if name == "" {
+				name = f.Title
+			}
+			entry.Authors = []atomPerson{{Name: name}}
+		}
+
+		if a.Description != "" {
+			entry.Summary = &atomText{Type: "text", Value: a.Description}
+		}
+		if c := a.ContentText(); c != "" {
+			entry.Content = &atomText{Type: "text", Value: c}
+		}
+		if a.Source.Name != "" {
+			entry.Source = &atomSource{Title: a.Source.Name}
+		}
+
+		doc.Entries = append(doc.Entries, entry)
+	}
+
+	return writeXML(w, doc)
+}
+
+func writeXML(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type jsonFeed struct {
+	Version     string       `json:"version"`
+	Title       string       `json:"title"`
+	HomePageURL string       `json:"home_page_url,omitempty"`
+	FeedURL     string       `json:"feed_url,omitempty"`
+	Description string       `json:"description,omitempty"`
+	Icon        string       `json:"icon,omitempty"`
+	Authors     []jsonAuthor `json:"authors,omitempty"`
+	Language    string       `json:"language,omitempty"`
+	Items       []jsonItem   `json:"items"`
+}
+
+type jsonAuthor struct {
+	Name string `json:"name,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+type jsonItem struct {
+	ID            string       `json:"id"`
+	URL           string       `json:"url,omitempty"`
+	Title         string       `json:"title,omitempty"`
+	ContentText   string       `json:"content_text"`
+	Summary       string       `json:"summary,omitempty"`
+	Image         string       `json:"image,omitempty"`
+	DatePublished string       `json:"date_published,omitempty"`
+	Authors       []jsonAuthor `json:"authors,omitempty"`
+	Tags          []string     `json:"tags,omitempty"`
+}
+
+// WriteJSON writes the articles to w as a JSON Feed 1.1 document. Every item has a content_text field
+// since the spec requires it. It contains the description of the article if the content is empty.
+func (f Feed) WriteJSON(w io.Writer, articles []newsapi.Article) error {
+	if err := f.check(); err != nil {
+		return err
+	}
+
+	doc := jsonFeed{
+		Version:     jsonFeedVersion,
+		Title:       f.Title,
+		HomePageURL: f.Link,
+		FeedURL:     f.FeedURL,
+		Description: f.Description,
+		Icon:        f.Image,
+		Language:    f.Language,
+		Items:       []jsonItem{},
+	}
+
+	if f.Author != "" {
+		doc.Authors = []jsonAuthor{{Name: f.Author}}
+	}
+
+	for _, a := range articles {
+		id, _ := guid(a)
+		item := jsonItem{
+			ID:          id,
+			URL:         a.URL,
+			Title:       a.Title,
+			ContentText: a.ContentText(),
+			Summary:     a.Description,
+			Image:       a.URLToImage,
+		}
+
+		if item.ContentText == "" {
+			item.ContentText = a.Description
+		}
+		if !a.PublishedAt.IsZero() {
+			item.DatePublished = a.PublishedAt.Format(time.RFC3339)
+		}
+		for _, author := range a.Authors() {
+			item.Authors = append(item.Authors, jsonAuthor{Name: author.Name, URL: author.URL})
+		}
+		if a.Source.Name != "" {
+			item.Tags = []string{a.Source.Name}
+		}
+
+		doc.Items = append(doc.Items, item)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
@@ -0,0 +1,227 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+var published = time.Date(2020, 5, 1, 8, 30, 0, 0, time.UTC)
+
+var articles = []newsapi.Article{
+	{
+		Source:      newsapi.ArticleSource{ID: "example", Name: "Example News"},
+		Author:      "Jane Doe and John Smith",
+		Title:       `Markets <rally> & "bonds" fall`,
+		Description: "Stocks rose on Friday.",
+		URL:         "https://example.com/markets?id=1&ref=feed",
+		URLToImage:  "https://example.com/images/markets.png",
+		PublishedAt: published,
+		Content:     "Stocks rose on Friday\x00 after… [+1200 chars]",
+	},
+	{
+		Source: newsapi.ArticleSource{Name: "Other"},
+		Title:  "No URL, no image, no date",
+	},
+}
+
+var testFeed = Feed{
+	Title:    "Headlines",
+	Link:     "https://news.example.com/",
+	FeedURL:  "https://news.example.com/feed",
+	Language: "en",
+}
+
+func TestWriteRSS(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testFeed.WriteRSS(&buf, articles); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for _, ns := range []string{`xmlns:atom="` + atomNS + `"`, `xmlns:media="` + mediaNS + `"`, `xmlns:dc="` + dcNS + `"`} {
+		if !strings.Contains(out, ns) {
+			t.Errorf("Expected the namespace %s to be declared", ns)
+		}
+	}
+
+	var doc struct {
+		Version string `xml:"version,attr"`
+		Channel struct {
+			Title string `xml:"title"`
+			// the atom:link element is decoded as well
+			Links         []string `xml:"link"`
+			Description   string   `xml:"description"`
+			LastBuildDate string   `xml:"lastBuildDate"`
+			Items         []struct {
+				Title   string `xml:"title"`
+				Link    string `xml:"link"`
+				Creator string `xml:"creator"`
+				GUID    struct {
+					IsPermaLink string `xml:"isPermaLink,attr"`
+					Value       string `xml:",chardata"`
+				} `xml:"guid"`
+				PubDate   string `xml:"pubDate"`
+				Enclosure struct {
+					URL    string `xml:"url,attr"`
+					Length string `xml:"length,attr"`
+					Type   string `xml:"type,attr"`
+				} `xml:"enclosure"`
+				Media struct {
+					URL string `xml:"url,attr"`
+				} `xml:"content"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Expected well-formed XML but got %v", err)
+	}
+
+	ch := doc.Channel
+	if doc.Version != "2.0" || ch.Title != "Headlines" || len(ch.Links) != 2 || ch.Links[0] != testFeed.Link || ch.Description == "" {
+		t.Errorf("Expected the required channel elements but got %+v", ch)
+	}
+
+	if d, err := time.Parse(time.RFC1123Z, ch.LastBuildDate); err != nil || !d.Equal(published) {
+		t.Errorf("Expected the newest publishing time as lastBuildDate but got %q", ch.LastBuildDate)
+	}
+
+	if len(ch.Items) != 2 {
+		t.Fatalf("Expected 2 items but got %d", len(ch.Items))
+	}
+
+	item := ch.Items[0]
+	if item.Title != articles[0].Title || item.Link != articles[0].URL || item.Creator != articles[0].Author {
+		t.Errorf("Expected the escaped fields to survive but got %+v", item)
+	}
+	if item.GUID.IsPermaLink != "true" || item.GUID.Value != articles[0].URL {
+		t.Errorf("Expected the URL as permalink GUID but got %+v", item.GUID)
+	}
+	if d, err := time.Parse(time.RFC1123Z, item.PubDate); err != nil || !d.Equal(published) {
+		t.Errorf("Expected an RFC 822 date but got %q", item.PubDate)
+	}
+	if item.Enclosure.URL != articles[0].URLToImage || item.Enclosure.Length != "0" || item.Enclosure.Type != "image/png" || item.Media.URL != articles[0].URLToImage {
+		t.Errorf("Expected an enclosure and a media element but got %+v and %+v", item.Enclosure, item.Media)
+	}
+
+	if g := ch.Items[1].GUID; g.IsPermaLink != "false" || !strings.HasPrefix(g.Value, "urn:newsapi:article:") || ch.Items[1].PubDate != "" {
+		t.Errorf("Expected a non-permalink GUID and no date but got %+v", ch.Items[1])
+	}
+
+	if err := (Feed{Title: "No link"}).WriteRSS(&buf, articles); err == nil {
+		t.Error("Expected an error for a feed without a link")
+	}
+}
+
+func TestWriteAtom(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testFeed.WriteAtom(&buf, articles); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		XMLName xml.Name
+		ID      string `xml:"id"`
+		Title   string `xml:"title"`
+		Updated string `xml:"updated"`
+		Links   []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+		Entries []struct {
+			ID      string `xml:"id"`
+			Title   string `xml:"title"`
+			Updated string `xml:"updated"`
+			Links   []struct {
+				Href string `xml:"href,attr"`
+				Rel  string `xml:"rel,attr"`
+				Type string `xml:"type,attr"`
+			} `xml:"link"`
+			Authors []struct {
+				Name string `xml:"name"`
+			} `xml:"author"`
+			Content string `xml:"content"`
+		} `xml:"entry"`
+	}
+
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Expected well-formed XML but got %v", err)
+	}
+
+	if doc.XMLName.Space != atomNS || doc.XMLName.Local != "feed" {
+		t.Errorf("Expected an Atom feed but got %v", doc.XMLName)
+	}
+	if doc.ID != testFeed.FeedURL || doc.Title != testFeed.Title || len(doc.Links) != 2 || doc.Links[1].Rel != "self" {
+		t.Errorf("Expected the required feed elements but got %+v", doc)
+	}
+	if _, err := time.Parse(time.RFC3339, doc.Updated); err != nil {
+		t.Errorf("Expected an RFC 3339 date but got %q", doc.Updated)
+	}
+
+	if len(doc.Entries) != 2 {
+		t.Fatalf("Expected 2 entries but got %d", len(doc.Entries))
+	}
+
+	for _, e := range doc.Entries {
+		if e.ID == "" || e.Updated == "" || len(e.Authors) == 0 {
+			t.Errorf("Expected an id, updated and author element in every entry but got %+v", e)
+		}
+	}
+
+	e := doc.Entries[0]
+	if e.Title != articles[0].Title || len(e.Authors) != 2 || e.Authors[1].Name != "John Smith" {
+		t.Errorf("Unexpected entry %+v", e)
+	}
+	if len(e.Links) != 2 || e.Links[1].Rel != "enclosure" || e.Links[1].Type != "image/png" {
+		t.Errorf("Expected an alternate and an enclosure link but got %+v", e.Links)
+	}
+	if strings.Contains(e.Content, "chars]") || strings.ContainsRune(e.Content, 0) {
+		t.Errorf("Expected the content without the truncation marker and invalid characters but got %q", e.Content)
+	}
+	if doc.Entries[1].Authors[0].Name != "Other" {
+		t.Errorf("Expected the source as fallback author but got %+v", doc.Entries[1].Authors)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testFeed.Write(&buf, JSONFeed, articles); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc["version"] != jsonFeedVersion || doc["title"] != testFeed.Title || doc["feed_url"] != testFeed.FeedURL {
+		t.Errorf("Expected the required feed fields but got %v", doc)
+	}
+
+	items := doc["items"].([]interface{})
+	if len(items) != 2 {
+		t.Fatalf("Expected 2 items but got %d", len(items))
+	}
+
+	for _, i := range items {
+		item := i.(map[string]interface{})
+		if item["id"] == "" || item["content_text"] == nil {
+			t.Errorf("Expected an id and content_text in every item but got %v", item)
+		}
+	}
+
+	item := items[0].(map[string]interface{})
+	if item["image"] != articles[0].URLToImage || item["date_published"] != "2020-05-01T08:30:00Z" || item["title"] != articles[0].Title {
+		t.Errorf("Unexpected item %v", item)
+	}
+
+	if err := testFeed.Write(&buf, Format("text/plain"), articles); err == nil {
+		t.Error("Expected an error for an unsupported format")
+	}
+}
@@ -0,0 +1,501 @@
+/*
+Package imageprobe inspects the images behind the URLToImage field of articles. Many of those URLs point to
+images which don't exist anymore, to tracking pixels or to the same stock photo over and over again. A Prober
+fetches every image (up to a size limit), decodes its format and dimensions, computes perceptual hashes which
+can be used to find duplicates and flags broken and placeholder images:
+
+	var p imageprobe.Prober
+
+	results := p.ProbeArticles(ctx, r.Articles)
+	for i, res := range results {
+		if res.Broken || res.Placeholder {
+			r.Articles[i].URLToImage = ""
+		}
+	}
+
+	for _, group := range imageprobe.Duplicates(results, imageprobe.DefaultMaxDistance) {
+		fmt.Println("the same image is used by the articles", group)
+	}
+
+JPEG, PNG and GIF images are decoded with the standard library. The dimensions of WebP images are read from
+their header, but they aren't hashed.
+*/
+package imageprobe
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"  // register the GIF decoder
+	_ "image/jpeg" // register the JPEG decoder
+	_ "image/png"  // register the PNG decoder
+	"io"
+	"math/bits"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+const (
+	// DefaultMaxBytes is the maximum size of an image if Prober.MaxBytes isn't set. Larger images are
+	// only partially read, their dimensions are still known but they aren't hashed.
+	DefaultMaxBytes = 10 << 20
+	// DefaultMaxPixels is the maximum number of pixels of an image which is decoded if Prober.MaxPixels isn't
+	// set. A small file can declare huge dimensions, so the pixels are limited as well as the bytes.
+	DefaultMaxPixels = 4000000
+	// DefaultConcurrency is the number of images fetched at once if Prober.Concurrency isn't set.
+	DefaultConcurrency = 8
+	// DefaultPerHost is the number of images fetched at once from a single host if Prober.PerHost isn't set.
+	DefaultPerHost = 2
+	// DefaultMinSize is the minimum width and height of an image which isn't a placeholder if Prober.MinSize isn't set.
+	DefaultMinSize = 50
+	// DefaultMaxDistance is the maximum number of differing bits of the dHashes of two images that are
+	// considered to be the same.
+	DefaultMaxDistance = 6
+)
+
+// Result represents what is known about an image.
+type Result struct {
+	URL string `json:"url"`
+	// Status is the HTTP status code of the response. It's zero if the request failed.
+	Status      int    `json:"status"`
+	ContentType string `json:"contentType"`
+	// Format is the format of the image as returned by image.DecodeConfig, e.g. "jpeg", "png", "gif" or "webp".
+	Format string `json:"format"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	// Bytes is the number of bytes which have been read. It's at most MaxBytes + 1.
+	Bytes int64 `json:"bytes"`
+	// Truncated is true if the image is larger than MaxBytes.
+	Truncated bool `json:"truncated"`
+	// TooLarge is true if the image has more than MaxPixels pixels. It isn't decoded, so it isn't hashed either.
+	TooLarge bool `json:"tooLarge"`
+	// AHash and DHash are the average hash and the difference hash of the image. They are only set if Hashed is true.
+	AHash  uint64 `json:"aHash"`
+	DHash  uint64 `json:"dHash"`
+	Hashed bool   `json:"hashed"`
+	// Broken is true if the image couldn't be fetched or decoded.
+	Broken bool `json:"broken"`
+	// Placeholder is true if the image is a tracking pixel, a blank image or a known placeholder.
+	Placeholder bool `json:"placeholder"`
+	// Reason explains why the image is broken or a placeholder.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Prober fetches and inspects images. The zero value is ready to use.
+type Prober struct {
+	// Client is used to fetch the images. http.DefaultClient is used if it's nil.
+	Client *http.Client
+	// MaxBytes is the maximum number of bytes read from an image. DefaultMaxBytes is used if it's smaller than 1.
+	MaxBytes int64
+	// MaxPixels is the maximum number of pixels of an image which is decoded. DefaultMaxPixels is used if it's smaller than 1.
+	MaxPixels int64
+	// Concurrency is the number of images fetched at once by ProbeArticles. DefaultConcurrency is used if it's smaller than 1.
+	Concurrency int
+	// PerHost is the number of images fetched at once from a single host. DefaultPerHost is used if it's smaller than 1.
+	PerHost int
+	// MinSize is the minimum width and height of an image which isn't a placeholder. DefaultMinSize is used if it's smaller than 1.
+	MinSize int
+	// Placeholders contains the dHashes of known placeholder images, e.g. the default image of an outlet.
+	// Images within MaxDistance of one of them are flagged as placeholders.
+	Placeholders []uint64
+	// MaxDistance is the maximum number of differing bits of the dHash of an image and a placeholder.
+	// DefaultMaxDistance is used if it's smaller than 1.
+	MaxDistance int
+	// UserAgent is sent with every request if it's not empty.
+	UserAgent string
+}
+
+// Probe fetches and inspects the image at rawURL. Errors aren't returned but mark the result as broken.
+func (p *Prober) Probe(ctx context.Context, rawURL string) Result {
+	res := Result{URL: rawURL}
+
+	if rawURL == "" {
+		return broken(res, "The URL is empty")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return broken(res, err.Error())
+	}
+	req.Header.Set("Accept", "image/*")
+	if p.UserAgent != "" {
+		req.Header.Set("User-Agent", p.UserAgent)
+	}
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return broken(res, err.Error())
+	}
+	defer resp.Body.Close()
+
+	res.Status = resp.StatusCode
+	res.ContentType = resp.Header.Get("Content-Type")
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return broken(res, fmt.Sprintf("The request failed with status %s", resp.Status))
+	}
+
+	if mt, _, err := mime.ParseMediaType(res.ContentType); err == nil && !strings.HasPrefix(mt, "image/") && mt != "application/octet-stream" {
+		return broken(res, fmt.Sprintf("The content type %s isn't an image", mt))
+	}
+
+	max := p.MaxBytes
+	if max < 1 {
+		max = DefaultMaxBytes
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, max+1))
+	if err != nil {
+		return broken(res, err.Error())
+	}
+	res.Bytes = int64(len(body))
+	res.Truncated = res.Bytes > max
+
+	return p.inspect(res, body)
+}
+
+// inspect decodes the image and sets the remaining fields of the result.
+func (p *Prober) inspect(res Result, body []byte) Result {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(body))
+	if err != nil {
+		w, h, ok := webpSize(body)
+		if !ok {
+			return broken(res, "The image format is unknown or the image is corrupt")
+		}
+		cfg, format = image.Config{Width: w, Height: h}, "webp"
+	}
+	res.Format, res.Width, res.Height = format, cfg.Width, cfg.Height
+
+	minSize := p.MinSize
+	if minSize < 1 {
+		minSize = DefaultMinSize
+	}
+
+	if res.Width < minSize || res.Height < minSize {
+		return placeholder(res, fmt.Sprintf("The image is only %dx%d pixels large", res.Width, res.Height))
+	}
+
+	maxPixels := p.MaxPixels
+	if maxPixels < 1 {
+		maxPixels = DefaultMaxPixels
+	}
+	res.TooLarge = int64(res.Width)*int64(res.Height) > maxPixels
+
+	if res.Truncated || res.TooLarge || format == "webp" {
+		return res
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(body))
+	if err != nil {
+		return broken(res, "The image is corrupt")
+	}
+
+	gray := grayscale(img, 9, 8)
+	res.AHash, res.DHash, res.Hashed = aHash(grayscale(img, 8, 8)), dHash(gray), true
+
+	if blank(img) {
+		return placeholder(res, "The image consists of a single color")
+	}
+
+	maxDistance := p.MaxDistance
+	if maxDistance < 1 {
+		maxDistance = DefaultMaxDistance
+	}
+
+	for _, h := range p.Placeholders {
+		if Distance(h, res.DHash) <= maxDistance {
+			return placeholder(res, "The image is a known placeholder")
+		}
+	}
+
+	return res
+}
+
+func broken(res Result, reason string) Result {
+	res.Broken, res.Reason = true, reason
+	return res
+}
+
+func placeholder(res Result, reason string) Result {
+	res.Placeholder, res.Reason = true, reason
+	return res
+}
+
+// ProbeArticles probes the URLToImage of every article. The result at index i belongs to the article at index i.
+// Images are fetched concurrently but never more than PerHost at once from the same host, and an image used
+// by several articles is only fetched once.
+func (p *Prober) ProbeArticles(ctx context.Context, articles []newsapi.Article) []Result {
+	urls := make([]string, len(articles))
+	for i, a := range articles {
+		urls[i] = a.URLToImage
+	}
+
+	return p.ProbeAll(ctx, urls)
+}
+
+// ProbeAll probes every URL like ProbeArticles does.
+func (p *Prober) ProbeAll(ctx context.Context, urls []string) []Result {
+	concurrency := p.Concurrency
+	if concurrency < 1 {
+		concurrency = DefaultConcurrency
+	}
+
+	perHost := p.PerHost
+	if perHost < 1 {
+		perHost = DefaultPerHost
+	}
+
+	var (
+		results = make([]Result, len(urls))
+		unique  = make(map[string][]int)
+		order   []string
+	)
+
+	for i, u := range urls {
+		if _, ok := unique[u]; !ok {
+			order = append(order, u)
+		}
+		unique[u] = append(unique[u], i)
+	}
+
+	var (
+		mu    sync.Mutex
+		hosts = make(map[string]chan struct{})
+		sem   = make(chan struct{}, concurrency)
+		wg    sync.WaitGroup
+	)
+
+	hostSem := func(rawURL string) chan struct{} {
+		host := rawURL
+		if u, err := url.Parse(rawURL); err == nil {
+			host = u.Host
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		s, ok := hosts[host]
+		if !ok {
+			s = make(chan struct{}, perHost)
+			hosts[host] = s
+		}
+
+		return s
+	}
+
+	for _, u := range order {
+		wg.Add(1)
+		go func(u string) {
+			defer wg.Done()
+
+			var res Result
+
+			// the host limit is acquired first so that a slow host doesn't block the global slots
+			hs := hostSem(u)
+			select {
+			case hs <- struct{}{}:
+				select {
+				case sem <- struct{}{}:
+					res = p.Probe(ctx, u)
+					<-sem
+				case <-ctx.Done():
+					res = broken(Result{URL: u}, ctx.Err().Error())
+				}
+				<-hs
+			case <-ctx.Done():
+				res = broken(Result{URL: u}, ctx.Err().Error())
+			}
+
+			for _, i := range unique[u] {
+				results[i] = res
+			}
+		}(u)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// Distance returns the number of differing bits of two hashes.
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Duplicates groups the indices of the results whose images are the same according to their dHashes, even if
+// they have been fetched from different URLs. Only groups with more than one result are returned. Results which
+// haven't been hashed are ignored.
+func Duplicates(results []Result, maxDistance int) [][]int {
+	var (
+		groups [][]int
+		hashes []uint64
+	)
+
+	for i, r := range results {
+		if !r.Hashed {
+			continue
+		}
+
+		found := false
+		for g, h := range hashes {
+			if Distance(h, r.DHash) <= maxDistance {
+				groups[g] = append(groups[g], i)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			groups = append(groups, []int{i})
+			hashes = append(hashes, r.DHash)
+		}
+	}
+
+	var dups [][]int
+	for _, g := range groups {
+		if len(g) > 1 {
+			dups = append(dups, g)
+		}
+	}
+
+	return dups
+}
+
+// grayscale shrinks the image to w x h pixels by averaging the luminance of the pixels of every cell.
+// At most 16x16 pixels are sampled per cell to keep large images fast.
+func grayscale(img image.Image, w, h int) [][]float64 {
+	b := img.Bounds()
+	cells := make([][]float64, h)
+
+	for y := 0; y < h; y++ {
+		cells[y] = make([]float64, w)
+		y0, y1 := b.Min.Y+y*b.Dy()/h, b.Min.Y+(y+1)*b.Dy()/h
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+
+		for x := 0; x < w; x++ {
+			x0, x1 := b.Min.X+x*b.Dx()/w, b.Min.X+(x+1)*b.Dx()/w
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			stepX, stepY := (x1-x0+15)/16, (y1-y0+15)/16
+
+			var sum float64
+			var n int
+			for py := y0; py < y1; py += stepY {
+				for px := x0; px < x1; px += stepX {
+					sum += float64(color.GrayModel.Convert(img.At(px, py)).(color.Gray).Y)
+					n++
+				}
+			}
+			cells[y][x] = sum / float64(n)
+		}
+	}
+
+	return cells
+}
+
+// aHash sets a bit for every cell which is brighter than the mean.
+func aHash(cells [][]float64) uint64 {
+	var mean float64
+	for _, row := range cells {
+		for _, v := range row {
+			mean += v
+		}
+	}
+	mean /= 64
+
+	var h uint64
+	for _, row := range cells {
+		for _, v := range row {
+			h <<= 1
+			if v > mean {
+				h |= 1
+			}
+		}
+	}
+
+	return h
+}
+
+// dHash sets a bit for every cell which is brighter than its right neighbour. cells has to be 9 cells wide.
+func dHash(cells [][]float64) uint64 {
+	var h uint64
+	for _, row := range cells {
+		for x := 0; x < 8; x++ {
+			h <<= 1
+			if row[x] > row[x+1] {
+				h |= 1
+			}
+		}
+	}
+
+	return h
+}
+
+// blank reports whether every sampled pixel of the image has the same color.
+func blank(img image.Image) bool {
+	b := img.Bounds()
+	first := color.RGBA64Model.Convert(img.At(b.Min.X, b.Min.Y))
+
+	stepX, stepY := b.Dx()/32+1, b.Dy()/32+1
+	for y := b.Min.Y; y < b.Max.Y; y += stepY {
+		for x := b.Min.X; x < b.Max.X; x += stepX {
+			if color.RGBA64Model.Convert(img.At(x, y)) != first {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// webpSize reads the dimensions of a WebP image from its header.
+func webpSize(b []byte) (int, int, bool) {
+	if len(b) < 30 || string(b[0:4]) != "RIFF" || string(b[8:12]) != "WEBP" {
+		return 0, 0, false
+	}
+
+	chunk := b[20:]
+	switch string(b[12:16]) {
+	case "VP8 ":
+		// lossy: a frame tag of 3 bytes and a start code precede the 14 bit dimensions
+		if len(chunk) < 10 || chunk[3] != 0x9d || chunk[4] != 0x01 || chunk[5] != 0x2a {
+			return 0, 0, false
+		}
+		w := int(binary.LittleEndian.Uint16(chunk[6:8]) & 0x3fff)
+		h := int(binary.LittleEndian.Uint16(chunk[8:10]) & 0x3fff)
+		return w, h, true
+	case "VP8L":
+		// lossless: a signature byte is followed by two 14 bit values storing the dimensions minus one
+		if len(chunk) < 5 || chunk[0] != 0x2f {
+			return 0, 0, false
+		}
+		v := binary.LittleEndian.Uint32(chunk[1:5])
+		return int(v&0x3fff) + 1, int(v>>14&0x3fff) + 1, true
+	case "VP8X":
+		// extended: the canvas size is stored as two 24 bit values minus one after 4 bytes of flags
+		if len(chunk) < 10 {
+			return 0, 0, false
+		}
+		w := int(chunk[4]) | int(chunk[5])<<8 | int(chunk[6])<<16
+		h := int(chunk[7]) | int(chunk[8])<<8 | int(chunk[9])<<16
+		return w + 1, h + 1, true
+	}
+
+	return 0, 0, false
+}
@@ -0,0 +1,260 @@
+package imageprobe
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+// gradient returns an image with a diagonal gradient and a bright square so it has some structure to hash.
+func gradient(w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := uint8((x + y) * 255 / (w + h))
+			if x > w/2 && y < h/3 {
+				v = 255 - v
+			}
+			img.Set(x, y, color.RGBA{v, v / 2, 255 - v, 255})
+		}
+	}
+
+	return img
+}
+
+func encode(t *testing.T, img image.Image, format string) []byte {
+	var buf bytes.Buffer
+
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&buf, img)
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 80})
+	case "gif":
+		err = gif.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// webp is the header of a lossless 400x300 WebP image.
+var webp = func() []byte {
+	b := []byte("RIFF\x00\x00\x00\x00WEBPVP8L\x00\x00\x00\x00\x2f")
+	v := uint32(399) | uint32(299)<<14
+	b = append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+	return append(b, make([]byte, 16)...)
+}()
+
+func newServer(t *testing.T) *httptest.Server {
+	photo := gradient(320, 240)
+	blank := image.NewRGBA(image.Rect(0, 0, 200, 200))
+
+	files := map[string]struct {
+		contentType string
+		body        []byte
+	}{
+		"/photo.png":   {"image/png", encode(t, photo, "png")},
+		"/photo.jpg":   {"image/jpeg", encode(t, photo, "jpeg")},
+		"/pixel.gif":   {"image/gif", encode(t, image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.White}), "gif")},
+		"/blank.png":   {"image/png", encode(t, blank, "png")},
+		"/image.webp":  {"image/webp", webp},
+		"/page.html":   {"text/html", []byte("<html></html>")},
+		"/corrupt.jpg": {"image/jpeg", []byte("not an image")},
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", f.contentType)
+		w.Write(f.body)
+	}))
+}
+
+func TestProbe(t *testing.T) {
+	srv := newServer(t)
+	defer srv.Close()
+
+	var p Prober
+
+	cases := []struct {
+		path        string
+		format      string
+		width       int
+		hashed      bool
+		broken      bool
+		placeholder bool
+	}{
+		{"/photo.png", "png", 320, true, false, false},
+		{"/photo.jpg", "jpeg", 320, true, false, false},
+		{"/pixel.gif", "gif", 1, false, false, true},
+		{"/blank.png", "png", 200, true, false, true},
+		{"/image.webp", "webp", 400, false, false, false},
+		{"/page.html", "", 0, false, true, false},
+		{"/corrupt.jpg", "", 0, false, true, false},
+		{"/missing.jpg", "", 0, false, true, false},
+	}
+
+	for _, i := range cases {
+		res := p.Probe(context.Background(), srv.URL+i.path)
+
+		if res.Format != i.format || res.Width != i.width || res.Hashed != i.hashed || res.Broken != i.broken || res.Placeholder != i.placeholder {
+			t.Errorf("Unexpected result %+v when case=%v", res, i.path)
+		}
+
+		if (res.Broken || res.Placeholder) && res.Reason == "" {
+			t.Errorf("Expected a reason when case=%v", i.path)
+		}
+	}
+
+	if res := p.Probe(context.Background(), srv.URL+"/missing.jpg"); res.Status != http.StatusNotFound {
+		t.Errorf("Expected the status %d but got %d", http.StatusNotFound, res.Status)
+	}
+
+	if res := p.Probe(context.Background(), ""); !res.Broken {
+		t.Error("Expected an empty URL to be broken")
+	}
+}
+
+func TestProbeLimits(t *testing.T) {
+	srv := newServer(t)
+	defer srv.Close()
+
+	small := Prober{MaxBytes: 1000}
+	res := small.Probe(context.Background(), srv.URL+"/photo.png")
+	if !res.Truncated || res.Hashed || res.Width != 320 || res.Height != 240 || res.Broken || res.Bytes != 1001 {
+		t.Errorf("Expected a truncated image with known dimensions but got %+v", res)
+	}
+
+	var p Prober
+	photo := p.Probe(context.Background(), srv.URL+"/photo.png")
+
+	known := Prober{Placeholders: []uint64{photo.DHash}}
+	if res := known.Probe(context.Background(), srv.URL+"/photo.jpg"); !res.Placeholder {
+		t.Errorf("Expected a known placeholder to be flagged but got %+v", res)
+	}
+
+	// a placeholder which differs in 8 bits is only found with a larger distance
+	similar := Prober{Placeholders: []uint64{photo.DHash ^ 0xff}}
+	if res := similar.Probe(context.Background(), srv.URL+"/photo.png"); res.Placeholder {
+		t.Errorf("Expected the placeholder to be too different but got %+v", res)
+	}
+	similar.MaxDistance = 8
+	if res := similar.Probe(context.Background(), srv.URL+"/photo.png"); !res.Placeholder {
+		t.Errorf("Expected the placeholder to be within the distance but got %+v", res)
+	}
+
+	few := Prober{MaxPixels: 1000}
+	if res := few.Probe(context.Background(), srv.URL+"/photo.png"); !res.TooLarge || res.Hashed || res.Width != 320 || res.Broken {
+		t.Errorf("Expected an image with too many pixels but got %+v", res)
+	}
+}
+
+// a PNG header which declares a huge image isn't decoded
+func TestProbeDecompressionBomb(t *testing.T) {
+	ihdr := make([]byte, 17)
+	copy(ihdr, "IHDR")
+	binary.BigEndian.PutUint32(ihdr[4:], 50000)
+	binary.BigEndian.PutUint32(ihdr[8:], 50000)
+	ihdr[12], ihdr[13] = 8, 2 // 8 bit RGB
+
+	body := []byte("\x89PNG\r\n\x1a\n")
+	body = binary.BigEndian.AppendUint32(body, 13)
+	body = append(body, ihdr...)
+	body = binary.BigEndian.AppendUint32(body, crc32.ChecksumIEEE(ihdr))
+
+	var p Prober
+	res := p.inspect(Result{}, body)
+	if !res.TooLarge || res.Hashed || res.Broken || res.Width != 50000 {
+		t.Errorf("Expected the image to be too large but got %+v", res)
+	}
+}
+
+func TestProbeArticles(t *testing.T) {
+	srv := newServer(t)
+	defer srv.Close()
+
+	articles := []newsapi.Article{
+		{URLToImage: srv.URL + "/photo.png"},
+		{URLToImage: srv.URL + "/pixel.gif"},
+		{URLToImage: srv.URL + "/photo.jpg"},
+		{URLToImage: srv.URL + "/photo.png"},
+		{},
+	}
+
+	var p Prober
+	results := p.ProbeArticles(context.Background(), articles)
+
+	for i, res := range results {
+		if res.URL != articles[i].URLToImage {
+			t.Errorf("Expected the result %d to belong to %q but got %q", i, articles[i].URLToImage, res.URL)
+		}
+	}
+
+	if !results[4].Broken {
+		t.Error("Expected an article without an image to be broken")
+	}
+
+	dups := Duplicates(results, DefaultMaxDistance)
+	if len(dups) != 1 || len(dups[0]) != 3 || dups[0][0] != 0 || dups[0][1] != 2 || dups[0][2] != 3 {
+		t.Errorf("Expected the PNG and the JPEG of the same photo to be duplicates but got %v", dups)
+	}
+
+	if d := Distance(results[0].AHash, results[2].AHash); d > DefaultMaxDistance {
+		t.Errorf("Expected similar average hashes but got a distance of %d", d)
+	}
+}
+
+func TestProbePerHost(t *testing.T) {
+	var (
+		inFlight, max int32
+		mu            sync.Mutex
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		mu.Lock()
+		if n > max {
+			max = n
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	urls := make([]string, 6)
+	for i := range urls {
+		urls[i] = srv.URL + "/" + string(rune('a'+i)) + ".jpg"
+	}
+
+	p := Prober{PerHost: 2, Concurrency: 10}
+	p.ProbeAll(context.Background(), urls)
+
+	if max > 2 {
+		t.Errorf("Expected at most 2 requests at once but got %d", max)
+	}
+}
@@ -0,0 +1,352 @@
+/*
+Package keyphrase extracts the most important phrases of a set of articles. It offers two methods:
+TF-IDF, which ranks phrases by how often they occur in the articles compared to how common they
+are in general, and RAKE, which ranks phrases by how often their words occur together.
+
+The document frequencies TF-IDF relies on are collected in a Corpus which can be fed with articles over
+time, e.g. with every response of the Everything route, so the scores get better the more articles it has seen:
+
+	var c keyphrase.Corpus
+
+	c.Add(r.Articles...)
+
+	for _, p := range c.TFIDF(r.Articles, 10) {
+		fmt.Println(p.Text, p.Score)
+	}
+
+Stopwords are removed in all languages of the Language option. The language of an article is detected
+on the fly.
+*/
+package keyphrase
+
+import (
+	"embed"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+// DefaultMaxWords is the maximum number of words of a phrase if Corpus.MaxWords isn't set.
+const DefaultMaxWords = 3
+
+//go:embed stopwords/*.txt
+var stopwordFiles embed.FS
+
+var (
+	stopwordsMu    sync.Mutex
+	stopwordsCache = make(map[string]map[string]bool)
+)
+
+// Phrase represents a ranked keyphrase.
+type Phrase struct {
+	Text  string  `json:"text"`
+	Score float64 `json:"score"`
+	// Count is how often the phrase occurs in all articles.
+	Count int `json:"count"`
+	// Articles contains the indices of the articles the phrase occurs in.
+	Articles []int `json:"articles"`
+}
+
+// Stopwords returns the stopwords of a language. The language has to be one of the values of
+// the Language option, nil is returned for other languages.
+func Stopwords(lang string) map[string]bool {
+	stopwordsMu.Lock()
+	defer stopwordsMu.Unlock()
+
+	if sw, ok := stopwordsCache[lang]; ok {
+		return sw
+	}
+
+	b, err := stopwordFiles.ReadFile("stopwords/" + lang + ".txt")
+	if err != nil {
+		return nil
+	}
+
+	sw := make(map[string]bool)
+	for _, w := range strings.Fields(string(b)) {
+		sw[w] = true
+	}
+	stopwordsCache[lang] = sw
+
+	return sw
+}
+
+// Corpus collects the document frequencies of phrases. The zero value is ready to use and
+// a Corpus is safe for concurrent use.
+type Corpus struct {
+	// MaxWords is the maximum number of words of a phrase. DefaultMaxWords is used if it's smaller than 1.
+	MaxWords int
+
+	mu   sync.Mutex
+	docs int
+	df   map[string]int
+}
+
+// Add counts the phrases of every article towards the document frequencies.
+func (c *Corpus) Add(articles ...newsapi.Article) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.df == nil {
+		c.df = make(map[string]int)
+	}
+
+	for _, a := range articles {
+		seen := make(map[string]bool)
+		for _, chunk := range chunks(a) {
+			for _, p := range subPhrases(chunk, c.maxWords()) {
+				if !seen[p] {
+					seen[p] = true
+					c.df[p]++
+				}
+			}
+		}
+		c.docs++
+	}
+}
+
+// Docs returns the number of articles which have been added to the corpus.
+func (c *Corpus) Docs() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.docs
+}
+
+// DocFreq returns the number of added articles the phrase occurs in.
+func (c *Corpus) DocFreq(phrase string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.df[phrase]
+}
+
+func (c *Corpus) maxWords() int {
+	if c.MaxWords < 1 {
+		return DefaultMaxWords
+	}
+
+	return c.MaxWords
+}
+
+// TFIDF returns the limit highest ranked phrases of the articles. The score of a phrase is the sum of its
+// TF-IDF scores in every article. The articles don't have to be part of the corpus, but phrases which
+// the corpus has never seen get the highest possible inverse document frequency. All phrases are
+// returned if limit is smaller than 1.
+func (c *Corpus) TFIDF(articles []newsapi.Article, limit int) []Phrase {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	phrases := make(map[string]*Phrase)
+
+	for i, a := range articles {
+		counts := make(map[string]int)
+		for _, chunk := range chunks(a) {
+			for _, p := range subPhrases(chunk, c.maxWords()) {
+				counts[p]++
+			}
+		}
+
+		for text, n := range counts {
+			idf := math.Log(float64(c.docs+1)/float64(c.df[text]+1)) + 1
+			tf := 1 + math.Log(float64(n))
+
+			p := phrases[text]
+			if p == nil {
+				p = &Phrase{Text: text}
+				phrases[text] = p
+			}
+			p.Score += tf * idf
+			p.Count += n
+			p.Articles = append(p.Articles, i)
+		}
+	}
+
+	return ranked(phrases, limit)
+}
+
+// RAKE returns the limit highest ranked phrases of the articles using the Rapid Automatic Keyword Extraction
+// algorithm. The text is split into candidate phrases at stopwords and punctuation and every word is scored by
+// the ratio of its degree (the number of words it co-occurs with) to its frequency. The score of a phrase is
+// the sum of the scores of its words. Candidates with more than maxWords words are dropped, DefaultMaxWords is
+// used if maxWords is smaller than 1. All phrases are returned if limit is smaller than 1.
+func RAKE(articles []newsapi.Article, maxWords, limit int) []Phrase {
+	if maxWords < 1 {
+		maxWords = DefaultMaxWords
+	}
+
+	var (
+		freq       = make(map[string]int)
+		degree     = make(map[string]int)
+		phrases    = make(map[string]*Phrase)
+		candidates [][]chunk
+	)
+
+	for _, a := range articles {
+		cs := chunks(a)
+		candidates = append(candidates, cs)
+
+		for _, c := range cs {
+			for _, w := range c.words {
+				freq[w]++
+				degree[w] += len(c.words)
+			}
+		}
+	}
+
+	for i, cs := range candidates {
+		for _, c := range cs {
+			if len(c.words) > maxWords {
+				continue
+			}
+
+			text := c.join(0, len(c.words))
+
+			p := phrases[text]
+			if p == nil {
+				p = &Phrase{Text: text}
+				for _, w := range c.words {
+					p.Score += float64(degree[w]) / float64(freq[w])
+				}
+				phrases[text] = p
+			}
+			p.Count++
+			if len(p.Articles) == 0 || p.Articles[len(p.Articles)-1] != i {
+				p.Articles = append(p.Articles, i)
+			}
+		}
+	}
+
+	return ranked(phrases, limit)
+}
+
+func ranked(phrases map[string]*Phrase, limit int) []Phrase {
+	result := make([]Phrase, 0, len(phrases))
+	for _, p := range phrases {
+		sort.Ints(p.Articles)
+		result = append(result, *p)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].Text < result[j].Text
+	})
+
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result
+}
+
+// chunk is a run of consecutive words without any stopword or punctuation in between.
+type chunk struct {
+	words []string
+	han   bool // the words are single Chinese characters which are joined without spaces
+}
+
+func (c chunk) join(from, to int) string {
+	if c.han {
+		return strings.Join(c.words[from:to], "")
+	}
+
+	return strings.Join(c.words[from:to], " ")
+}
+
+// subPhrases returns every run of up to maxWords consecutive words of the chunk.
+func subPhrases(c chunk, maxWords int) []string {
+	var phrases []string
+
+	for i := range c.words {
+		for n := 1; n <= maxWords && i+n <= len(c.words); n++ {
+			phrases = append(phrases, c.join(i, i+n))
+		}
+	}
+
+	return phrases
+}
+
+// chunks splits the title, description and content of the article into chunks.
+func chunks(a newsapi.Article) []chunk {
+	text := a.Title + ".\n" + a.Description + ".\n" + a.ContentText()
+
+	lang, _ := newsapi.DetectLanguage(text)
+	stopwords := Stopwords(lang)
+
+	var (
+		result []chunk
+		cur    chunk
+		word   []rune
+	)
+
+	flushChunk := func() {
+		if len(cur.words) > 0 {
+			result = append(result, cur)
+		}
+		cur = chunk{}
+	}
+
+	flushWord := func() {
+		if len(word) == 0 {
+			return
+		}
+
+		w := strings.Trim(strings.ToLower(string(word)), "-")
+		word = word[:0]
+
+		if w == "" || stopwords[w] || isNumber(w) || len([]rune(w)) < 2 && !isHan([]rune(w)[0]) {
+			flushChunk()
+			return
+		}
+
+		if han := isHan([]rune(w)[0]); han != cur.han && len(cur.words) > 0 {
+			flushChunk()
+		}
+		cur.han = isHan([]rune(w)[0])
+		cur.words = append(cur.words, w)
+	}
+
+	for _, r := range text {
+		switch {
+		case isHan(r):
+			// there are no spaces in Chinese so every character is treated as a word
+			flushWord()
+			word = append(word, r)
+			flushWord()
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r):
+			word = append(word, r)
+		case r == '-' && len(word) > 0:
+			word = append(word, r)
+		case unicode.IsSpace(r):
+			flushWord()
+		default:
+			// punctuation ends a phrase
+			flushWord()
+			flushChunk()
+		}
+	}
+	flushWord()
+	flushChunk()
+
+	return result
+}
+
+func isHan(r rune) bool {
+	return unicode.Is(unicode.Han, r)
+}
+
+func isNumber(w string) bool {
+	for _, r := range w {
+		if !unicode.IsDigit(r) && r != '-' {
+			return false
+		}
+	}
+
+	return true
+}
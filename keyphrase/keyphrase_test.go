@@ -0,0 +1,127 @@
+package keyphrase
+
+import (
+	"testing"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+var articles = []newsapi.Article{
+	{
+		Title:       "Central bank raises interest rates as inflation climbs",
+		Description: "The central bank raised interest rates on Wednesday, the third increase this year.",
+		Content:     "The central bank raised interest rates on Wednesday as inflation climbed to its highest level in a decade… [+2300 chars]",
+	},
+	{
+		Title:       "Mortgage costs rise after interest rates decision",
+		Description: "Homeowners face higher mortgage costs after the central bank decision.",
+		Content:     "Homeowners face higher mortgage costs after the central bank raised interest rates again… [+1800 chars]",
+	},
+	{
+		Title:       "Football club signs striker in record transfer",
+		Description: "The club confirmed the record transfer of the striker on Tuesday.",
+		Content:     "The club confirmed the record transfer on Tuesday after weeks of talks… [+1500 chars]",
+	},
+}
+
+func contains(phrases []Phrase, text string) (Phrase, bool) {
+	for _, p := range phrases {
+		if p.Text == text {
+			return p, true
+		}
+	}
+
+	return Phrase{}, false
+}
+
+func TestStopwords(t *testing.T) {
+	for _, lang := range []string{"ar", "de", "en", "es", "fr", "he", "it", "nl", "no", "pt", "ru", "se", "ud", "zh"} {
+		if len(Stopwords(lang)) == 0 {
+			t.Errorf("Expected stopwords for %s", lang)
+		}
+	}
+
+	if Stopwords("xx") != nil {
+		t.Error("Expected no stopwords for an unknown language")
+	}
+}
+
+func TestChunks(t *testing.T) {
+	cs := chunks(newsapi.Article{Title: "The Prime Minister said, on Monday, that COVID-19 cases rose by 12%"})
+
+	expected := []string{"prime minister", "monday", "covid-19 cases rose"}
+	if len(cs) != len(expected) {
+		t.Fatalf("Expected %d chunks but got %d: %v", len(expected), len(cs), cs)
+	}
+
+	for i, c := range cs {
+		if text := c.join(0, len(c.words)); text != expected[i] {
+			t.Errorf("Expected %q but got %q", expected[i], text)
+		}
+	}
+
+	zh := chunks(newsapi.Article{Title: "中国的经济增长"})
+	if len(zh) != 2 || zh[0].join(0, len(zh[0].words)) != "中国" || zh[1].join(0, len(zh[1].words)) != "经济增长" {
+		t.Errorf("Unexpected chinese chunks %v", zh)
+	}
+}
+
+func TestTFIDF(t *testing.T) {
+	var c Corpus
+	c.Add(articles...)
+
+	if c.Docs() != 3 {
+		t.Errorf("Expected 3 documents but got %d", c.Docs())
+	}
+
+	if df := c.DocFreq("interest rates"); df != 2 {
+		t.Errorf("Expected a document frequency of 2 but got %d", df)
+	}
+
+	phrases := c.TFIDF(articles, 0)
+
+	p, ok := contains(phrases, "interest rates")
+	if !ok {
+		t.Fatal("Expected \"interest rates\" to be a phrase")
+	}
+
+	if len(p.Articles) != 2 || p.Articles[0] != 0 || p.Articles[1] != 1 {
+		t.Errorf("Expected the phrase to come from the first two articles but got %v", p.Articles)
+	}
+
+	for i := 1; i < len(phrases); i++ {
+		if phrases[i].Score > phrases[i-1].Score {
+			t.Fatal("Expected the phrases to be sorted by their score")
+		}
+	}
+
+	if top := c.TFIDF(articles, 5); len(top) != 5 {
+		t.Errorf("Expected 5 phrases but got %d", len(top))
+	}
+}
+
+func TestRAKE(t *testing.T) {
+	phrases := RAKE(articles, 5, 0)
+
+	bank, ok := contains(phrases, "central bank raised interest rates")
+	if !ok {
+		t.Fatal("Expected \"central bank raised interest rates\" to be a phrase")
+	}
+
+	if bank.Count != 3 || len(bank.Articles) != 2 {
+		t.Errorf("Expected the phrase to occur 3 times in 2 articles but got %d and %v", bank.Count, bank.Articles)
+	}
+
+	tuesday, ok := contains(phrases, "tuesday")
+	if !ok {
+		t.Fatal("Expected \"tuesday\" to be a phrase")
+	}
+
+	if bank.Score <= tuesday.Score {
+		t.Errorf("Expected a multi-word phrase to score higher than a single word but got %f and %f", bank.Score, tuesday.Score)
+	}
+
+	if _, ok := contains(RAKE(articles, 0, 0), bank.Text); ok {
+		t.Errorf("Expected phrases with more than %d words to be dropped", DefaultMaxWords)
+	}
+}
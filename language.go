@@ -0,0 +1,252 @@
+package newsapi
+
+import (
+	"embed"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// langSamples contains a sample text for every language of the language options. The n-gram profiles
+// of the languages are built from them the first time a language is detected, so the detection works
+// offline and without downloading any models.
+//
+//go:embed langprofiles/*.txt
+var langSamples embed.FS
+
+// langEvidence is the maximum number of n-grams a confidence is based on. Without it the confidence
+// of every text longer than a sentence would be exactly 1 since naive Bayes is overly sure of itself.
+const langEvidence = 50
+
+// langLetterPenalty is the exponent of the share of letters which are written in one of the scripts of the
+// languages. Letters of other scripts are the strongest hint that the text is written in a language none
+// of the languages is, e.g. the kana of Japanese text which is detected as Chinese because of its kanji.
+const langLetterPenalty = 10
+
+type langProfile struct {
+	counts map[string]int
+	total  int
+}
+
+var (
+	langProfilesOnce sync.Once
+	langProfiles     map[string]langProfile
+
+	// the languages which can be written in a script
+	scriptLangs = []struct {
+		script *unicode.RangeTable
+		langs  []string
+	}{
+		{unicode.Latin, []string{"de", "en", "es", "fr", "it", "nl", "no", "pt", "se"}},
+		{unicode.Cyrillic, []string{"ru"}},
+		{unicode.Arabic, []string{"ar", "ud"}},
+		{unicode.Hebrew, []string{"he"}},
+		{unicode.Han, []string{"zh"}},
+	}
+)
+
+func loadLangProfiles() {
+	langProfiles = make(map[string]langProfile, len(langOpts))
+
+	for _, lang := range langOpts {
+		b, err := langSamples.ReadFile("langprofiles/" + lang + ".txt")
+		if err != nil {
+			// can only happen if a sample is missing which the tests make sure of
+			panic(err)
+		}
+
+		p := langProfile{counts: make(map[string]int)}
+		for _, g := range ngrams(string(b)) {
+			p.counts[g]++
+			p.total++
+		}
+
+		langProfiles[lang] = p
+	}
+}
+
+// ngrams returns the character uni-, bi- and trigrams of every word of the text. The words are lower
+// cased and padded with a space on both sides so the beginnings and endings of words are taken into account.
+func ngrams(text string) []string {
+	var grams []string
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.Is(unicode.Mn, r)
+	})
+
+	for _, w := range words {
+		r := []rune(" " + w + " ")
+		for n := 1; n <= 3; n++ {
+			for i := 0; i+n <= len(r); i++ {
+				g := string(r[i : i+n])
+				if g != " " {
+					grams = append(grams, g)
+				}
+			}
+		}
+	}
+
+	return grams
+}
+
+// candidateLangs returns the languages which are written in the script most letters of the text are written in.
+func candidateLangs(text string) []string {
+	counts := make([]int, len(scriptLangs))
+
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+
+		for i, s := range scriptLangs {
+			if unicode.Is(s.script, r) {
+				counts[i]++
+				break
+			}
+		}
+	}
+
+	best := -1
+	for i, c := range counts {
+		if c > 0 && (best < 0 || c > counts[best]) {
+			best = i
+		}
+	}
+
+	if best < 0 {
+		return nil
+	}
+
+	return scriptLangs[best].langs
+}
+
+// DetectLanguage returns the language the text is written in as one of the values of the Language option
+// together with a confidence between 0 and 1. An empty string is returned if the language cannot be detected,
+// e.g. because the text doesn't contain any letters or is written in a script none of the languages uses.
+//
+// The language is detected by comparing the character n-grams of the text with the n-gram profiles of the
+// languages, so the detection gets more reliable the longer the text is. Texts shorter than a few words
+// should be taken with a grain of salt. Letters of scripts none of the languages use lower the confidence, so
+// e.g. Japanese text is detected as Chinese with a low confidence. Languages which are written in the same
+// script as one of the supported languages, like Ukrainian or Polish, can still be mistaken for it.
+func DetectLanguage(text string) (string, float64) {
+	langs := candidateLangs(text)
+	if len(langs) == 0 {
+		return "", 0
+	}
+
+	grams := ngrams(text)
+	if len(grams) == 0 {
+		return "", 0
+	}
+
+	langProfilesOnce.Do(loadLangProfiles)
+
+	best, confidence := 0, 1.0
+	if len(langs) > 1 {
+		best, confidence = rankLangs(langs, grams)
+	}
+
+	return langs[best], confidence * letterFit(text)
+}
+
+// rankLangs returns the index of the language whose profile fits the n-grams best together with the
+// probability of it being the right one among the languages.
+func rankLangs(langs []string, grams []string) (int, float64) {
+	scores := make([]float64, len(langs))
+	for i, lang := range langs {
+		p := langProfiles[lang]
+		vocab := float64(len(p.counts) + 1)
+
+		for _, g := range grams {
+			// add-one smoothing so unknown n-grams don't rule out a language entirely
+			scores[i] += math.Log((float64(p.counts[g]) + 1) / (float64(p.total) + vocab))
+		}
+		scores[i] /= float64(len(grams))
+	}
+
+	evidence := math.Min(float64(len(grams)), langEvidence)
+
+	best, max := 0, scores[0]
+	for i, s := range scores {
+		if s > max {
+			best, max = i, s
+		}
+	}
+
+	var sum float64
+	for _, s := range scores {
+		sum += math.Exp((s - max) * evidence)
+	}
+
+	return best, 1 / sum
+}
+
+// letterFit returns the share of the letters of the text which are written in one of the scripts of the
+// languages between 0 and 1, raised to langLetterPenalty. The ranking only compares the candidates of a
+// script with each other, so without it a text in a language which mixes scripts, like Japanese, would be
+// detected with full confidence. The letters aren't compared with the samples since they only contain a
+// small part of the characters of scripts like Han.
+func letterFit(text string) float64 {
+	var letters, known int
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+
+		letters++
+		for _, s := range scriptLangs {
+			if unicode.Is(s.script, r) {
+				known++
+				break
+			}
+		}
+	}
+
+	if letters == 0 {
+		return 0
+	}
+
+	return math.Pow(float64(known)/float64(letters), langLetterPenalty)
+}
+
+// DetectArticleLanguage detects the language of the title, description and content of the article with
+// DetectLanguage.
+func DetectArticleLanguage(a Article) (string, float64) {
+	return DetectLanguage(a.Title + "\n" + a.Description + "\n" + a.ContentText())
+}
+
+// DetectLanguages detects the language of every article and returns them as enriched articles with their
+// Language and LanguageConfidence fields set.
+func DetectLanguages(articles []Article) []EnrichedArticle {
+	enriched := make([]EnrichedArticle, len(articles))
+	for i, a := range articles {
+		enriched[i].Article = a
+		enriched[i].Language, enriched[i].LanguageConfidence = DetectArticleLanguage(a)
+	}
+
+	return enriched
+}
+
+// FilterLanguage returns the articles which have been detected to be written in one of the languages with
+// at least the given confidence.
+func FilterLanguage(articles []Article, minConfidence float64, langs ...string) []Article {
+	var filtered []Article
+
+	for _, a := range articles {
+		lang, confidence := DetectArticleLanguage(a)
+		if confidence < minConfidence {
+			continue
+		}
+
+		for _, l := range langs {
+			if lang == l {
+				filtered = append(filtered, a)
+				break
+			}
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,104 @@
+package newsapi
+
+import "testing"
+
+func TestLangSamples(t *testing.T) {
+	for _, lang := range langOpts {
+		if _, err := langSamples.ReadFile("langprofiles/" + lang + ".txt"); err != nil {
+			t.Errorf("Missing sample text for %s", lang)
+		}
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	cases := []struct {
+		text, lang string
+	}{
+		{"The president met with business leaders on Monday to discuss the new trade agreement with the European Union.", "en"},
+		{"Der Präsident hat sich am Montag mit Vertretern der Wirtschaft getroffen, um über das neue Handelsabkommen zu sprechen.", "de"},
+		{"Le président a rencontré lundi des chefs d'entreprise pour discuter du nouvel accord commercial avec l'Union européenne.", "fr"},
+		{"El presidente se reunió el lunes con empresarios para hablar del nuevo acuerdo comercial con la Unión Europea.", "es"},
+		{"Il presidente ha incontrato lunedì i rappresentanti delle imprese per discutere del nuovo accordo commerciale.", "it"},
+		{"O presidente reuniu-se na segunda-feira com empresários para discutir o novo acordo comercial com a União Europeia.", "pt"},
+		{"De president heeft maandag met vertegenwoordigers van het bedrijfsleven gesproken over het nieuwe handelsakkoord.", "nl"},
+		{"Presidenten møtte næringslivsledere mandag for å diskutere den nye handelsavtalen med EU og de økonomiske utsiktene.", "no"},
+		{"Presidenten träffade på måndagen företrädare för näringslivet för att diskutera det nya handelsavtalet med EU.", "se"},
+		{"Президент в понедельник встретился с представителями бизнеса, чтобы обсудить новое торговое соглашение.", "ru"},
+		{"التقى الرئيس يوم الاثنين بممثلي قطاع الأعمال لمناقشة الاتفاقية التجارية الجديدة مع الاتحاد الأوروبي.", "ar"},
+		{"صدر نے پیر کے روز کاروباری رہنماؤں سے ملاقات کی اور یورپی یونین کے ساتھ نئے تجارتی معاہدے پر بات چیت کی۔", "ud"},
+		{"הנשיא נפגש ביום שני עם נציגי המגזר העסקי כדי לדון בהסכם הסחר החדש עם האיחוד האירופי.", "he"},
+		{"总统星期一会见了商界代表，讨论与欧盟的新贸易协定。", "zh"},
+		{"", ""},
+		{"1234 !!! 5678", ""},
+	}
+
+	for _, i := range cases {
+		lang, conf := DetectLanguage(i.text)
+		if lang != i.lang {
+			t.Errorf("Expected %s but got %s when case=%s", i.lang, lang, i.text)
+		}
+
+		if conf < 0 || conf > 1 || lang != "" && conf == 0 {
+			t.Errorf("Unexpected confidence %f when case=%s", conf, i.text)
+		}
+	}
+}
+
+func TestDetectLanguages(t *testing.T) {
+	articles := []Article{
+		{Title: "Storm causes flooding across the north of the country", Content: "Heavy rain has caused flooding in several towns... [+1200 chars]"},
+		{Title: "Sturm sorgt für Überschwemmungen im Norden des Landes", Content: "Heftiger Regen hat in mehreren Städten zu Überschwemmungen geführt... [+1200 chars]"},
+		{Title: "La tempête provoque des inondations dans le nord du pays", Content: "De fortes pluies ont provoqué des inondations dans plusieurs villes... [+1200 chars]"},
+	}
+
+	filtered := FilterLanguage(articles, 0.5, "de", "fr")
+	if len(filtered) != 2 {
+		t.Errorf("Expected 2 articles but got %d", len(filtered))
+	}
+
+	enriched := DetectLanguages(articles)
+
+	for i, lang := range []string{"en", "de", "fr"} {
+		if enriched[i].Language != lang {
+			t.Errorf("Expected %s but got %s when case=%s", lang, enriched[i].Language, enriched[i].Title)
+		}
+
+		if enriched[i].LanguageConfidence < 0.5 {
+			t.Errorf("Expected a high confidence but got %f when case=%s", enriched[i].LanguageConfidence, enriched[i].Title)
+		}
+	}
+}
+
+// languages which aren't supported but use a script of one which is together with another script
+func TestDetectLanguageUnsupported(t *testing.T) {
+	cases := []string{
+		"日本の首相は新しい経済政策を発表しました",
+		"東京の株式市場は木曜日に大きく値上がりしました",
+		"한국 정부는 새로운 경제 정책을 발표했다",
+	}
+
+	for _, i := range cases {
+		if lang, conf := DetectLanguage(i); conf >= 0.5 {
+			t.Errorf("Expected a low confidence but got %s with %f when case=%s", lang, conf, i)
+		}
+	}
+}
+
+// the sample of Chinese only contains a small part of the Han characters
+func TestDetectLanguageChinese(t *testing.T) {
+	articles := []Article{
+		{Title: "上海证券交易所周三收盘时，主要股指小幅上涨，银行股和能源股领涨。"},
+		{Title: "苹果公司周二发布了新款iPhone手机，售价比去年的型号更高。"},
+		{Title: "台风登陆后，沿海多个城市停课停工，铁路和航班大面积取消。"},
+	}
+
+	for _, a := range articles {
+		if lang, conf := DetectArticleLanguage(a); lang != "zh" || conf < 0.9 {
+			t.Errorf("Expected zh with a high confidence but got %s with %f when case=%s", lang, conf, a.Title)
+		}
+	}
+
+	if filtered := FilterLanguage(articles, 0.8, "zh"); len(filtered) != len(articles) {
+		t.Errorf("Expected %d articles but got %d", len(articles), len(filtered))
+	}
+}
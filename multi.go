@@ -0,0 +1,266 @@
+package newsapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// DefaultMultiConcurrency is the number of requests TopHeadlinesMulti keeps in flight at once when
+// TopHeadlinesMultiOpts.Concurrency isn't set.
+const DefaultMultiConcurrency = 4
+
+// TopHeadlinesMultiOpts defines the options for the TopHeadlinesMulti method. The Country, Category and Sources
+// options are missing on purpose since the countries and categories are passed to TopHeadlinesMulti directly and
+// the Sources option cannot be used in conjunction with them anyway.
+type TopHeadlinesMultiOpts struct {
+	PageSize    uint8  // cannot be larger than 100 and smaller than 0 so uint8 is sufficient
+	Page        uint16 // unlikely to be larger than ~65k
+	Q           string
+	Concurrency int // the number of requests in flight at once, DefaultMultiConcurrency is used if it's smaller than 1
+}
+
+// HeadlineTag represents the country and category combination an article has been returned for.
+// One of the two fields is empty if TopHeadlinesMulti has been called without any countries or categories.
+type HeadlineTag struct {
+	Country  string `json:"country,omitempty"`
+	Category string `json:"category,omitempty"`
+}
+
+// TaggedArticle is an article together with every country and category combination that returned it.
+type TaggedArticle struct {
+	Article
+	Tags []HeadlineTag `json:"tags"`
+}
+
+// TopHeadlinesMultiResp represents the merged responses of all requests made by TopHeadlinesMulti.
+// Articles which have been returned by more than one request only appear once but keep all of their tags.
+type TopHeadlinesMultiResp struct {
+	Requests int             `json:"requests"`
+	Articles []TaggedArticle `json:"articles"`
+	// Enriched is only set if the client has a Pipeline. It contains the same articles as the
+	// Articles field together with their annotations.
+	Enriched []EnrichedArticle `json:"enriched,omitempty"`
+}
+
+// topHeadlinesFunc is the signature of Client.TopHeadlines. It only exists so the fan-out can be tested
+// without making any real requests.
+type topHeadlinesFunc func(ctx context.Context, opts TopHeadlinesOpts) (TopHeadlinesResp, error)
+
+// TopHeadlinesMultiCost returns the number of requests a TopHeadlinesMulti call with the same countries and
+// categories would make. Every request counts against the API key's quota so it's a good idea to check
+// the cost before fanning out over a lot of countries.
+func TopHeadlinesMultiCost(countries, categories []string) int {
+	return len(multiTags(countries, categories))
+}
+
+// multiTags returns the cartesian product of the countries and categories. If one of them is empty
+// only the other one is used.
+func multiTags(countries, categories []string) []HeadlineTag {
+	var tags []HeadlineTag
+
+	switch {
+	case len(countries) == 0:
+		for _, cat := range categories {
+			tags = append(tags, HeadlineTag{Category: cat})
+		}
+	case len(categories) == 0:
+		for _, country := range countries {
+			tags = append(tags, HeadlineTag{Country: country})
+		}
+	default:
+		for _, country := range countries {
+			for _, cat := range categories {
+				tags = append(tags, HeadlineTag{Country: country, Category: cat})
+			}
+		}
+	}
+
+	return tags
+}
+
+func checkTopHeadlinesMultiParams(countries, categories []string, opts TopHeadlinesMultiOpts) error {
+	if len(countries) == 0 && len(categories) == 0 {
+		return errors.New("At least one country or category must be specified")
+	}
+
+	for _, country := range countries {
+		if !isOptOf(country, countryOpts) {
+			return errors.New("A specified country isn't a valid country")
+		}
+	}
+
+	for _, cat := range categories {
+		if !isOptOf(cat, categoryOpts) {
+			return errors.New("A specified category isn't a valid category")
+		}
+	}
+
+	if opts.PageSize > 100 {
+		return errors.New("The specified pageSize options is largen than the maximum of 100")
+	}
+
+	return nil
+}
+
+// TopHeadlinesMulti fetches the top headlines for every combination of the given countries and categories
+// and merges the responses into a single TopHeadlinesMultiResp object. At most opts.Concurrency requests are
+// in flight at once. If one of the requests fails, the remaining ones are cancelled and the error is returned.
+// The pipeline of the client runs once on the merged articles instead of on every response.
+func (c *Client) TopHeadlinesMulti(ctx context.Context, countries, categories []string, opts TopHeadlinesMultiOpts) (TopHeadlinesMultiResp, error) {
+	sub := *c
+	sub.Pipeline = nil
+
+	r, err := topHeadlinesMulti(ctx, sub.TopHeadlines, countries, categories, opts)
+	if err != nil {
+		return TopHeadlinesMultiResp{}, err
+	}
+
+	if err := c.enrichTagged(ctx, &r); err != nil {
+		return TopHeadlinesMultiResp{}, err
+	}
+
+	return r, nil
+}
+
+// enrichTagged runs the pipeline of the client on the merged articles. The articles keep their tags.
+func (c *Client) enrichTagged(ctx context.Context, r *TopHeadlinesMultiResp) error {
+	if c.Pipeline == nil {
+		return nil
+	}
+
+	articles := make([]Article, len(r.Articles))
+	for i, a := range r.Articles {
+		articles[i] = a.Article
+	}
+
+	enriched, keep, err := c.Pipeline.run(ctx, articles)
+	if err != nil {
+		return err
+	}
+
+	var tagged []TaggedArticle
+	r.Enriched = nil
+	for i, ok := range keep {
+		if ok {
+			tagged = append(tagged, TaggedArticle{Article: enriched[i].Article, Tags: r.Articles[i].Tags})
+			r.Enriched = append(r.Enriched, enriched[i])
+		}
+	}
+	r.Articles = tagged
+
+	return nil
+}
+
+func topHeadlinesMulti(ctx context.Context, fetch topHeadlinesFunc, countries, categories []string, opts TopHeadlinesMultiOpts) (TopHeadlinesMultiResp, error) {
+	err := checkTopHeadlinesMultiParams(countries, categories, opts)
+	if err != nil {
+		return TopHeadlinesMultiResp{}, err
+	}
+
+	tags := multiTags(countries, categories)
+
+	workers := opts.Concurrency
+	if workers < 1 {
+		workers = DefaultMultiConcurrency
+	}
+	if workers > len(tags) {
+		workers = len(tags)
+	}
+
+	fetchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+		jobs     = make(chan int)
+		results  = make([]TopHeadlinesResp, len(tags))
+	)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				r, err := fetch(fetchCtx, TopHeadlinesOpts{
+					PageSize: opts.PageSize,
+					Page:     opts.Page,
+					Q:        opts.Q,
+					Country:  tags[i].Country,
+					Category: tags[i].Category,
+				})
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+
+				results[i] = r
+			}
+		}()
+	}
+
+	// the results are stored by index so the merged order doesn't depend on which request finishes first
+	for i := range tags {
+		select {
+		case jobs <- i:
+		case <-fetchCtx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return TopHeadlinesMultiResp{}, firstErr
+	}
+
+	if err := ctx.Err(); err != nil {
+		return TopHeadlinesMultiResp{}, err
+	}
+
+	return TopHeadlinesMultiResp{
+		Requests: len(tags),
+		Articles: mergeTagged(tags, results),
+	}, nil
+}
+
+// mergeTagged folds the articles of all responses together by their URL. The first occurrence of an article
+// is kept and the tags of all later occurrences are appended to it.
+func mergeTagged(tags []HeadlineTag, results []TopHeadlinesResp) []TaggedArticle {
+	var (
+		merged []TaggedArticle
+		seen   = make(map[string]int)
+	)
+
+	for i, r := range results {
+		for _, a := range r.Articles {
+			j, ok := seen[a.URL]
+			if !ok {
+				seen[a.URL] = len(merged)
+				merged = append(merged, TaggedArticle{Article: a, Tags: []HeadlineTag{tags[i]}})
+				continue
+			}
+
+			if !hasTag(merged[j].Tags, tags[i]) {
+				merged[j].Tags = append(merged[j].Tags, tags[i])
+			}
+		}
+	}
+
+	return merged
+}
+
+func hasTag(tags []HeadlineTag, tag HeadlineTag) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
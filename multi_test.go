@@ -0,0 +1,161 @@
+package newsapi
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTopHeadlinesMultiCost(t *testing.T) {
+	cases := []struct {
+		countries, categories []string
+		cost                  int
+	}{
+		{nil, nil, 0},
+		{[]string{"de"}, nil, 1},
+		{nil, []string{"health", "science"}, 2},
+		{[]string{"de", "fr", "it"}, []string{"technology", "science"}, 6},
+	}
+
+	for _, i := range cases {
+		cost := TopHeadlinesMultiCost(i.countries, i.categories)
+		if cost != i.cost {
+			t.Errorf("Expected %d but got %d when case=%v", i.cost, cost, i)
+		}
+	}
+}
+
+func TestCheckTopHeadlinesMultiParams(t *testing.T) {
+	cases := []struct {
+		countries, categories []string
+		opts                  TopHeadlinesMultiOpts
+		valid                 bool
+	}{
+		{nil, nil, TopHeadlinesMultiOpts{}, false},
+		{[]string{"wrong-country"}, nil, TopHeadlinesMultiOpts{}, false},
+		{nil, []string{"wrong-category"}, TopHeadlinesMultiOpts{}, false},
+		{[]string{"de"}, nil, TopHeadlinesMultiOpts{PageSize: 101}, false},
+		{[]string{"de", "fr"}, []string{"technology"}, TopHeadlinesMultiOpts{}, true},
+	}
+
+	for _, i := range cases {
+		err := checkTopHeadlinesMultiParams(i.countries, i.categories, i.opts)
+		if !i.valid {
+			if err == nil {
+				t.Errorf("Expected error but got nil when case=%v", i)
+			}
+		} else {
+			if err != nil {
+				t.Errorf("Unexpected error %v when case=%v", err, i)
+			}
+		}
+	}
+}
+
+func TestTopHeadlinesMulti(t *testing.T) {
+	var inFlight, maxInFlight int32
+
+	fetch := func(ctx context.Context, opts TopHeadlinesOpts) (TopHeadlinesResp, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+
+		return TopHeadlinesResp{
+			Status: "ok",
+			Articles: []Article{
+				{URL: "https://example.com/" + opts.Country + "/" + opts.Category},
+				{URL: "https://example.com/shared"},
+			},
+		}, nil
+	}
+
+	opts := TopHeadlinesMultiOpts{Concurrency: 2}
+
+	r, err := topHeadlinesMulti(context.Background(), fetch, []string{"de", "fr", "it"}, []string{"science", "technology"}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Requests != 6 {
+		t.Errorf("Expected 6 requests but got %d", r.Requests)
+	}
+
+	if maxInFlight > 2 {
+		t.Errorf("Expected at most 2 requests in flight but got %d", maxInFlight)
+	}
+
+	// every combination returns its own article plus the shared one
+	if len(r.Articles) != 7 {
+		t.Fatalf("Expected 7 articles but got %d", len(r.Articles))
+	}
+
+	if r.Articles[0].URL != "https://example.com/de/science" {
+		t.Errorf("Expected the first article to belong to the first combination but got %s", r.Articles[0].URL)
+	}
+
+	for _, a := range r.Articles {
+		expected := 1
+		if a.URL == "https://example.com/shared" {
+			expected = 6
+		}
+
+		if len(a.Tags) != expected {
+			t.Errorf("Expected %d tags but got %d when url=%s", expected, len(a.Tags), a.URL)
+		}
+	}
+}
+
+func TestTopHeadlinesMultiError(t *testing.T) {
+	errFetch := errors.New("fetch failed")
+
+	fetch := func(ctx context.Context, opts TopHeadlinesOpts) (TopHeadlinesResp, error) {
+		if opts.Country == "fr" {
+			return TopHeadlinesResp{}, errFetch
+		}
+
+		return TopHeadlinesResp{}, ctx.Err()
+	}
+
+	_, err := topHeadlinesMulti(context.Background(), fetch, []string{"de", "fr", "it"}, nil, TopHeadlinesMultiOpts{Concurrency: 1})
+	if err != errFetch {
+		t.Errorf("Expected %v but got %v", errFetch, err)
+	}
+}
+
+func TestClientPipelineTagged(t *testing.T) {
+	var runs int32
+	c := Client{Pipeline: &Pipeline{Stages: []Stage{
+		{Name: "drop", Enricher: EnricherFunc(func(ctx context.Context, a *EnrichedArticle) error {
+			atomic.AddInt32(&runs, 1)
+			if a.URL == "https://example.com/1" {
+				return errors.New("drop")
+			}
+			a.Annotations["seen"] = true
+			return nil
+		}), OnError: ErrorDrop},
+	}}}
+
+	r := TopHeadlinesMultiResp{Requests: 2}
+	for i, a := range pipelineArticles(3) {
+		r.Articles = append(r.Articles, TaggedArticle{Article: a, Tags: []HeadlineTag{{Country: countryOpts[i]}}})
+	}
+
+	if err := c.enrichTagged(context.Background(), &r); err != nil {
+		t.Fatal(err)
+	}
+
+	if runs != 3 || len(r.Articles) != 2 || len(r.Enriched) != 2 || r.Enriched[0].Annotations["seen"] != true {
+		t.Fatalf("Expected the pipeline to run once per article but got %d runs and %+v", runs, r)
+	}
+
+	if r.Articles[1].URL != r.Enriched[1].URL || r.Articles[1].Tags[0].Country != countryOpts[2] {
+		t.Errorf("Expected the articles to keep their tags but got %+v", r.Articles)
+	}
+}
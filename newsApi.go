@@ -16,6 +16,15 @@ import (
 // Client represents the client type for the API. It represents the entry point for the library.
 type Client struct {
 	APIKey string
+	// Normalizer is applied to every article returned by the TopHeadlines and Everything methods
+	// if it's not nil. See the Normalizer type for more information.
+	Normalizer *Normalizer
+	// Pipeline is run on the articles returned by the TopHeadlines and Everything methods if it's not nil.
+	// It runs after the Normalizer. The enriched articles are available in the Enriched field of the response.
+	Pipeline *Pipeline
+	// Recorder receives the raw body of every response of the API if it's not nil, e.g. to archive it.
+	// See the Recorder type for more information.
+	Recorder Recorder
 }
 
 var (
@@ -58,6 +67,11 @@ var (
 	}
 )
 
+// Categories returns the values of the Category option.
+func Categories() []string {
+	return append([]string(nil), categoryOpts...)
+}
+
 // statusBody represents the response status. It's being used to determine if the request was successful ot not. If the
 // request failed the message returned by the NewsAPI service will be returned to the user.
 type statusBody struct {
@@ -65,9 +79,9 @@ type statusBody struct {
 	Code   string `json:"code"`
 }
 
-// articleSource is called "source" in the json response but it has a different values
-// than the "sources" field in the /sources field.
-type articleSource struct {
+// ArticleSource represents the "source" field of an article. It has different values
+// than the "sources" field in the /sources route which is represented by the Source type.
+type ArticleSource struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
 }
@@ -75,7 +89,7 @@ type articleSource struct {
 // The Article type represents an article returned in the "articles" field of the /everything and
 // /top-headliens routes.
 type Article struct {
-	Source      articleSource `json:"source"`
+	Source      ArticleSource `json:"source"`
 	Author      string        `json:"author"`
 	Title       string        `json:"title"`
 	Description string        `json:"description"`
@@ -91,6 +105,9 @@ type articleResp struct {
 	Status string `json:"status"`
 	// TotalResults uint      `json:"totalResults"`
 	Articles []Article `json:"articles"`
+	// Enriched is only set if the client has a Pipeline. It contains the same articles as the
+	// Articles field together with their annotations.
+	Enriched []EnrichedArticle `json:"enriched,omitempty"`
 }
 
 func isOptOf(userOpt string, optArr []string) bool {
@@ -187,7 +204,7 @@ func errType(errCode string) error {
 
 // fectchGetRoute exclusively fetches GET routes as other http methods aren't currently supported by the "NewsAPI" service
 // and adding a param for the http methood seems unnecessary and just makes things more complicated
-func fetchGetRoute(ctx context.Context, baseURL, apiKey string, opt interface{}) (interface{}, error) {
+func fetchGetRoute(ctx context.Context, baseURL, apiKey string, opt interface{}, rec Recorder) (interface{}, error) {
 	if apiKey == "" {
 		return nil, errors.New("The API key cannot be nil")
 	}
@@ -203,6 +220,7 @@ func fetchGetRoute(ctx context.Context, baseURL, apiKey string, opt interface{})
 	}
 	req.Header.Add("X-Api-Key", apiKey)
 
+	requested := time.Now()
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
@@ -215,6 +233,24 @@ func fetchGetRoute(ctx context.Context, baseURL, apiKey string, opt interface{})
 		return nil, err
 	}
 
+	if rec != nil {
+		err := rec.Record(ctx, RawResponse{
+			Route:         req.URL.Path,
+			Method:        req.Method,
+			URL:           url,
+			RequestHeader: RedactHeader(req.Header),
+			Opts:          opt,
+			StatusCode:    resp.StatusCode,
+			Header:        resp.Header.Clone(),
+			Body:          b,
+			RequestedAt:   requested,
+			RespondedAt:   time.Now(),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	var errBody statusBody
 
 	err = json.Unmarshal(b, &errBody)
@@ -31,6 +31,19 @@ func TestAreOpts(t *testing.T) {
 	}
 }
 
+func TestCategories(t *testing.T) {
+	cats := Categories()
+	if len(cats) != len(categoryOpts) || !isOptOf(cats[0], categoryOpts) {
+		t.Errorf("Expected the category options but got %v", cats)
+	}
+
+	// the options can't be changed through the returned slice
+	cats[0] = "weather"
+	if categoryOpts[0] == "weather" {
+		t.Error("Expected a copy of the category options")
+	}
+}
+
 func TestConstructURL(t *testing.T) {
 	type mockStruct struct {
 		// randomly named fields without any context
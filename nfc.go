@@ -0,0 +1,170 @@
+package newsapi
+
+import (
+	"sort"
+	"sync"
+)
+
+// The Hangul syllables are composed and decomposed algorithmically instead of through the tables.
+const (
+	hangulSBase  = 0xAC00
+	hangulLBase  = 0x1100
+	hangulVBase  = 0x1161
+	hangulTBase  = 0x11A7
+	hangulLCount = 19
+	hangulVCount = 21
+	hangulTCount = 28
+	hangulNCount = hangulVCount * hangulTCount
+	hangulSCount = hangulLCount * hangulNCount
+)
+
+var (
+	nfcDecompOnce sync.Once
+	nfcPrimaries  map[rune][2]rune
+)
+
+// toNFC returns s in Unicode normalization form C. Only the characters of the basic multilingual plane
+// are being normalized since the tables don't cover the other planes, which is fine for news articles.
+func toNFC(s string) string {
+	// nothing below U+0300 decomposes to something different or combines with anything
+	quick := true
+	for _, r := range s {
+		if r >= 0x300 {
+			quick = false
+			break
+		}
+	}
+	if quick {
+		return s
+	}
+
+	loadNFCPrimaries()
+
+	var decomposed []rune
+	for _, r := range s {
+		decomposed = decomposeRune(decomposed, r)
+	}
+
+	reorderMarks(decomposed)
+
+	return string(composeRunes(decomposed))
+}
+
+// loadNFCPrimaries builds the reverse of the composition table. It has to be called before decomposeRune.
+func loadNFCPrimaries() {
+	nfcDecompOnce.Do(func() {
+		nfcPrimaries = make(map[rune][2]rune, len(nfcCompositions))
+		for pair, r := range nfcCompositions {
+			nfcPrimaries[r] = pair
+		}
+	})
+}
+
+// decomposeRune appends the full canonical decomposition of r to buf.
+func decomposeRune(buf []rune, r rune) []rune {
+	if r >= hangulSBase && r < hangulSBase+hangulSCount {
+		i := r - hangulSBase
+		buf = append(buf, hangulLBase+i/hangulNCount, hangulVBase+(i%hangulNCount)/hangulTCount)
+		if t := i % hangulTCount; t != 0 {
+			buf = append(buf, hangulTBase+t)
+		}
+		return buf
+	}
+
+	d, ok := nfcPrimaries[r]
+	if !ok {
+		d, ok = nfcDecompositions[r]
+	}
+	if !ok {
+		return append(buf, r)
+	}
+
+	buf = decomposeRune(buf, d[0])
+	if d[1] != 0 {
+		buf = decomposeRune(buf, d[1])
+	}
+
+	return buf
+}
+
+// reorderMarks sorts every run of combining marks by their combining class. The sort has to be
+// stable so marks of the same class keep their order.
+func reorderMarks(runes []rune) {
+	for i := 0; i < len(runes); {
+		if combiningClass(runes[i]) == 0 {
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(runes) && combiningClass(runes[j]) != 0 {
+			j++
+		}
+
+		marks := runes[i:j]
+		sort.SliceStable(marks, func(a, b int) bool {
+			return combiningClass(marks[a]) < combiningClass(marks[b])
+		})
+		i = j
+	}
+}
+
+// composeRunes applies the canonical composition algorithm to a decomposed and reordered string.
+func composeRunes(runes []rune) []rune {
+	var (
+		out       = make([]rune, 0, len(runes))
+		starter   = -1
+		lastClass uint8
+	)
+
+	for _, r := range runes {
+		class := combiningClass(r)
+
+		if starter >= 0 {
+			// a mark is blocked from its starter if there's a character with the same or a higher class between them
+			adjacent := starter == len(out)-1
+			if adjacent || lastClass != 0 && lastClass < class {
+				if c, ok := composePair(out[starter], r); ok {
+					out[starter] = c
+					continue
+				}
+			}
+		}
+
+		if class == 0 {
+			starter = len(out)
+		}
+		lastClass = class
+		out = append(out, r)
+	}
+
+	return out
+}
+
+func composePair(a, b rune) (rune, bool) {
+	// leading consonant + vowel and LV syllable + trailing consonant
+	if a >= hangulLBase && a < hangulLBase+hangulLCount && b >= hangulVBase && b < hangulVBase+hangulVCount {
+		return hangulSBase + ((a-hangulLBase)*hangulVCount+b-hangulVBase)*hangulTCount, true
+	}
+	if a >= hangulSBase && a < hangulSBase+hangulSCount && (a-hangulSBase)%hangulTCount == 0 && b > hangulTBase && b < hangulTBase+hangulTCount {
+		return a + b - hangulTBase, true
+	}
+
+	c, ok := nfcCompositions[[2]rune{a, b}]
+	return c, ok
+}
+
+func combiningClass(r rune) uint8 {
+	if r < 0x300 {
+		return 0
+	}
+
+	i := sort.Search(len(nfcCombiningClasses), func(i int) bool {
+		return nfcCombiningClasses[i].hi >= r
+	})
+	if i < len(nfcCombiningClasses) && nfcCombiningClasses[i].lo <= r {
+		return nfcCombiningClasses[i].class
+	}
+
+	return 0
+}
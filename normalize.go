@@ -0,0 +1,136 @@
+package newsapi
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	markupTag = regexp.MustCompile(`(?s)<!--.*?-->|</?[a-zA-Z][^>]*>`)
+
+	// the characters which are removed instead of being treated as whitespace
+	zeroWidth = strings.NewReplacer("\u200b", "", "\u2060", "", "\ufeff", "", "\u00ad", "")
+)
+
+// Normalizer cleans up the Title, Description and Content fields of articles. The fields sometimes contain
+// markup, HTML entities, odd unicode whitespace or, in case of the title, the name of the source at the end.
+// Every rule can be switched on and off on its own. The zero value doesn't change anything, use
+// DefaultNormalizer to apply all rules.
+//
+// A Normalizer can either be used by hand or be set on a Client which then applies it to every article it
+// receives:
+//
+//	c := newsapi.Client{APIKey: "your-api-key", Normalizer: &newsapi.DefaultNormalizer}
+type Normalizer struct {
+	// StripMarkup removes HTML tags and comments. Block level tags are replaced with a space
+	// so the words around them don't get glued together. If DecodeEntities is set as well, tags
+	// which have been encoded as entities, e.g. "&lt;p&gt;", are removed too.
+	StripMarkup bool
+	// DecodeEntities decodes HTML entities like "&amp;" or "&#8217;". Entities which have been
+	// encoded twice, e.g. "&amp;amp;", are decoded as well.
+	DecodeEntities bool
+	// NFC normalizes the text to Unicode normalization form C so that e.g. an "e" followed by a
+	// combining acute accent becomes a single "é".
+	NFC bool
+	// CollapseWhitespace replaces every run of unicode whitespace with a single space, removes
+	// zero-width characters and trims the text.
+	CollapseWhitespace bool
+	// StripTitleSource removes the name of the source from the end of the title,
+	// e.g. "Some headline - BBC News" becomes "Some headline".
+	StripTitleSource bool
+}
+
+// DefaultNormalizer applies all rules.
+var DefaultNormalizer = Normalizer{
+	StripMarkup:        true,
+	DecodeEntities:     true,
+	NFC:                true,
+	CollapseWhitespace: true,
+	StripTitleSource:   true,
+}
+
+// Normalize returns a copy of the article with normalized Title, Description and Content fields.
+func (n Normalizer) Normalize(a Article) Article {
+	a.Title = n.Text(a.Title)
+	a.Description = n.Text(a.Description)
+	a.Content = n.Text(a.Content)
+
+	if n.StripTitleSource {
+		a.Title = stripTitleSource(a.Title, a.Source)
+	}
+
+	return a
+}
+
+// NormalizeAll normalizes the articles in place.
+func (n Normalizer) NormalizeAll(articles []Article) {
+	for i := range articles {
+		articles[i] = n.Normalize(articles[i])
+	}
+}
+
+// Text applies all enabled rules apart from StripTitleSource to s.
+func (n Normalizer) Text(s string) string {
+	// the entities are decoded first, so tags which have been encoded as "&lt;p&gt;" are stripped as well
+	if n.DecodeEntities {
+		// decode at most twice, otherwise text which is actually about entities would be mangled
+		for i := 0; i < 2 && strings.Contains(s, "&"); i++ {
+			s = html.UnescapeString(s)
+		}
+	}
+
+	if n.StripMarkup {
+		s = markupTag.ReplaceAllStringFunc(s, func(tag string) string {
+			if isInlineTag(tag) {
+				return ""
+			}
+			return " "
+		})
+	}
+
+	if n.NFC {
+		s = toNFC(s)
+	}
+
+	if n.CollapseWhitespace {
+		s = strings.Join(strings.FieldsFunc(zeroWidth.Replace(s), unicode.IsSpace), " ")
+	}
+
+	return s
+}
+
+var inlineTags = map[string]bool{
+	"a": true, "abbr": true, "b": true, "bdi": true, "bdo": true, "cite": true, "code": true, "em": true,
+	"i": true, "mark": true, "q": true, "s": true, "small": true, "span": true, "strong": true, "sub": true,
+	"sup": true, "time": true, "u": true,
+}
+
+func isInlineTag(tag string) bool {
+	name := strings.TrimLeft(tag, "</")
+	if i := strings.IndexFunc(name, func(r rune) bool { return unicode.IsSpace(r) || r == '>' || r == '/' }); i >= 0 {
+		name = name[:i]
+	}
+
+	return inlineTags[strings.ToLower(name)]
+}
+
+// stripTitleSource removes " - Source", " | Source", " – Source" or " — Source" from the end of the title
+// if Source is the name or the id of the article's source. Titles which would end up empty are left alone.
+func stripTitleSource(title string, src ArticleSource) string {
+	for _, name := range []string{src.Name, src.ID} {
+		if name == "" {
+			continue
+		}
+
+		for _, sep := range []string{" - ", " | ", " – ", " — ", " : "} {
+			suffix := sep + name
+			if len(title) > len(suffix) && strings.EqualFold(title[len(title)-len(suffix):], suffix) {
+				return strings.TrimSpace(title[:len(title)-len(suffix)])
+			}
+		}
+	}
+
+	return title
+}
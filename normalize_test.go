@@ -0,0 +1,87 @@
+package newsapi
+
+import "testing"
+
+func TestNormalizerText(t *testing.T) {
+	cases := []struct {
+		in, out string
+	}{
+		{"Tom &amp; Jerry", "Tom & Jerry"},
+		{"It&#8217;s over", "It’s over"},
+		{"Fish &amp;amp; chips", "Fish & chips"},
+		{"<p>First</p><p>Second</p>", "First Second"},
+		{"A <b>bold</b> move", "A bold move"},
+		{"<ul><li>One</li><li>Two</li></ul>", "One Two"},
+		{"&lt;ul&gt;&lt;li&gt;One&lt;/li&gt;&lt;li&gt;Two&lt;/li&gt;&lt;/ul&gt;", "One Two"},
+		{"A &amp;lt;b&amp;gt;bold&amp;lt;/b&amp;gt; move", "A bold move"},
+		{"1 &lt; 2 &gt; 0", "1 < 2 > 0"},
+		{"No\u00a0break space \n\t here", "No break space here"},
+		{"zero\u200bwidth", "zerowidth"},
+		{"Cafe\u0301", "Caf\u00e9"},
+		{"The quick... [+1234 chars]", "The quick... [+1234 chars]"},
+	}
+
+	for _, i := range cases {
+		out := DefaultNormalizer.Text(i.in)
+		if out != i.out {
+			t.Errorf("Expected %q but got %q when case=%q", i.out, out, i.in)
+		}
+	}
+}
+
+func TestNormalizerRules(t *testing.T) {
+	n := Normalizer{DecodeEntities: true}
+
+	if out := n.Text("<b>Tom &amp; Jerry</b>  "); out != "<b>Tom & Jerry</b>  " {
+		t.Errorf("Expected only the entities to be decoded but got %q", out)
+	}
+
+	if out := (Normalizer{}).Text("<b>Tom &amp; Jerry</b>"); out != "<b>Tom &amp; Jerry</b>" {
+		t.Errorf("Expected the zero value to leave the text alone but got %q", out)
+	}
+}
+
+func TestNormalizerTitleSource(t *testing.T) {
+	cases := []struct {
+		title  string
+		source ArticleSource
+		out    string
+	}{
+		{"Storm hits coast - BBC News", ArticleSource{ID: "bbc-news", Name: "BBC News"}, "Storm hits coast"},
+		{"Storm hits coast | bbc news", ArticleSource{Name: "BBC News"}, "Storm hits coast"},
+		{"Storm hits coast – Reuters", ArticleSource{Name: "Reuters"}, "Storm hits coast"},
+		{"Storm hits coast - CNN", ArticleSource{Name: "BBC News"}, "Storm hits coast - CNN"},
+		{"BBC News", ArticleSource{Name: "BBC News"}, "BBC News"},
+	}
+
+	for _, i := range cases {
+		a := DefaultNormalizer.Normalize(Article{Title: i.title, Source: i.source})
+		if a.Title != i.out {
+			t.Errorf("Expected %q but got %q when case=%v", i.out, a.Title, i)
+		}
+	}
+}
+
+func TestToNFC(t *testing.T) {
+	cases := []struct {
+		in, out string
+	}{
+		{"plain ascii", "plain ascii"},
+		{"e\u0301", "\u00e9"},
+		{"\u00e9", "\u00e9"},
+		// the marks are reordered by their combining class before composing
+		{"e\u0301\u0323", "\u1eb9\u0301"},
+		{"\u00e9\u0323", "\u1eb9\u0301"},
+		// singletons are replaced by their decomposition
+		{"\u2126", "\u03a9"},
+		{"\u1100\u1161\u11a8", "\uac01"},
+		{"A\u030a", "\u00c5"},
+	}
+
+	for _, i := range cases {
+		out := toNFC(i.in)
+		if out != i.out {
+			t.Errorf("Expected %+q but got %+q when case=%+q", i.out, out, i.in)
+		}
+	}
+}
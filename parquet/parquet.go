@@ -0,0 +1,578 @@
+/*
+Package parquet writes articles as Apache Parquet files without any dependencies, so archives can be
+loaded into a data warehouse or analyzed with tools like DuckDB, Spark or pandas directly:
+
+	f, err := os.Create("articles.parquet")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	w := parquet.NewWriter(f, parquet.Options{Compression: parquet.Snappy})
+	for _, a := range r.Articles {
+		if err := w.Write(parquet.Record{Article: a, CollectedAt: time.Now(), Query: "bitcoin"}); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		log.Fatal(err)
+	}
+
+Every record becomes a row with the following optional columns. Empty strings and zero times are written as null.
+
+	id                 string     the ID of the article, see Article.ID
+	source_id          string
+	source_name        string
+	author             string
+	title              string
+	description        string
+	url                string
+	url_to_image       string
+	published_at       timestamp  milliseconds, UTC
+	content            string
+	detected_language  string     the language of the article, see Record.Language
+	collected_at       timestamp  milliseconds, UTC, when the article has been fetched
+	query              string     the query the article has been fetched with
+
+Rows are collected in memory until a row group is full and written to the file afterwards, so the size of
+the row groups is a trade-off between memory usage and how well the file compresses.
+*/
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+	"time"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+const (
+	// DefaultRowGroupRows is the maximum number of rows of a row group if Options.RowGroupRows isn't set.
+	DefaultRowGroupRows = 100000
+	// DefaultRowGroupBytes is the maximum uncompressed size of a row group if Options.RowGroupBytes isn't set.
+	DefaultRowGroupBytes = 128 << 20
+
+	magic = "PAR1"
+)
+
+// Compression is the compression codec of the pages of a file.
+type Compression int
+
+// The supported compression codecs. Their values are the codec IDs of the Parquet format.
+const (
+	Uncompressed Compression = 0
+	Snappy       Compression = 1
+	Gzip         Compression = 2
+)
+
+// Options configures a Writer.
+type Options struct {
+	// RowGroupRows is the maximum number of rows of a row group. DefaultRowGroupRows is used if it's 0.
+	RowGroupRows int
+	// RowGroupBytes is the maximum uncompressed size of the values of a row group. DefaultRowGroupBytes is used if it's 0.
+	RowGroupBytes int
+	Compression   Compression
+	// Metadata is added to the key-value metadata of the file.
+	Metadata map[string]string
+}
+
+// Record is an article together with the metadata of its collection.
+type Record struct {
+	newsapi.Article
+	// CollectedAt is the time the article has been fetched.
+	CollectedAt time.Time
+	// Query is the query the article has been fetched with, e.g. the Q option of the /everything route.
+	Query string
+	// Language is the detected language of the article, e.g. the Language field of an EnrichedArticle.
+	Language string
+}
+
+// The physical types, converted types and encodings of the Parquet format which are used.
+const (
+	typeInt64     = 2
+	typeByteArray = 6
+
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	repetitionOptional = 1
+	pageData           = 0
+)
+
+type column struct {
+	name string
+	typ  int32
+	str  func(r Record) string
+	time func(r Record) time.Time
+}
+
+var columns = []column{
+	{name: "id", typ: typeByteArray, str: func(r Record) string { return r.ID() }},
+	{name: "source_id", typ: typeByteArray, str: func(r Record) string { return r.Source.ID }},
+	{name: "source_name", typ: typeByteArray, str: func(r Record) string { return r.Source.Name }},
+	{name: "author", typ: typeByteArray, str: func(r Record) string { return r.Author }},
+	{name: "title", typ: typeByteArray, str: func(r Record) string { return r.Title }},
+	{name: "description", typ: typeByteArray, str: func(r Record) string { return r.Description }},
+	{name: "url", typ: typeByteArray, str: func(r Record) string { return r.URL }},
+	{name: "url_to_image", typ: typeByteArray, str: func(r Record) string { return r.URLToImage }},
+	{name: "published_at", typ: typeInt64, time: func(r Record) time.Time { return r.PublishedAt }},
+	{name: "content", typ: typeByteArray, str: func(r Record) string { return r.Content }},
+	{name: "detected_language", typ: typeByteArray, str: func(r Record) string { return r.Language }},
+	{name: "collected_at", typ: typeInt64, time: func(r Record) time.Time { return r.CollectedAt }},
+	{name: "query", typ: typeByteArray, str: func(r Record) string { return r.Query }},
+}
+
+// columnBuffer contains the values of a column of the current row group.
+type columnBuffer struct {
+	levels   []byte
+	values   bytes.Buffer
+	nulls    int64
+	min, max int64
+	hasStats bool
+}
+
+// columnChunk is the metadata of a column of a row group which has been written.
+type columnChunk struct {
+	offset           int64
+	numValues        int64
+	uncompressedSize int64
+	compressedSize   int64
+	nulls            int64
+	min, max         int64
+	hasStats         bool
+}
+
+type rowGroup struct {
+	columns   []columnChunk
+	numRows   int64
+	totalSize int64
+}
+
+// Writer writes records to a Parquet file. Close has to be called after the last record has been
+// written to write the metadata of the file.
+type Writer struct {
+	w      io.Writer
+	opts   Options
+	offset int64
+
+	buffers []columnBuffer
+	rows    int
+	size    int
+
+	groups  []rowGroup
+	started bool
+	closed  bool
+	err     error
+}
+
+// NewWriter returns a writer which writes a Parquet file to w.
+func NewWriter(w io.Writer, opts Options) *Writer {
+	if opts.RowGroupRows <= 0 {
+		opts.RowGroupRows = DefaultRowGroupRows
+	}
+	if opts.RowGroupBytes <= 0 {
+		opts.RowGroupBytes = DefaultRowGroupBytes
+	}
+
+	return &Writer{w: w, opts: opts, buffers: make([]columnBuffer, len(columns))}
+}
+
+// Write adds the records to the current row group. The row group is written to the underlying writer
+// once it's full.
+func (w *Writer) Write(records ...Record) error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.closed {
+		return errors.New("The writer has already been closed")
+	}
+
+	if err := w.checkCompression(); err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		for i, c := range columns {
+			buf := &w.buffers[i]
+
+			if c.typ == typeInt64 {
+				t := c.time(r)
+				if t.IsZero() {
+					buf.levels = append(buf.levels, 0)
+					buf.nulls++
+					continue
+				}
+
+				v := t.UnixNano() / int64(time.Millisecond)
+				var b [8]byte
+				binary.LittleEndian.PutUint64(b[:], uint64(v))
+				buf.values.Write(b[:])
+				buf.levels = append(buf.levels, 1)
+
+				if !buf.hasStats || v < buf.min {
+					buf.min = v
+				}
+				if !buf.hasStats || v > buf.max {
+					buf.max = v
+				}
+				buf.hasStats = true
+				w.size += 8
+				continue
+			}
+
+			s := c.str(r)
+			if s == "" {
+				buf.levels = append(buf.levels, 0)
+				buf.nulls++
+				continue
+			}
+
+			var b [4]byte
+			binary.LittleEndian.PutUint32(b[:], uint32(len(s)))
+			buf.values.Write(b[:])
+			buf.values.WriteString(s)
+			buf.levels = append(buf.levels, 1)
+			w.size += 4 + len(s)
+		}
+
+		w.rows++
+		if w.rows >= w.opts.RowGroupRows || w.size >= w.opts.RowGroupBytes {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (w *Writer) checkCompression() error {
+	switch w.opts.Compression {
+	case Uncompressed, Snappy, Gzip:
+		return nil
+	}
+
+	return errors.New("The compression codec isn't supported")
+}
+
+func (w *Writer) write(b []byte) error {
+	if w.err != nil {
+		return w.err
+	}
+
+	n, err := w.w.Write(b)
+	w.offset += int64(n)
+	w.err = err
+
+	return err
+}
+
+// Flush writes the current row group to the underlying writer even if it isn't full yet.
+func (w *Writer) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+
+	if !w.started {
+		w.started = true
+		if err := w.write([]byte(magic)); err != nil {
+			return err
+		}
+	}
+
+	if w.rows == 0 {
+		return nil
+	}
+
+	group := rowGroup{numRows: int64(w.rows)}
+	for i := range columns {
+		chunk, err := w.writeColumn(&w.buffers[i])
+		if err != nil {
+			return err
+		}
+
+		group.columns = append(group.columns, chunk)
+		group.totalSize += chunk.uncompressedSize
+		w.buffers[i] = columnBuffer{}
+	}
+
+	w.groups = append(w.groups, group)
+	w.rows, w.size = 0, 0
+
+	return nil
+}
+
+// writeColumn writes the values of a column as a single data page.
+func (w *Writer) writeColumn(buf *columnBuffer) (columnChunk, error) {
+	var page bytes.Buffer
+
+	// the definition levels are prefixed with their length
+	levels := encodeLevels(buf.levels)
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], uint32(len(levels)))
+	page.Write(b[:])
+	page.Write(levels)
+	page.Write(buf.values.Bytes())
+
+	data, err := w.compress(page.Bytes())
+	if err != nil {
+		return columnChunk{}, err
+	}
+
+	var t thriftWriter
+	t.structBegin()
+	t.i32(1, pageData)
+	t.i32(2, int32(page.Len()))
+	t.i32(3, int32(len(data)))
+	t.structField(5)
+	t.i32(1, int32(len(buf.levels)))
+	t.i32(2, encodingPlain)
+	t.i32(3, encodingRLE)
+	t.i32(4, encodingRLE)
+	t.structEnd()
+	t.structEnd()
+	header := t.buf.Bytes()
+
+	chunk := columnChunk{
+		offset:           w.offset,
+		numValues:        int64(len(buf.levels)),
+		uncompressedSize: int64(len(header) + page.Len()),
+		compressedSize:   int64(len(header) + len(data)),
+		nulls:            buf.nulls,
+		min:              buf.min,
+		max:              buf.max,
+		hasStats:         buf.hasStats,
+	}
+
+	if err := w.write(header); err != nil {
+		return columnChunk{}, err
+	}
+
+	return chunk, w.write(data)
+}
+
+func (w *Writer) compress(b []byte) ([]byte, error) {
+	switch w.opts.Compression {
+	case Snappy:
+		return snappyEncode(b), nil
+	case Gzip:
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(b); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	return b, nil
+}
+
+// encodeLevels encodes definition levels with a bit width of 1 using the RLE part of the RLE/bit-packing
+// hybrid encoding.
+func encodeLevels(levels []byte) []byte {
+	var (
+		out []byte
+		b   [binary.MaxVarintLen64]byte
+	)
+
+	for i := 0; i < len(levels); {
+		j := i
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+
+		n := binary.PutUvarint(b[:], uint64(j-i)<<1)
+		out = append(out, b[:n]...)
+		out = append(out, levels[i])
+		i = j
+	}
+
+	return out
+}
+
+// Close writes the last row group and the metadata of the file. It doesn't close the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return w.err
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	w.closed = true
+
+	meta := w.metadata()
+
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], uint32(len(meta)))
+
+	if err := w.write(meta); err != nil {
+		return err
+	}
+	if err := w.write(b[:]); err != nil {
+		return err
+	}
+
+	return w.write([]byte(magic))
+}
+
+// metadata encodes the FileMetaData struct of the file.
+func (w *Writer) metadata() []byte {
+	var t thriftWriter
+
+	var numRows int64
+	for _, g := range w.groups {
+		numRows += g.numRows
+	}
+
+	t.structBegin()
+	t.i32(1, 1)
+
+	// the schema is a tree flattened in depth-first order, the root has the columns as children
+	t.listField(2, tStruct, len(columns)+1)
+	t.structBegin()
+	t.string(4, "schema")
+	t.i32(5, int32(len(columns)))
+	t.structEnd()
+	for _, c := range columns {
+		t.structBegin()
+		t.i32(1, c.typ)
+		t.i32(3, repetitionOptional)
+		t.string(4, c.name)
+		if c.typ == typeInt64 {
+			t.i32(6, convertedTimestampMillis)
+			// LogicalType{TIMESTAMP: TimestampType{isAdjustedToUTC: true, unit: TimeUnit{MILLIS: MilliSeconds{}}}}
+			t.structField(10)
+			t.structField(8)
+			t.bool(1, true)
+			t.structField(2)
+			t.structField(1)
+			t.structEnd()
+			t.structEnd()
+			t.structEnd()
+			t.structEnd()
+		} else {
+			t.i32(6, convertedUTF8)
+			// LogicalType{STRING: StringType{}}
+			t.structField(10)
+			t.structField(1)
+			t.structEnd()
+			t.structEnd()
+		}
+		t.structEnd()
+	}
+
+	t.i64(3, numRows)
+
+	t.listField(4, tStruct, len(w.groups))
+	for _, g := range w.groups {
+		t.structBegin()
+		t.listField(1, tStruct, len(g.columns))
+		for i, c := range g.columns {
+			t.structBegin()
+			t.i64(2, c.offset)
+			t.structField(3)
+			t.i32(1, columns[i].typ)
+			t.listField(2, tI32, 2)
+			t.listI32(encodingPlain)
+			t.listI32(encodingRLE)
+			t.listField(3, tBinary, 1)
+			t.listString(columns[i].name)
+			t.i32(4, int32(w.opts.Compression))
+			t.i64(5, c.numValues)
+			t.i64(6, c.uncompressedSize)
+			t.i64(7, c.compressedSize)
+			t.i64(9, c.offset)
+			t.structField(12)
+			t.i64(3, c.nulls)
+			if c.hasStats {
+				var min, max [8]byte
+				binary.LittleEndian.PutUint64(min[:], uint64(c.min))
+				binary.LittleEndian.PutUint64(max[:], uint64(c.max))
+				t.binary(5, max[:])
+				t.binary(6, min[:])
+			}
+			t.structEnd()
+			t.structEnd()
+			t.structEnd()
+		}
+		t.i64(2, g.totalSize)
+		t.i64(3, g.numRows)
+		t.structEnd()
+	}
+
+	keys := make([]string, 0, len(w.opts.Metadata))
+	for k := range w.opts.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	if len(keys) > 0 {
+		t.listField(5, tStruct, len(keys))
+		for _, k := range keys {
+			t.structBegin()
+			t.string(1, k)
+			t.string(2, w.opts.Metadata[k])
+			t.structEnd()
+		}
+	}
+
+	t.string(6, "newsapi-golang parquet")
+
+	// ColumnOrder{TYPE_ORDER: TypeDefinedOrder{}} for every column, so readers trust the min and max values
+	t.listField(7, tStruct, len(columns))
+	for range columns {
+		t.structBegin()
+		t.structField(1)
+		t.structEnd()
+		t.structEnd()
+	}
+	t.structEnd()
+
+	return t.buf.Bytes()
+}
+
+// WriteArticles writes the articles to w as a Parquet file.
+func WriteArticles(w io.Writer, articles []newsapi.Article, opts Options) error {
+	pw := NewWriter(w, opts)
+	for _, a := range articles {
+		if err := pw.Write(Record{Article: a}); err != nil {
+			return err
+		}
+	}
+
+	return pw.Close()
+}
+
+// WriteFunc writes the records returned by next to w as a Parquet file until next returns io.EOF. Only
+// one row group is held in memory at once, so it can be used to convert archives of any size.
+func WriteFunc(w io.Writer, next func() (Record, error), opts Options) error {
+	pw := NewWriter(w, opts)
+	for {
+		r, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := pw.Write(r); err != nil {
+			return err
+		}
+	}
+
+	return pw.Close()
+}
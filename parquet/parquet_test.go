@@ -0,0 +1,391 @@
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+// thriftReader decodes structs of the Thrift compact protocol into maps from field IDs to values.
+// Lists become slices and structs become maps.
+type thriftReader struct {
+	b   []byte
+	pos int
+}
+
+func (r *thriftReader) byte() byte {
+	b := r.b[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *thriftReader) varint() uint64 {
+	v, n := binary.Uvarint(r.b[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) zigzag() int64 {
+	v := r.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case tBoolTrue:
+		return true
+	case tBoolFalse:
+		return false
+	case tI32, tI64:
+		return r.zigzag()
+	case tBinary:
+		n := int(r.varint())
+		v := r.b[r.pos : r.pos+n]
+		r.pos += n
+		return string(v)
+	case tList:
+		h := r.byte()
+		n, elem := int(h>>4), h&0x0f
+		if n == 15 {
+			n = int(r.varint())
+		}
+		list := make([]interface{}, n)
+		for i := range list {
+			// booleans in lists are encoded as a single byte
+			if elem == tBoolTrue || elem == tBoolFalse {
+				list[i] = r.byte() == 1
+				continue
+			}
+			list[i] = r.value(elem)
+		}
+		return list
+	case tStruct:
+		return r.readStruct()
+	}
+
+	panic(fmt.Sprintf("unexpected type %d", typ))
+}
+
+func (r *thriftReader) readStruct() map[int16]interface{} {
+	s := make(map[int16]interface{})
+
+	var last int16
+	for {
+		h := r.byte()
+		if h == 0 {
+			return s
+		}
+
+		typ := h & 0x0f
+		id := last + int16(h>>4)
+		if h>>4 == 0 {
+			id = int16(r.zigzag())
+		}
+		s[id] = r.value(typ)
+		last = id
+	}
+}
+
+func snappyDecode(src []byte) ([]byte, error) {
+	n, l := binary.Uvarint(src)
+	src = src[l:]
+
+	var dst []byte
+	for len(src) > 0 {
+		tag := src[0]
+		switch tag & 3 {
+		case 0:
+			length := int(tag>>2) + 1
+			src = src[1:]
+			switch {
+			case length == 61:
+				length = int(src[0]) + 1
+				src = src[1:]
+			case length == 62:
+				length = int(binary.LittleEndian.Uint16(src)) + 1
+				src = src[2:]
+			case length > 61:
+				return nil, errors.New("unsupported literal length")
+			}
+			dst = append(dst, src[:length]...)
+			src = src[length:]
+		case 2:
+			length := int(tag>>2) + 1
+			offset := int(binary.LittleEndian.Uint16(src[1:]))
+			if offset == 0 || offset > len(dst) {
+				return nil, errors.New("invalid offset")
+			}
+			for i := 0; i < length; i++ {
+				dst = append(dst, dst[len(dst)-offset])
+			}
+			src = src[3:]
+		default:
+			return nil, errors.New("unsupported tag")
+		}
+	}
+
+	if uint64(len(dst)) != n {
+		return nil, errors.New("wrong length")
+	}
+
+	return dst, nil
+}
+
+// readFile decodes the metadata of a Parquet file and the values of its columns. Null values are nil.
+func readFile(t *testing.T, b []byte) (map[int16]interface{}, map[string][]interface{}) {
+	if string(b[:4]) != magic || string(b[len(b)-4:]) != magic {
+		t.Fatal("Expected the file to start and end with the magic bytes")
+	}
+
+	n := int(binary.LittleEndian.Uint32(b[len(b)-8:]))
+	r := &thriftReader{b: b[len(b)-8-n : len(b)-8]}
+	meta := r.readStruct()
+	if r.pos != n {
+		t.Fatalf("Expected the metadata to be %d bytes long but read %d bytes", n, r.pos)
+	}
+
+	values := make(map[string][]interface{})
+	for _, g := range meta[4].([]interface{}) {
+		for _, c := range g.(map[int16]interface{})[1].([]interface{}) {
+			cm := c.(map[int16]interface{})[3].(map[int16]interface{})
+			name := cm[3].([]interface{})[0].(string)
+			offset := int(cm[9].(int64))
+
+			pr := &thriftReader{b: b[offset:]}
+			header := pr.readStruct()
+			data := b[offset+pr.pos : offset+pr.pos+int(header[3].(int64))]
+			if int64(pr.pos+len(data)) != cm[7].(int64) {
+				t.Errorf("Expected the compressed size %d but got %d when case=%v", cm[7], pr.pos+len(data), name)
+			}
+
+			switch cm[4].(int64) {
+			case int64(Snappy):
+				var err error
+				if data, err = snappyDecode(data); err != nil {
+					t.Fatal(err)
+				}
+			case int64(Gzip):
+				zr, err := gzip.NewReader(bytes.NewReader(data))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if data, err = io.ReadAll(zr); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if int64(len(data)) != header[2].(int64) {
+				t.Errorf("Expected the uncompressed size %d but got %d when case=%v", header[2], len(data), name)
+			}
+
+			numValues := int(header[5].(map[int16]interface{})[1].(int64))
+			levelsLen := int(binary.LittleEndian.Uint32(data))
+			lr := &thriftReader{b: data[4 : 4+levelsLen]}
+
+			var levels []byte
+			for lr.pos < len(lr.b) {
+				run := int(lr.varint())
+				if run&1 != 0 {
+					t.Fatal("Expected RLE runs only")
+				}
+				v := lr.byte()
+				for i := 0; i < run>>1; i++ {
+					levels = append(levels, v)
+				}
+			}
+			if len(levels) != numValues {
+				t.Fatalf("Expected %d levels but got %d", numValues, len(levels))
+			}
+
+			vals := data[4+levelsLen:]
+			for _, l := range levels {
+				if l == 0 {
+					values[name] = append(values[name], nil)
+					continue
+				}
+
+				if cm[1].(int64) == typeInt64 {
+					values[name] = append(values[name], int64(binary.LittleEndian.Uint64(vals)))
+					vals = vals[8:]
+				} else {
+					n := int(binary.LittleEndian.Uint32(vals))
+					values[name] = append(values[name], string(vals[4:4+n]))
+					vals = vals[4+n:]
+				}
+			}
+		}
+	}
+
+	return meta, values
+}
+
+var published = time.Date(2020, 5, 1, 8, 30, 0, 0, time.UTC)
+
+func testRecords(n int) []Record {
+	records := make([]Record, n)
+	for i := range records {
+		records[i] = Record{
+			Article: newsapi.Article{
+				Source:      newsapi.ArticleSource{ID: "example", Name: "Example News"},
+				Title:       fmt.Sprintf("Article %d", i),
+				URL:         fmt.Sprintf("https://example.com/%d", i),
+				PublishedAt: published.Add(time.Duration(i) * time.Minute),
+				Content:     strings.Repeat("Markets rallied on Friday. ", i%5),
+			},
+			CollectedAt: published.Add(time.Hour),
+			Query:       "markets",
+		}
+	}
+
+	// an article with nothing but a title
+	records[0] = Record{Article: newsapi.Article{Title: "Only a title"}}
+
+	return records
+}
+
+func TestWriter(t *testing.T) {
+	records := testRecords(250)
+
+	for _, c := range []Compression{Uncompressed, Snappy, Gzip} {
+		var buf bytes.Buffer
+		w := NewWriter(&buf, Options{RowGroupRows: 100, Compression: c, Metadata: map[string]string{"source": "test"}})
+		if err := w.Write(records...); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		meta, values := readFile(t, buf.Bytes())
+
+		if meta[3].(int64) != 250 || len(meta[4].([]interface{})) != 3 {
+			t.Errorf("Expected 250 rows in 3 row groups but got %v rows in %d row groups when case=%v", meta[3], len(meta[4].([]interface{})), c)
+		}
+
+		schema := meta[2].([]interface{})
+		if len(schema) != len(columns)+1 || schema[0].(map[int16]interface{})[5].(int64) != int64(len(columns)) {
+			t.Errorf("Expected a root with %d columns but got %v when case=%v", len(columns), schema[0], c)
+		}
+
+		if orders := meta[7].([]interface{}); len(orders) != len(columns) {
+			t.Errorf("Expected a column order for every column but got %v when case=%v", orders, c)
+		}
+
+		kv := meta[5].([]interface{})[0].(map[int16]interface{})
+		if kv[1] != "source" || kv[2] != "test" {
+			t.Errorf("Expected the key-value metadata but got %v when case=%v", kv, c)
+		}
+
+		for i, r := range records {
+			expected := map[string]interface{}{
+				"title":        r.Title,
+				"url":          nil,
+				"published_at": nil,
+				"content":      nil,
+				"query":        nil,
+			}
+			if i > 0 {
+				expected["url"] = r.URL
+				expected["published_at"] = r.PublishedAt.UnixNano() / int64(time.Millisecond)
+				expected["query"] = "markets"
+				if r.Content != "" {
+					expected["content"] = r.Content
+				}
+			}
+
+			for name, v := range expected {
+				if got := values[name][i]; got != v {
+					t.Errorf("Expected %v but got %v when case=%v/%v/%d", v, got, c, name, i)
+				}
+			}
+		}
+	}
+}
+
+func TestTimestampSchema(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteArticles(&buf, nil, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	meta, _ := readFile(t, buf.Bytes())
+	if meta[3].(int64) != 0 {
+		t.Errorf("Expected an empty file but got %v rows", meta[3])
+	}
+
+	for _, e := range meta[2].([]interface{})[1:] {
+		el := e.(map[int16]interface{})
+		if el[4] != "published_at" {
+			continue
+		}
+
+		ts := el[10].(map[int16]interface{})[8].(map[int16]interface{})
+		expected := map[int16]interface{}{1: true, 2: map[int16]interface{}{1: map[int16]interface{}{}}}
+		if el[1].(int64) != typeInt64 || el[6].(int64) != convertedTimestampMillis || !reflect.DeepEqual(ts, expected) {
+			t.Errorf("Expected a UTC timestamp in milliseconds but got %v", el)
+		}
+	}
+}
+
+func TestWriteFunc(t *testing.T) {
+	records := testRecords(20)
+
+	i := 0
+	next := func() (Record, error) {
+		if i == len(records) {
+			return Record{}, io.EOF
+		}
+		i++
+		return records[i-1], nil
+	}
+
+	var buf bytes.Buffer
+	if err := WriteFunc(&buf, next, Options{RowGroupBytes: 200}); err != nil {
+		t.Fatal(err)
+	}
+
+	meta, values := readFile(t, buf.Bytes())
+	if meta[3].(int64) != 20 || len(meta[4].([]interface{})) < 2 || len(values["title"]) != 20 {
+		t.Errorf("Expected 20 rows in several row groups but got %v rows in %d row groups", meta[3], len(meta[4].([]interface{})))
+	}
+
+	failing := func() (Record, error) { return Record{}, errors.New("failed") }
+	if err := WriteFunc(&buf, failing, Options{}); err == nil {
+		t.Error("Expected the error of the iterator")
+	}
+}
+
+func TestSnappy(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	random := make([]byte, 100000)
+	rnd.Read(random)
+
+	cases := [][]byte{
+		nil,
+		[]byte("a"),
+		[]byte(strings.Repeat("abcd", 50000)),
+		[]byte(strings.Repeat("Markets rallied on Friday. ", 1000)),
+		random,
+	}
+
+	for _, i := range cases {
+		out, err := snappyDecode(snappyEncode(i))
+		if err != nil || !bytes.Equal(out, i) {
+			t.Errorf("Expected the input to survive a round trip when case=%d bytes: %v", len(i), err)
+		}
+	}
+
+	if c := snappyEncode(cases[2]); len(c) > len(cases[2])/10 {
+		t.Errorf("Expected repetitive input to be compressed but got %d bytes", len(c))
+	}
+}
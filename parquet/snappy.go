@@ -0,0 +1,103 @@
+package parquet
+
+import (
+	"encoding/binary"
+)
+
+const (
+	snappyBlockSize = 1 << 16
+	snappyTableBits = 14
+)
+
+// snappyEncode compresses src with the raw Snappy block format Parquet uses. The input is compressed in
+// blocks of 64 KiB like the reference implementation does, so every copy fits into a 2 byte offset.
+func snappyEncode(src []byte) []byte {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], uint64(len(src)))
+
+	dst := make([]byte, 0, n+len(src)+len(src)/6)
+	dst = append(dst, b[:n]...)
+
+	for len(src) > 0 {
+		block := src
+		if len(block) > snappyBlockSize {
+			block = block[:snappyBlockSize]
+		}
+		dst = snappyEncodeBlock(dst, block)
+		src = src[len(block):]
+	}
+
+	return dst
+}
+
+func snappyEncodeBlock(dst, src []byte) []byte {
+	// the positions are stored with an offset of 1 so 0 means no position
+	var table [1 << snappyTableBits]int32
+
+	load := func(i int) uint32 {
+		return binary.LittleEndian.Uint32(src[i:])
+	}
+	hash := func(v uint32) uint32 {
+		return (v * 0x1e35a7bd) >> (32 - snappyTableBits)
+	}
+
+	lit := 0
+	for s := 0; s+4 <= len(src); {
+		v := load(s)
+		h := hash(v)
+		cand := int(table[h]) - 1
+		table[h] = int32(s + 1)
+
+		if cand < 0 || load(cand) != v {
+			s++
+			continue
+		}
+
+		dst = snappyLiteral(dst, src[lit:s])
+
+		length := 4
+		for s+length < len(src) && src[cand+length] == src[s+length] {
+			length++
+		}
+
+		dst = snappyCopy(dst, s-cand, length)
+		s += length
+		lit = s
+	}
+
+	return snappyLiteral(dst, src[lit:])
+}
+
+func snappyLiteral(dst, lit []byte) []byte {
+	if len(lit) == 0 {
+		return dst
+	}
+
+	n := len(lit) - 1
+	switch {
+	case n < 60:
+		dst = append(dst, byte(n)<<2)
+	case n < 1<<8:
+		dst = append(dst, 60<<2, byte(n))
+	default:
+		// a block is at most 64 KiB so 2 bytes are always enough
+		dst = append(dst, 61<<2, byte(n), byte(n>>8))
+	}
+
+	return append(dst, lit...)
+}
+
+func snappyCopy(dst []byte, offset, length int) []byte {
+	// a copy with a 2 byte offset has a length between 1 and 64
+	for length > 0 {
+		n := length
+		if n > 64 {
+			n = 64
+		}
+
+		dst = append(dst, byte(n-1)<<2|2, byte(offset), byte(offset>>8))
+		length -= n
+	}
+
+	return dst
+}
@@ -0,0 +1,111 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// The types of the Thrift compact protocol.
+const (
+	tBoolTrue  = 1
+	tBoolFalse = 2
+	tI32       = 5
+	tI64       = 6
+	tBinary    = 8
+	tList      = 9
+	tStruct    = 12
+)
+
+// thriftWriter writes structs with the Thrift compact protocol, which is used for the metadata of
+// Parquet files. Fields have to be written in ascending order of their IDs.
+type thriftWriter struct {
+	buf bytes.Buffer
+	// last contains the ID of the last field of every struct which is being written
+	last []int16
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	t.buf.Write(b[:n])
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+func (t *thriftWriter) fieldHeader(id int16, typ byte) {
+	last := &t.last[len(t.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(zigzag(int64(id)))
+	}
+	*last = id
+}
+
+func (t *thriftWriter) structBegin() {
+	t.last = append(t.last, 0)
+}
+
+func (t *thriftWriter) structEnd() {
+	t.buf.WriteByte(0)
+	t.last = t.last[:len(t.last)-1]
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.fieldHeader(id, tI32)
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.fieldHeader(id, tI64)
+	t.varint(zigzag(v))
+}
+
+func (t *thriftWriter) bool(id int16, v bool) {
+	if v {
+		t.fieldHeader(id, tBoolTrue)
+	} else {
+		t.fieldHeader(id, tBoolFalse)
+	}
+}
+
+func (t *thriftWriter) binary(id int16, v []byte) {
+	t.fieldHeader(id, tBinary)
+	t.varint(uint64(len(v)))
+	t.buf.Write(v)
+}
+
+func (t *thriftWriter) string(id int16, v string) {
+	t.binary(id, []byte(v))
+}
+
+// structField starts a field containing a struct. It has to be closed with structEnd.
+func (t *thriftWriter) structField(id int16) {
+	t.fieldHeader(id, tStruct)
+	t.structBegin()
+}
+
+// listField starts a field containing a list of n elements of the type.
+func (t *thriftWriter) listField(id int16, typ byte, n int) {
+	t.fieldHeader(id, tList)
+	if n < 15 {
+		t.buf.WriteByte(byte(n)<<4 | typ)
+	} else {
+		t.buf.WriteByte(0xf0 | typ)
+		t.varint(uint64(n))
+	}
+}
+
+// The elements of lists are written without field headers.
+
+func (t *thriftWriter) listI32(v int32) {
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) listString(v string) {
+	t.varint(uint64(len(v)))
+	t.buf.WriteString(v)
+}
@@ -0,0 +1,378 @@
+package newsapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultPipelineConcurrency is the number of articles a Pipeline processes at once when
+// Pipeline.Concurrency isn't set.
+const DefaultPipelineConcurrency = 4
+
+// Annotations holds the results of the stages of a pipeline. Every stage decides on its own keys,
+// it's a good idea to prefix them with the name of the stage, e.g. "sentiment.compound".
+type Annotations map[string]interface{}
+
+// EnrichedArticle is an article together with the annotations the stages of a pipeline have attached to it.
+type EnrichedArticle struct {
+	Article
+	// Language and LanguageConfidence are set by the LanguageEnricher and DetectLanguages.
+	Language           string  `json:"language,omitempty"`
+	LanguageConfidence float64 `json:"languageConfidence,omitempty"`
+
+	Annotations Annotations `json:"annotations,omitempty"`
+	// Errors maps the names of the stages which failed with the ErrorSkip policy to their error messages.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// Enricher is implemented by every stage of a pipeline. Enrich may modify the article and add annotations.
+// The context is cancelled once the timeout of the stage has passed, so long running enrichers should watch it.
+type Enricher interface {
+	Enrich(ctx context.Context, a *EnrichedArticle) error
+}
+
+// EnricherFunc turns a function into an Enricher.
+type EnricherFunc func(ctx context.Context, a *EnrichedArticle) error
+
+// Enrich calls f(ctx, a).
+func (f EnricherFunc) Enrich(ctx context.Context, a *EnrichedArticle) error {
+	return f(ctx, a)
+}
+
+// Enrich normalizes the article. It makes a Normalizer usable as a pipeline stage.
+func (n Normalizer) Enrich(ctx context.Context, a *EnrichedArticle) error {
+	a.Article = n.Normalize(a.Article)
+	return nil
+}
+
+// LanguageEnricher sets the Language and LanguageConfidence fields of the article with DetectArticleLanguage.
+var LanguageEnricher = EnricherFunc(func(ctx context.Context, a *EnrichedArticle) error {
+	a.Language, a.LanguageConfidence = DetectArticleLanguage(a.Article)
+	return nil
+})
+
+// ErrorPolicy determines what happens to an article when a stage fails.
+type ErrorPolicy int
+
+const (
+	// ErrorSkip records the error in the Errors field of the article and continues with the next stage.
+	ErrorSkip ErrorPolicy = iota
+	// ErrorDrop removes the article from the result.
+	ErrorDrop
+	// ErrorAbort stops the whole pipeline and returns the error.
+	ErrorAbort
+)
+
+// Stage is a single step of a pipeline.
+type Stage struct {
+	// Name identifies the stage in the errors of an article and in the metrics of the pipeline.
+	Name     string
+	Enricher Enricher
+	// Timeout limits how long the stage may take per article. There's no limit if it's zero.
+	Timeout time.Duration
+	OnError ErrorPolicy
+}
+
+// StageMetrics contains the metrics of a single stage.
+type StageMetrics struct {
+	// Processed is the number of articles the stage has been run on.
+	Processed int `json:"processed"`
+	// Failed is the number of articles the stage returned an error for, TimedOut the number of those
+	// errors which have been caused by the timeout of the stage.
+	Failed   int `json:"failed"`
+	TimedOut int `json:"timedOut"`
+	// Dropped is the number of articles which have been dropped because of the ErrorDrop policy.
+	Dropped int `json:"dropped"`
+	// Duration is the total time spent in the stage.
+	Duration time.Duration `json:"duration"`
+}
+
+// StageError is returned by a pipeline when a stage with the ErrorAbort policy fails.
+type StageError struct {
+	Stage string
+	// URL is the URL of the article the stage failed on.
+	URL string
+	Err error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("Stage %s failed for %s: %v", e.Stage, e.URL, e.Err)
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// Pipeline runs every article through a list of stages. The stages of a single article run one after another
+// in the order of the Stages field while several articles are processed at once. A pipeline can be run
+// by hand or be set on a Client which then runs it on every response:
+//
+//	p := &newsapi.Pipeline{Stages: []newsapi.Stage{
+//		{Name: "normalize", Enricher: newsapi.DefaultNormalizer},
+//		{Name: "language", Enricher: newsapi.LanguageEnricher},
+//		{Name: "tags", Enricher: myTagger, Timeout: time.Second, OnError: newsapi.ErrorSkip},
+//	}}
+//
+//	c := newsapi.Client{APIKey: "your-api-key", Pipeline: p}
+//
+// The Stages and Concurrency fields must not be changed while the pipeline is running.
+type Pipeline struct {
+	Stages []Stage
+	// Concurrency is the number of articles processed at once. DefaultPipelineConcurrency is used
+	// if it's smaller than 1.
+	Concurrency int
+
+	mu      sync.Mutex
+	metrics map[string]StageMetrics
+}
+
+// Metrics returns the metrics of every stage collected since the pipeline has been created, keyed by the names of the stages.
+func (p *Pipeline) Metrics() map[string]StageMetrics {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	m := make(map[string]StageMetrics, len(p.metrics))
+	for name, sm := range p.metrics {
+		m[name] = sm
+	}
+
+	return m
+}
+
+func (p *Pipeline) record(name string, d time.Duration, err error, timedOut, dropped bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.metrics == nil {
+		p.metrics = make(map[string]StageMetrics)
+	}
+
+	m := p.metrics[name]
+	m.Processed++
+	m.Duration += d
+	if err != nil {
+		m.Failed++
+	}
+	if timedOut {
+		m.TimedOut++
+	}
+	if dropped {
+		m.Dropped++
+	}
+	p.metrics[name] = m
+}
+
+func (p *Pipeline) concurrency() int {
+	if p.Concurrency < 1 {
+		return DefaultPipelineConcurrency
+	}
+
+	return p.Concurrency
+}
+
+// process runs the stages on a single article. It returns false if the article has been dropped.
+func (p *Pipeline) process(ctx context.Context, a *EnrichedArticle) (bool, error) {
+	for _, s := range p.Stages {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+
+		stageCtx, cancel := ctx, context.CancelFunc(func() {})
+		if s.Timeout > 0 {
+			stageCtx, cancel = context.WithTimeout(ctx, s.Timeout)
+		}
+
+		start := time.Now()
+		err := s.Enricher.Enrich(stageCtx, a)
+		timedOut := err != nil && errors.Is(stageCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil
+		cancel()
+
+		if err == nil {
+			p.record(s.Name, time.Since(start), nil, false, false)
+			continue
+		}
+
+		p.record(s.Name, time.Since(start), err, timedOut, s.OnError == ErrorDrop)
+
+		switch s.OnError {
+		case ErrorDrop:
+			return false, nil
+		case ErrorAbort:
+			return false, &StageError{Stage: s.Name, URL: a.URL, Err: err}
+		default:
+			if a.Errors == nil {
+				a.Errors = make(map[string]string)
+			}
+			a.Errors[s.Name] = err.Error()
+		}
+	}
+
+	return true, nil
+}
+
+// Run runs the pipeline over the articles and returns the enriched articles in their original order,
+// without those which have been dropped. If a stage with the ErrorAbort policy fails or the context is
+// cancelled, the remaining articles aren't processed and the error is returned.
+func (p *Pipeline) Run(ctx context.Context, articles []Article) ([]EnrichedArticle, error) {
+	enriched, keep, err := p.run(ctx, articles)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]EnrichedArticle, 0, len(articles))
+	for i, ok := range keep {
+		if ok {
+			result = append(result, enriched[i])
+		}
+	}
+
+	return result, nil
+}
+
+// run runs the pipeline over the articles and returns every enriched article together with whether it
+// has been kept.
+func (p *Pipeline) run(ctx context.Context, articles []Article) ([]EnrichedArticle, []bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		enriched = make([]EnrichedArticle, len(articles))
+		keep     = make([]bool, len(articles))
+		jobs     = make(chan int)
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+
+	for w := 0; w < p.concurrency(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				enriched[i] = EnrichedArticle{Article: articles[i], Annotations: make(Annotations)}
+				ok, err := p.process(ctx, &enriched[i])
+				if err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+				keep[i] = ok
+			}
+		}()
+	}
+
+feed:
+	for i := range articles {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return enriched, keep, nil
+}
+
+// Stream runs the pipeline over the articles received from in until in is closed or the context is cancelled.
+// The enriched articles are sent to the returned channel as soon as they're done, so they may arrive in
+// a different order than they have been received in. Both returned channels are closed once the pipeline
+// has finished. If a stage with the ErrorAbort policy fails, the error is sent to the error channel and
+// the pipeline stops.
+func (p *Pipeline) Stream(ctx context.Context, in <-chan Article) (<-chan EnrichedArticle, <-chan error) {
+	var (
+		out  = make(chan EnrichedArticle)
+		errc = make(chan error, 1)
+		wg   sync.WaitGroup
+		once sync.Once
+	)
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	for w := 0; w < p.concurrency(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				var (
+					a  Article
+					ok bool
+				)
+
+				select {
+				case a, ok = <-in:
+					if !ok {
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+
+				e := EnrichedArticle{Article: a, Annotations: make(Annotations)}
+				keep, err := p.process(ctx, &e)
+				if err != nil {
+					once.Do(func() {
+						// cancellation from the outside isn't an error of the pipeline
+						if ctx.Err() == nil {
+							errc <- err
+						}
+						cancel()
+					})
+					return
+				}
+
+				if !keep {
+					continue
+				}
+
+				select {
+				case out <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		cancel()
+		close(out)
+		close(errc)
+	}()
+
+	return out, errc
+}
+
+// enrich runs the pipeline of the client on the articles of a response.
+func (c *Client) enrich(ctx context.Context, r *articleResp) error {
+	if c.Pipeline == nil {
+		return nil
+	}
+
+	enriched, err := c.Pipeline.Run(ctx, r.Articles)
+	if err != nil {
+		return err
+	}
+
+	r.Enriched = enriched
+	r.Articles = make([]Article, len(enriched))
+	for i, e := range enriched {
+		r.Articles[i] = e.Article
+	}
+
+	return nil
+}
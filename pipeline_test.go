@@ -0,0 +1,193 @@
+package newsapi
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func pipelineArticles(n int) []Article {
+	articles := make([]Article, n)
+	for i := range articles {
+		articles[i] = Article{URL: "https://example.com/" + strconv.Itoa(i), Title: "  Title   " + strconv.Itoa(i)}
+	}
+
+	return articles
+}
+
+func TestPipelineRun(t *testing.T) {
+	var calls int32
+
+	p := &Pipeline{
+		Concurrency: 3,
+		Stages: []Stage{
+			{Name: "normalize", Enricher: DefaultNormalizer},
+			{Name: "index", Enricher: EnricherFunc(func(ctx context.Context, a *EnrichedArticle) error {
+				atomic.AddInt32(&calls, 1)
+				a.Annotations["index.title"] = a.Title
+				return nil
+			})},
+			{Name: "flaky", Enricher: EnricherFunc(func(ctx context.Context, a *EnrichedArticle) error {
+				if a.Title == "Title 3" {
+					return errors.New("flaky")
+				}
+				return nil
+			}), OnError: ErrorSkip},
+			{Name: "filter", Enricher: EnricherFunc(func(ctx context.Context, a *EnrichedArticle) error {
+				if a.Title == "Title 5" {
+					return errors.New("unwanted")
+				}
+				return nil
+			}), OnError: ErrorDrop},
+		},
+	}
+
+	enriched, err := p.Run(context.Background(), pipelineArticles(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(enriched) != 9 {
+		t.Fatalf("Expected 9 articles but got %d", len(enriched))
+	}
+
+	for i, e := range enriched {
+		// the dropped article leaves a gap
+		n := i
+		if i >= 5 {
+			n++
+		}
+
+		if e.Title != "Title "+strconv.Itoa(n) || e.Annotations["index.title"] != e.Title {
+			t.Errorf("Expected the articles to keep their order and annotations but got %q and %v", e.Title, e.Annotations)
+		}
+
+		if (n == 3) != (e.Errors["flaky"] == "flaky") {
+			t.Errorf("Unexpected errors %v for %q", e.Errors, e.Title)
+		}
+	}
+
+	if calls != 10 {
+		t.Errorf("Expected 10 calls but got %d", calls)
+	}
+
+	m := p.Metrics()
+	if m["index"].Processed != 10 || m["flaky"].Failed != 1 || m["filter"].Dropped != 1 || m["filter"].Processed != 10 {
+		t.Errorf("Unexpected metrics %+v", m)
+	}
+}
+
+func TestPipelineAbort(t *testing.T) {
+	errBroken := errors.New("broken")
+
+	p := &Pipeline{Stages: []Stage{
+		{Name: "broken", Enricher: EnricherFunc(func(ctx context.Context, a *EnrichedArticle) error {
+			if a.URL == "https://example.com/2" {
+				return errBroken
+			}
+			return nil
+		}), OnError: ErrorAbort},
+	}}
+
+	_, err := p.Run(context.Background(), pipelineArticles(20))
+
+	var stageErr *StageError
+	if !errors.As(err, &stageErr) || stageErr.Stage != "broken" || !errors.Is(err, errBroken) {
+		t.Errorf("Expected a StageError but got %v", err)
+	}
+}
+
+func TestPipelineTimeout(t *testing.T) {
+	p := &Pipeline{Stages: []Stage{
+		{Name: "slow", Timeout: 10 * time.Millisecond, Enricher: EnricherFunc(func(ctx context.Context, a *EnrichedArticle) error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second):
+				return nil
+			}
+		})},
+	}}
+
+	enriched, err := p.Run(context.Background(), pipelineArticles(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(enriched) != 2 || enriched[0].Errors["slow"] == "" {
+		t.Errorf("Expected the timeout to be recorded but got %v", enriched)
+	}
+
+	if m := p.Metrics()["slow"]; m.TimedOut != 2 || m.Failed != 2 {
+		t.Errorf("Unexpected metrics %+v", m)
+	}
+}
+
+func TestPipelineStream(t *testing.T) {
+	p := &Pipeline{Stages: []Stage{
+		{Name: "language", Enricher: LanguageEnricher},
+		{Name: "drop", Enricher: EnricherFunc(func(ctx context.Context, a *EnrichedArticle) error {
+			if a.URL == "https://example.com/0" {
+				return errors.New("drop")
+			}
+			return nil
+		}), OnError: ErrorDrop},
+	}}
+
+	in := make(chan Article)
+	go func() {
+		for _, a := range pipelineArticles(5) {
+			a.Title = "The government announced new measures on Tuesday"
+			in <- a
+		}
+		close(in)
+	}()
+
+	out, errc := p.Stream(context.Background(), in)
+
+	var n int
+	for e := range out {
+		if e.Language != "en" {
+			t.Errorf("Expected the language to be detected but got %q", e.Language)
+		}
+		n++
+	}
+
+	if err := <-errc; err != nil {
+		t.Error(err)
+	}
+
+	if n != 4 {
+		t.Errorf("Expected 4 articles but got %d", n)
+	}
+}
+
+func TestClientPipeline(t *testing.T) {
+	c := Client{Pipeline: &Pipeline{Stages: []Stage{
+		{Name: "drop", Enricher: EnricherFunc(func(ctx context.Context, a *EnrichedArticle) error {
+			if a.URL == "https://example.com/1" {
+				return errors.New("drop")
+			}
+			a.Annotations["seen"] = true
+			return nil
+		}), OnError: ErrorDrop},
+	}}}
+
+	r := articleResp{Articles: pipelineArticles(3)}
+	if err := c.enrich(context.Background(), &r); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.Articles) != 2 || len(r.Enriched) != 2 || r.Enriched[1].URL != r.Articles[1].URL || r.Enriched[0].Annotations["seen"] != true {
+		t.Errorf("Expected the response to contain the enriched articles but got %+v", r)
+	}
+
+	var plain Client
+	r = articleResp{Articles: pipelineArticles(3)}
+	if err := plain.enrich(context.Background(), &r); err != nil || len(r.Articles) != 3 || r.Enriched != nil {
+		t.Error("Expected the response to be unchanged without a pipeline")
+	}
+}
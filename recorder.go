@@ -0,0 +1,61 @@
+package newsapi
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Redacted replaces the API key in the request metadata passed to a Recorder.
+const Redacted = "REDACTED"
+
+// RawResponse is a response of the API exactly as it has been received, together with the request which
+// produced it. The API key is never part of it.
+type RawResponse struct {
+	// Route is the path of the route, e.g. "/v2/top-headlines".
+	Route string
+	// Method and URL are the method and URL of the request. The API key is sent in a header, so the URL
+	// doesn't contain it.
+	Method string
+	URL    string
+	// RequestHeader contains the headers of the request with the API key replaced by Redacted.
+	RequestHeader http.Header
+	// Opts are the options the request has been made with, e.g. a TopHeadlinesOpts.
+	Opts       interface{}
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// RequestedAt is the time the request has been sent and RespondedAt the time the whole body has
+	// been received.
+	RequestedAt time.Time
+	RespondedAt time.Time
+}
+
+// Recorder receives every response of the API, including error responses, before it's decoded. If Record
+// returns an error, the method of the client which made the request fails with it.
+type Recorder interface {
+	Record(ctx context.Context, r RawResponse) error
+}
+
+// RecorderFunc turns a function into a Recorder.
+type RecorderFunc func(ctx context.Context, r RawResponse) error
+
+// Record calls f(ctx, r).
+func (f RecorderFunc) Record(ctx context.Context, r RawResponse) error {
+	return f(ctx, r)
+}
+
+// RedactHeader returns a copy of the header with the values of the headers which carry credentials
+// replaced by Redacted.
+func RedactHeader(h http.Header) http.Header {
+	c := h.Clone()
+	for name := range c {
+		switch strings.ToLower(name) {
+		case "x-api-key", "authorization":
+			c[name] = []string{Redacted}
+		}
+	}
+
+	return c
+}
@@ -0,0 +1,86 @@
+package newsapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "secret" {
+			t.Errorf("Expected the API key to be sent but got %v", r.Header.Get("X-Api-Key"))
+		}
+		if r.URL.Query().Get("country") == "xx" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","code":"parameterInvalid"}`))
+			return
+		}
+		w.Write([]byte(`{"status":"ok","articles":[{"title":"Markets rally"}]}`))
+	}))
+	defer srv.Close()
+
+	var recorded []RawResponse
+	rec := RecorderFunc(func(ctx context.Context, r RawResponse) error {
+		recorded = append(recorded, r)
+		return nil
+	})
+
+	cases := []struct {
+		opts   TopHeadlinesOpts
+		status int
+		body   string
+		err    error
+	}{
+		{TopHeadlinesOpts{Country: "us"}, http.StatusOK, `{"status":"ok","articles":[{"title":"Markets rally"}]}`, nil},
+		{TopHeadlinesOpts{Country: "xx"}, http.StatusBadRequest, `{"status":"error","code":"parameterInvalid"}`, ErrParameterInvalid},
+	}
+
+	for _, i := range cases {
+		recorded = nil
+		_, err := fetchGetRoute(context.Background(), srv.URL+"/v2/top-headlines", "secret", i.opts, rec)
+		if err != i.err {
+			t.Errorf("Expected %v but got %v when case=%v", i.err, err, i.opts)
+		}
+
+		if len(recorded) != 1 {
+			t.Fatalf("Expected 1 recorded response but got %d when case=%v", len(recorded), i.opts)
+		}
+
+		r := recorded[0]
+		if r.Route != "/v2/top-headlines" || r.Method != "GET" || !strings.HasSuffix(r.URL, "country="+i.opts.Country) {
+			t.Errorf("Expected the request to be recorded but got %v %v %v when case=%v", r.Method, r.Route, r.URL, i.opts)
+		}
+		if r.StatusCode != i.status || string(r.Body) != i.body || !reflect.DeepEqual(r.Opts, i.opts) {
+			t.Errorf("Expected the response to be recorded but got %v %s %v when case=%v", r.StatusCode, r.Body, r.Opts, i.opts)
+		}
+		if r.RequestHeader.Get("X-Api-Key") != Redacted {
+			t.Errorf("Expected the API key to be redacted but got %v when case=%v", r.RequestHeader.Get("X-Api-Key"), i.opts)
+		}
+		if r.RequestedAt.IsZero() || r.RespondedAt.Before(r.RequestedAt) {
+			t.Errorf("Expected the timestamps to be set but got %v and %v when case=%v", r.RequestedAt, r.RespondedAt, i.opts)
+		}
+	}
+
+	failed := errors.New("disk full")
+	failing := RecorderFunc(func(ctx context.Context, r RawResponse) error { return failed })
+	if _, err := fetchGetRoute(context.Background(), srv.URL+"/v2/top-headlines", "secret", TopHeadlinesOpts{Country: "us"}, failing); err != failed {
+		t.Errorf("Expected the error of the recorder but got %v", err)
+	}
+}
+
+func TestRedactHeader(t *testing.T) {
+	h := http.Header{"X-Api-Key": {"secret"}, "Authorization": {"Bearer secret"}, "Accept": {"application/json"}}
+
+	r := RedactHeader(h)
+	if r.Get("X-Api-Key") != Redacted || r.Get("Authorization") != Redacted || r.Get("Accept") != "application/json" {
+		t.Errorf("Expected the credentials to be redacted but got %v", r)
+	}
+	if h.Get("X-Api-Key") != "secret" {
+		t.Error("Expected the original header to be unchanged")
+	}
+}
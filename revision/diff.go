@@ -0,0 +1,236 @@
+package revision
+
+import (
+	"strings"
+)
+
+// Op is the kind of an edit.
+type Op int
+
+// The kinds of edits.
+const (
+	Equal Op = iota
+	Delete
+	Insert
+)
+
+func (o Op) String() string {
+	switch o {
+	case Delete:
+		return "delete"
+	case Insert:
+		return "insert"
+	}
+
+	return "equal"
+}
+
+// Edit is a run of words which are the same in both texts, only part of the old text or only part of the
+// new text. The words are separated by single spaces.
+type Edit struct {
+	Op   Op
+	Text string
+}
+
+// Diff compares the words of two texts and returns the edits which turn the old text into the new one. Words
+// are separated by whitespace, so punctuation is part of the word it's attached to. If words have been
+// replaced, the deletion comes before the insertion. The memory needed only grows linearly with the length
+// of the texts, so the full texts of articles can be compared as well.
+func Diff(old, new string) []Edit {
+	a, b := strings.Fields(old), strings.Fields(new)
+
+	// the common prefix and suffix don't need to be part of the table
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []Op
+	var words []string
+	add := func(op Op, w string) {
+		ops = append(ops, op)
+		words = append(words, w)
+	}
+
+	for _, w := range a[:prefix] {
+		add(Equal, w)
+	}
+
+	diffWords(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], add)
+
+	for _, w := range a[len(a)-suffix:] {
+		add(Equal, w)
+	}
+
+	return merge(ops, words)
+}
+
+// maxTable is the maximum number of cells of the table of diffTable. Longer texts are split first, so
+// the memory only grows linearly with the length of the texts.
+const maxTable = 1 << 16
+
+// diffWords adds the edits which turn a into b. If the table would be too large, a is split in half and
+// b where the longest common subsequence crosses the middle of a (Hirschberg's algorithm).
+func diffWords(a, b []string, add func(Op, string)) {
+	if len(a) < 2 || len(a)*len(b) <= maxTable {
+		diffTable(a, b, add)
+		return
+	}
+
+	mid := len(a) / 2
+	head := lcsLengths(a[:mid], b)
+	tail := lcsLengths(reversed(a[mid:]), reversed(b))
+
+	// head[k] is the length for a[:mid] and b[:k], tail[len(b)-k] the one for a[mid:] and b[k:]
+	split, best := 0, -1
+	for k := range head {
+		if l := head[k] + tail[len(b)-k]; l > best {
+			split, best = k, l
+		}
+	}
+
+	diffWords(a[:mid], b[:split], add)
+	diffWords(a[mid:], b[split:], add)
+}
+
+// diffTable adds the edits which turn a into b using a table of the lengths of the longest common
+// subsequences of all suffixes of a and b.
+func diffTable(a, b []string, add func(Op, string)) {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			add(Equal, a[i])
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			add(Delete, a[i])
+			i++
+		default:
+			add(Insert, b[j])
+			j++
+		}
+	}
+}
+
+// lcsLengths returns the lengths of the longest common subsequences of a and every prefix of b. Only
+// two rows of the table are kept.
+func lcsLengths(a, b []string) []int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] >= cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev
+}
+
+func reversed(words []string) []string {
+	r := make([]string, len(words))
+	for i, w := range words {
+		r[len(words)-1-i] = w
+	}
+
+	return r
+}
+
+// merge joins the words of consecutive operations of the same kind. Deletions and insertions which are
+// interleaved are joined to a single deletion followed by a single insertion.
+func merge(ops []Op, words []string) []Edit {
+	var (
+		edits []Edit
+		del   []string
+		ins   []string
+	)
+
+	flush := func() {
+		if len(del) > 0 {
+			edits = append(edits, Edit{Op: Delete, Text: strings.Join(del, " ")})
+		}
+		if len(ins) > 0 {
+			edits = append(edits, Edit{Op: Insert, Text: strings.Join(ins, " ")})
+		}
+		del, ins = nil, nil
+	}
+
+	for k, op := range ops {
+		switch op {
+		case Delete:
+			del = append(del, words[k])
+		case Insert:
+			ins = append(ins, words[k])
+		default:
+			flush()
+			if n := len(edits); n > 0 && edits[n-1].Op == Equal {
+				edits[n-1].Text += " " + words[k]
+			} else {
+				edits = append(edits, Edit{Op: Equal, Text: words[k]})
+			}
+		}
+	}
+	flush()
+
+	return edits
+}
+
+// Markup shows the edits in the style of wdiff. Deleted words are enclosed in [- and -], inserted words
+// in {+ and +}.
+func Markup(edits []Edit) string {
+	parts := make([]string, len(edits))
+	for i, e := range edits {
+		switch e.Op {
+		case Delete:
+			parts[i] = "[-" + e.Text + "-]"
+		case Insert:
+			parts[i] = "{+" + e.Text + "+}"
+		default:
+			parts[i] = e.Text
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Changed returns the number of deleted and inserted words.
+func Changed(edits []Edit) (deleted, inserted int) {
+	for _, e := range edits {
+		switch e.Op {
+		case Delete:
+			deleted += len(strings.Fields(e.Text))
+		case Insert:
+			inserted += len(strings.Fields(e.Text))
+		}
+	}
+
+	return deleted, inserted
+}
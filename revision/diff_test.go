@@ -0,0 +1,85 @@
+package revision
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	cases := []struct {
+		old, new string
+		expected []Edit
+	}{
+		{"", "", nil},
+		{"Storm hits coast", "Storm hits coast", []Edit{{Equal, "Storm hits coast"}}},
+		{"Storm  hits\ncoast", "Storm hits coast", []Edit{{Equal, "Storm hits coast"}}},
+		{"", "Storm hits coast", []Edit{{Insert, "Storm hits coast"}}},
+		{"Storm hits coast", "", []Edit{{Delete, "Storm hits coast"}}},
+		{"Storm hits coast", "Storm batters coast", []Edit{{Equal, "Storm"}, {Delete, "hits"}, {Insert, "batters"}, {Equal, "coast"}}},
+		{"Storm hits coast", "Deadly storm hits coast", []Edit{{Delete, "Storm"}, {Insert, "Deadly storm"}, {Equal, "hits coast"}}},
+		// the punctuation is part of the word
+		{"Storm hits coast, leaves thousands without power", "Storm hits coast", []Edit{{Equal, "Storm hits"}, {Delete, "coast, leaves thousands without power"}, {Insert, "coast"}}},
+		{
+			"Protesters clash with police in the capital",
+			"Police clash with rioters in the capital",
+			[]Edit{{Delete, "Protesters"}, {Insert, "Police"}, {Equal, "clash with"}, {Delete, "police"}, {Insert, "rioters"}, {Equal, "in the capital"}},
+		},
+		{"a b c d", "c d a b", []Edit{{Delete, "a b"}, {Equal, "c d"}, {Insert, "a b"}}},
+	}
+
+	for _, i := range cases {
+		got := Diff(i.old, i.new)
+		if !reflect.DeepEqual(got, i.expected) {
+			t.Errorf("Expected %v but got %v when case=%q/%q", i.expected, got, i.old, i.new)
+		}
+	}
+}
+
+func TestDiffLong(t *testing.T) {
+	// the texts are too long for a single table
+	var old, new []string
+	for i := 0; i < 2000; i++ {
+		w := fmt.Sprintf("w%d", i)
+		if i%100 != 0 {
+			old = append(old, w)
+		}
+		if i%150 != 0 {
+			new = append(new, w)
+		}
+	}
+
+	edits := Diff(strings.Join(old, " "), strings.Join(new, " "))
+
+	var gotOld, gotNew []string
+	for _, e := range edits {
+		if e.Op != Insert {
+			gotOld = append(gotOld, e.Text)
+		}
+		if e.Op != Delete {
+			gotNew = append(gotNew, e.Text)
+		}
+	}
+	if strings.Join(gotOld, " ") != strings.Join(old, " ") || strings.Join(gotNew, " ") != strings.Join(new, " ") {
+		t.Fatal("Expected the edits to turn the old text into the new one")
+	}
+
+	// 20 words are missing from the old text and 14 from the new one, 7 of them from both
+	if deleted, inserted := Changed(edits); deleted != 7 || inserted != 13 {
+		t.Errorf("Expected 7 deleted and 13 inserted words but got %d and %d", deleted, inserted)
+	}
+}
+
+func TestMarkup(t *testing.T) {
+	edits := Diff("Storm hits the coast", "Storm batters coast overnight")
+
+	expected := "Storm [-hits the-] {+batters+} coast {+overnight+}"
+	if got := Markup(edits); got != expected {
+		t.Errorf("Expected %v but got %v", expected, got)
+	}
+
+	if deleted, inserted := Changed(edits); deleted != 2 || inserted != 2 {
+		t.Errorf("Expected 2 deleted and 2 inserted words but got %d and %d", deleted, inserted)
+	}
+}
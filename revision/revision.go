@@ -0,0 +1,359 @@
+/*
+Package revision keeps track of how articles change between polls. Outlets edit the titles and descriptions
+of their articles after publication, so the same article can look different every time TopHeadlines is
+called. A Tracker stores every version of an article, keyed by its canonical URL (see newsapi.CanonicalURL),
+and reports the changes:
+
+	var t revision.Tracker
+
+	for range time.Tick(15 * time.Minute) {
+		r, err := c.TopHeadlines(ctx, newsapi.TopHeadlinesOpts{Country: "us"})
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		for _, ch := range t.Observe(time.Now(), r.Articles...) {
+			if ch.Has(revision.Title) {
+				fmt.Println(revision.Markup(ch.Diff(revision.Title)))
+			}
+		}
+	}
+
+Diff compares two texts word by word, Markup shows the result in the style of wdiff:
+
+	Storm [-hits-] {+batters+} coast
+*/
+package revision
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+const formatVersion = 1
+
+// Field is a field of an article whose changes are tracked.
+type Field string
+
+// The tracked fields.
+const (
+	Title       Field = "title"
+	Description Field = "description"
+	Content     Field = "content"
+	URLToImage  Field = "urlToImage"
+	Author      Field = "author"
+)
+
+// Fields contains every tracked field.
+var Fields = []Field{Title, Description, Content, URLToImage, Author}
+
+// Value returns the value of the field of the article.
+func (f Field) Value(a newsapi.Article) string {
+	switch f {
+	case Title:
+		return a.Title
+	case Description:
+		return a.Description
+	case Content:
+		return a.Content
+	case URLToImage:
+		return a.URLToImage
+	case Author:
+		return a.Author
+	}
+
+	return ""
+}
+
+// Version is a version of an article.
+type Version struct {
+	newsapi.Article
+	// FirstSeen is the time the version has been observed for the first time and LastSeen the last time.
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+	// Changed contains the fields which differ from the previous version. It's empty for the first version.
+	Changed []Field `json:"changed,omitempty"`
+}
+
+// History contains the versions of an article, the oldest first.
+type History struct {
+	// Key is the canonical URL of the article.
+	Key      string    `json:"key"`
+	Versions []Version `json:"versions"`
+}
+
+// Latest returns the latest version.
+func (h History) Latest() Version {
+	return h.Versions[len(h.Versions)-1]
+}
+
+// Change is reported by Tracker.Observe if an article differs from its previous version.
+type Change struct {
+	Key      string
+	Previous Version
+	Current  Version
+}
+
+// Has reports whether the field has changed.
+func (c Change) Has(f Field) bool {
+	for _, changed := range c.Current.Changed {
+		if changed == f {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Diff returns the word-level differences of the field between the previous and the current version.
+func (c Change) Diff(f Field) []Edit {
+	return Diff(f.Value(c.Previous.Article), f.Value(c.Current.Article))
+}
+
+// Tracker stores the versions of articles. The zero value is ready to use and it's safe for concurrent use.
+type Tracker struct {
+	// IgnoreEmpty doesn't count a field which has become empty as a change, since the API sometimes omits
+	// the description or content of an article. The field keeps the value of the previous version instead.
+	IgnoreEmpty bool
+
+	mu        sync.Mutex
+	histories map[string]*History
+}
+
+// Observe records the articles as seen at the time and returns the articles which have changed since they
+// have been seen the last time. Articles without a URL are ignored, since they can't be recognized after
+// they have been edited.
+func (t *Tracker) Observe(at time.Time, articles ...newsapi.Article) []Change {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.histories == nil {
+		t.histories = make(map[string]*History)
+	}
+
+	var changes []Change
+	for _, a := range articles {
+		if a.URL == "" {
+			continue
+		}
+
+		key := newsapi.CanonicalURL(a)
+		h, ok := t.histories[key]
+		if !ok {
+			t.histories[key] = &History{Key: key, Versions: []Version{{Article: a, FirstSeen: at, LastSeen: at}}}
+			continue
+		}
+
+		latest := &h.Versions[len(h.Versions)-1]
+		if at.Before(latest.LastSeen) {
+			// an older response which arrived late doesn't replace a newer version
+			continue
+		}
+
+		var changed []Field
+		for _, f := range Fields {
+			if t.IgnoreEmpty && strings.TrimSpace(f.Value(a)) == "" {
+				setField(&a, f, f.Value(latest.Article))
+				continue
+			}
+			if normalize(f.Value(a)) != normalize(f.Value(latest.Article)) {
+				changed = append(changed, f)
+			}
+		}
+
+		if len(changed) == 0 {
+			latest.LastSeen = at
+			continue
+		}
+
+		v := Version{Article: a, FirstSeen: at, LastSeen: at, Changed: changed}
+		changes = append(changes, Change{Key: key, Previous: *latest, Current: v})
+		h.Versions = append(h.Versions, v)
+	}
+
+	return changes
+}
+
+func setField(a *newsapi.Article, f Field, v string) {
+	switch f {
+	case Title:
+		a.Title = v
+	case Description:
+		a.Description = v
+	case Content:
+		a.Content = v
+	case URLToImage:
+		a.URLToImage = v
+	case Author:
+		a.Author = v
+	}
+}
+
+// normalize collapses whitespace so a reformatted text doesn't count as a change.
+func normalize(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// History returns the versions of the article with the URL. The URL is canonicalized before it's looked
+// up, so any URL which has the same canonical URL can be used.
+func (t *Tracker) History(url string) (History, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h, ok := t.histories[newsapi.DefaultURLRules.Canonical(url)]
+	if !ok {
+		return History{}, false
+	}
+
+	return copyHistory(h), true
+}
+
+// Revised returns the histories of the articles with more than one version which have changed at or
+// after since, ordered by the time of their latest change, the most recent first.
+func (t *Tracker) Revised(since time.Time) []History {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var hs []History
+	for _, h := range t.histories {
+		if len(h.Versions) > 1 && !h.Latest().FirstSeen.Before(since) {
+			hs = append(hs, copyHistory(h))
+		}
+	}
+
+	sort.Slice(hs, func(i, j int) bool {
+		ti, tj := hs[i].Latest().FirstSeen, hs[j].Latest().FirstSeen
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return hs[i].Key < hs[j].Key
+	})
+
+	return hs
+}
+
+// Forget removes the histories of the articles with the URLs. The URLs are canonicalized first.
+func (t *Tracker) Forget(urls ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, u := range urls {
+		delete(t.histories, newsapi.DefaultURLRules.Canonical(u))
+	}
+}
+
+// Prune removes the histories of the articles which haven't been seen since before, e.g. to keep a
+// long-running tracker from growing without bounds. It returns the number of removed histories.
+func (t *Tracker) Prune(before time.Time) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	n := 0
+	for key, h := range t.histories {
+		if h.Latest().LastSeen.Before(before) {
+			delete(t.histories, key)
+			n++
+		}
+	}
+
+	return n
+}
+
+// Len returns the number of tracked articles.
+func (t *Tracker) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.histories)
+}
+
+func copyHistory(h *History) History {
+	return History{Key: h.Key, Versions: append([]Version(nil), h.Versions...)}
+}
+
+type model struct {
+	Version   int       `json:"version"`
+	Histories []History `json:"histories"`
+}
+
+// Save writes the histories as JSON to w.
+func (t *Tracker) Save(w io.Writer) error {
+	t.mu.Lock()
+	m := model{Version: formatVersion, Histories: make([]History, 0, len(t.histories))}
+	for _, h := range t.histories {
+		m.Histories = append(m.Histories, *h)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(m.Histories, func(i, j int) bool { return m.Histories[i].Key < m.Histories[j].Key })
+
+	return json.NewEncoder(w).Encode(m)
+}
+
+// SaveFile writes the histories to the file at path. An existing file is replaced. The histories are written
+// to a temporary file in the same directory first, so the file at path is never left half-written.
+func (t *Tracker) SaveFile(path string) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := t.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// Load reads a tracker which has been written by Save.
+func Load(r io.Reader) (*Tracker, error) {
+	var m model
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, err
+	}
+
+	if m.Version != formatVersion {
+		return nil, errors.New("The tracker has been saved in an unsupported format")
+	}
+
+	t := &Tracker{histories: make(map[string]*History, len(m.Histories))}
+	for i := range m.Histories {
+		h := m.Histories[i]
+		if len(h.Versions) == 0 {
+			return nil, errors.New("The tracker contains an article without versions")
+		}
+		t.histories[h.Key] = &h
+	}
+
+	return t, nil
+}
+
+// LoadFile reads a tracker from the file at path.
+func LoadFile(path string) (*Tracker, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Load(f)
+}
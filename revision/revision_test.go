@@ -0,0 +1,176 @@
+package revision
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+var polled = time.Date(2020, 5, 1, 8, 0, 0, 0, time.UTC)
+
+func article(title, description string) newsapi.Article {
+	return newsapi.Article{
+		Source:      newsapi.ArticleSource{Name: "Example News"},
+		Author:      "Jane Doe",
+		Title:       title,
+		Description: description,
+		URL:         "https://example.com/storm?utm_source=feed",
+	}
+}
+
+func TestObserve(t *testing.T) {
+	var tr Tracker
+
+	polls := []struct {
+		articles []newsapi.Article
+		changed  [][]Field
+	}{
+		{[]newsapi.Article{article("Storm hits coast", "A storm hit the coast."), {Title: "No URL"}}, nil},
+		{[]newsapi.Article{article("Storm hits coast", "A storm  hit the coast.")}, nil},
+		{[]newsapi.Article{article("Storm batters coast", "A storm hit the coast.")}, [][]Field{{Title}}},
+		{[]newsapi.Article{article("Deadly storm batters coast", "Three people died.")}, [][]Field{{Title, Description}}},
+		{[]newsapi.Article{article("Deadly storm batters coast", "")}, [][]Field{{Description}}},
+	}
+
+	for i, p := range polls {
+		changes := tr.Observe(polled.Add(time.Duration(i)*time.Hour), p.articles...)
+
+		var changed [][]Field
+		for _, c := range changes {
+			changed = append(changed, c.Current.Changed)
+		}
+		if !reflect.DeepEqual(changed, p.changed) {
+			t.Errorf("Expected %v but got %v when case=%d", p.changed, changed, i)
+		}
+	}
+
+	if tr.Len() != 1 {
+		t.Errorf("Expected articles without a URL to be ignored but got %d articles", tr.Len())
+	}
+
+	// any URL with the same canonical URL finds the history
+	h, ok := tr.History("https://example.com/storm")
+	if !ok || len(h.Versions) != 4 {
+		t.Fatalf("Expected 4 versions but got %v", h)
+	}
+
+	first := h.Versions[0]
+	if !first.FirstSeen.Equal(polled) || !first.LastSeen.Equal(polled.Add(time.Hour)) || first.Changed != nil {
+		t.Errorf("Expected the first version to be seen twice but got %v to %v", first.FirstSeen, first.LastSeen)
+	}
+	if latest := h.Latest(); !latest.FirstSeen.Equal(polled.Add(4*time.Hour)) || latest.Description != "" {
+		t.Errorf("Expected the latest version to be the last one but got %+v", latest)
+	}
+
+	// a late response with an older version doesn't count as a change
+	if changes := tr.Observe(polled.Add(time.Hour), article("Storm hits coast", "A storm hit the coast.")); len(changes) != 0 {
+		t.Errorf("Expected an older response to be ignored but got %v", changes)
+	}
+
+	revised := tr.Revised(polled.Add(3 * time.Hour))
+	if len(revised) != 1 || tr.Revised(polled.Add(5*time.Hour)) != nil {
+		t.Errorf("Expected the article to be revised in the fourth poll but got %v", revised)
+	}
+}
+
+func TestIgnoreEmpty(t *testing.T) {
+	tr := Tracker{IgnoreEmpty: true}
+	tr.Observe(polled, article("Storm hits coast", "A storm hit the coast."))
+
+	if changes := tr.Observe(polled.Add(time.Hour), article("Storm hits coast", "")); len(changes) != 0 {
+		t.Errorf("Expected an empty description to be ignored but got %v", changes)
+	}
+
+	changes := tr.Observe(polled.Add(2*time.Hour), article("Storm batters coast", ""))
+	if len(changes) != 1 || changes[0].Current.Description != "A storm hit the coast." {
+		t.Fatalf("Expected the new version to keep the description but got %v", changes)
+	}
+
+	c := changes[0]
+	if !c.Has(Title) || c.Has(Description) {
+		t.Errorf("Expected only the title to change but got %v", c.Current.Changed)
+	}
+	if got := Markup(c.Diff(Title)); got != "Storm [-hits-] {+batters+} coast" {
+		t.Errorf("Expected the diff of the title but got %v", got)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	var tr Tracker
+	other := article("Markets rally", "")
+	other.URL = "https://example.com/markets"
+
+	tr.Observe(polled, article("Storm hits coast", ""), other)
+	tr.Observe(polled.Add(2*time.Hour), article("Storm batters coast", ""))
+
+	if n := tr.Prune(polled.Add(time.Hour)); n != 1 || tr.Len() != 1 {
+		t.Errorf("Expected 1 history to be pruned but got %d and %d left", n, tr.Len())
+	}
+	if _, ok := tr.History(other.URL); ok {
+		t.Error("Expected the history which hasn't been seen recently to be pruned")
+	}
+
+	tr.Forget("https://example.com/storm?utm_source=rss")
+	if tr.Len() != 0 {
+		t.Errorf("Expected the history to be forgotten but got %d histories", tr.Len())
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	var tr Tracker
+	tr.Observe(polled, article("Storm hits coast", "A storm hit the coast."))
+	tr.Observe(polled.Add(time.Hour), article("Storm batters coast", "A storm hit the coast."))
+
+	path := filepath.Join(t.TempDir(), "revisions.json")
+	if err := tr.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// the file is replaced without leaving the temporary file behind
+	if err := tr.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if names, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*")); !reflect.DeepEqual(names, []string{path}) {
+		t.Errorf("Expected only the saved file but got %v", names)
+	}
+
+	loaded, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, _ := tr.History("https://example.com/storm")
+	got, _ := loaded.History("https://example.com/storm")
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v but got %v", expected, got)
+	}
+
+	// the loaded tracker continues the history
+	if changes := loaded.Observe(polled.Add(2*time.Hour), article("Storm batters coast", "Three people died.")); len(changes) != 1 {
+		t.Errorf("Expected a change but got %v", changes)
+	}
+
+	cases := []string{
+		`{"version":2,"histories":[]}`,
+		`{"version":1,"histories":[{"key":"https://example.com/storm","versions":[]}]}`,
+		`not json`,
+	}
+	for _, i := range cases {
+		if _, err := Load(strings.NewReader(i)); err == nil {
+			t.Errorf("Expected an error when case=%v", i)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := (&Tracker{}).Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(&buf); err != nil {
+		t.Errorf("Expected an empty tracker to be loaded but got %v", err)
+	}
+}
@@ -0,0 +1,385 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+// Query is a parsed search query in the syntax of the Q option of the /everything route:
+//
+//   - Words have to appear in the article, e.g. "bitcoin ethereum" matches articles which contain both words.
+//   - Phrases are surrounded by quotes and have to appear exactly, e.g. "\"federal reserve\"".
+//   - A word or phrase prefixed with + has to appear, one prefixed with - mustn't appear, e.g. "+bitcoin -ethereum".
+//   - AND, OR and NOT combine words, phrases and groups in parentheses, e.g. "crypto AND (ethereum OR litecoin) NOT bitcoin".
+//
+// Matching is case-insensitive. The zero value matches every article.
+type Query struct {
+	q    string
+	root node
+}
+
+type node interface{}
+
+// termNode is a single word if it contains one word and a phrase otherwise.
+type termNode struct {
+	words []string
+}
+
+type andNode struct {
+	nodes []node
+}
+
+type orNode struct {
+	nodes []node
+}
+
+type notNode struct {
+	node node
+}
+
+type tokenKind int
+
+const (
+	tokWord tokenKind = iota
+	tokPhrase
+	tokOpen
+	tokClose
+	tokPlus
+	tokMinus
+	tokAnd
+	tokOr
+	tokNot
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// ParseQuery parses q. An empty query matches every article.
+func ParseQuery(q string) (Query, error) {
+	toks, err := lex(q)
+	if err != nil {
+		return Query{}, err
+	}
+
+	if len(toks) == 0 {
+		return Query{q: q}, nil
+	}
+
+	p := parser{toks: toks}
+	root, err := p.parseOr()
+	if err != nil {
+		return Query{}, err
+	}
+
+	if p.pos < len(p.toks) {
+		return Query{}, fmt.Errorf("Unexpected %q in the query", p.toks[p.pos].text)
+	}
+
+	return Query{q: q, root: root}, nil
+}
+
+// String returns the query as it has been passed to ParseQuery.
+func (q Query) String() string {
+	return q.q
+}
+
+func lex(q string) ([]token, error) {
+	var (
+		toks  []token
+		runes = []rune(q)
+	)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			toks = append(toks, token{tokOpen, "("})
+			i++
+		case r == ')':
+			toks = append(toks, token{tokClose, ")"})
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, errors.New("The query contains a phrase without a closing quote")
+			}
+			toks = append(toks, token{tokPhrase, string(runes[i+1 : end])})
+			i = end + 1
+		case (r == '+' || r == '-') && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]):
+			// a + or - only is an operator at the start of a word or phrase, "covid-19" is a single word
+			if r == '+' {
+				toks = append(toks, token{tokPlus, "+"})
+			} else {
+				toks = append(toks, token{tokMinus, "-"})
+			}
+			i++
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && runes[end] != '(' && runes[end] != ')' && runes[end] != '"' {
+				end++
+			}
+
+			word := string(runes[i:end])
+			switch word {
+			case "AND":
+				toks = append(toks, token{tokAnd, word})
+			case "OR":
+				toks = append(toks, token{tokOr, word})
+			case "NOT":
+				toks = append(toks, token{tokNot, word})
+			default:
+				toks = append(toks, token{tokWord, word})
+			}
+			i = end
+		}
+	}
+
+	return toks, nil
+}
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.toks) {
+		return token{}, false
+	}
+
+	return p.toks[p.pos], true
+}
+
+func (p *parser) parseOr() (node, error) {
+	n, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []node{n}
+	for {
+		t, ok := p.peek()
+		if !ok || t.kind != tokOr {
+			break
+		}
+		p.pos++
+
+		n, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+
+	return orNode{nodes}, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	n, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []node{n}
+	for {
+		t, ok := p.peek()
+		if !ok {
+			break
+		}
+
+		switch t.kind {
+		case tokAnd:
+			p.pos++
+		case tokNot:
+			// "a NOT b" is short for "a AND NOT b", parseUnary handles the NOT
+		case tokWord, tokPhrase, tokOpen, tokPlus, tokMinus:
+			// words without an operator between them all have to appear
+		default:
+			if len(nodes) == 1 {
+				return nodes[0], nil
+			}
+			return andNode{nodes}, nil
+		}
+
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+
+	return andNode{nodes}, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, errors.New("The query ends unexpectedly")
+	}
+
+	switch t.kind {
+	case tokNot:
+		p.pos++
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	case tokMinus:
+		p.pos++
+		n, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	case tokPlus:
+		p.pos++
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, errors.New("The query ends unexpectedly")
+	}
+	p.pos++
+
+	switch t.kind {
+	case tokOpen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if t, ok := p.peek(); !ok || t.kind != tokClose {
+			return nil, errors.New("The query contains a parenthesis without a closing parenthesis")
+		}
+		p.pos++
+
+		return n, nil
+	case tokWord, tokPhrase:
+		// a word like "covid-19" becomes the phrase "covid 19", a word without any letters matches everything
+		words := tokenize(t.text)
+		if len(words) == 0 {
+			return andNode{}, nil
+		}
+		return termNode{words}, nil
+	}
+
+	return nil, fmt.Errorf("Unexpected %q in the query", t.text)
+}
+
+// Match reports whether the title, description, content or author of the article match the query.
+// It can be used to filter articles without an Index.
+func (q Query) Match(a newsapi.Article) bool {
+	if q.root == nil {
+		return true
+	}
+
+	var fields [][]string
+	for _, text := range fieldTexts(a) {
+		fields = append(fields, tokenize(text))
+	}
+
+	return matchNode(q.root, fields)
+}
+
+func matchNode(n node, fields [][]string) bool {
+	switch n := n.(type) {
+	case termNode:
+		for _, words := range fields {
+			if countPhrase(words, n.words) > 0 {
+				return true
+			}
+		}
+		return false
+	case andNode:
+		for _, c := range n.nodes {
+			if !matchNode(c, fields) {
+				return false
+			}
+		}
+		return true
+	case orNode:
+		for _, c := range n.nodes {
+			if matchNode(c, fields) {
+				return true
+			}
+		}
+		return false
+	case notNode:
+		return !matchNode(n.node, fields)
+	}
+
+	return false
+}
+
+// countPhrase returns how often phrase occurs in words.
+func countPhrase(words, phrase []string) int {
+	n := 0
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		match := true
+		for j, w := range phrase {
+			if words[i+j] != w {
+				match = false
+				break
+			}
+		}
+		if match {
+			n++
+		}
+	}
+
+	return n
+}
+
+// positiveTerms returns the terms of the query which aren't negated. Only they contribute to the score.
+func positiveTerms(n node) []termNode {
+	switch n := n.(type) {
+	case termNode:
+		return []termNode{n}
+	case andNode:
+		var terms []termNode
+		for _, c := range n.nodes {
+			terms = append(terms, positiveTerms(c)...)
+		}
+		return terms
+	case orNode:
+		var terms []termNode
+		for _, c := range n.nodes {
+			terms = append(terms, positiveTerms(c)...)
+		}
+		return terms
+	}
+
+	return nil
+}
+
+// tokenize splits text into lowercased words.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,73 @@
+package search
+
+import (
+	"testing"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+func TestParseQuery(t *testing.T) {
+	cases := []struct {
+		q     string
+		valid bool
+	}{
+		{"", true},
+		{"bitcoin", true},
+		{`"federal reserve" +rates -inflation`, true},
+		{"crypto AND (ethereum OR litecoin) NOT bitcoin", true},
+		{"covid-19", true},
+		{`"unclosed phrase`, false},
+		{"(bitcoin OR ethereum", false},
+		{"bitcoin)", false},
+		{"bitcoin AND", false},
+		{"OR bitcoin", false},
+	}
+
+	for _, i := range cases {
+		_, err := ParseQuery(i.q)
+		if (err == nil) != i.valid {
+			t.Errorf("Expected valid=%v but got %v when case=%q", i.valid, err, i.q)
+		}
+	}
+}
+
+func TestQueryMatch(t *testing.T) {
+	a := newsapi.Article{
+		Title:       "Bitcoin falls as the Federal Reserve raises rates",
+		Description: "Crypto markets react to the decision.",
+		Content:     "Ethereum and other coins followed. The COVID-19 recovery… [+1200 chars]",
+		Author:      "Jane Doe",
+	}
+
+	cases := []struct {
+		q        string
+		expected bool
+	}{
+		{"", true},
+		{"bitcoin", true},
+		{"BITCOIN rates", true},
+		{"bitcoin litecoin", false},
+		{"bitcoin OR litecoin", true},
+		{`"federal reserve"`, true},
+		{`"reserve federal"`, false},
+		{"+crypto -litecoin", true},
+		{"+crypto -ethereum", false},
+		{"crypto AND (ethereum OR litecoin) NOT bitcoin", false},
+		{"crypto AND (ethereum OR litecoin) NOT dogecoin", true},
+		{"NOT (litecoin OR dogecoin)", true},
+		{"covid-19", true},
+		{"doe", true},
+		{"chars", false},
+	}
+
+	for _, i := range cases {
+		q, err := ParseQuery(i.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if m := q.Match(a); m != i.expected {
+			t.Errorf("Expected %v but got %v when case=%q", i.expected, m, i.q)
+		}
+	}
+}
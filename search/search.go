@@ -0,0 +1,622 @@
+/*
+Package search is a full-text search index for articles which have already been fetched, so they can be
+searched again without spending requests. Articles are ranked with BM25 over their title, description,
+content and author, and queries use the same syntax as the Q option of the /everything route:
+
+	var idx search.Index
+
+	idx.Add(r.Articles...)
+
+	res, err := idx.Search(search.Opts{Q: "crypto AND (ethereum OR litecoin) NOT bitcoin", Limit: 10})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, h := range res.Hits {
+		fmt.Println(h.Score, h.Article.Title)
+	}
+
+The index can be written to a file with SaveFile and read again with LoadFile.
+*/
+package search
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+const (
+	// DefaultK1 controls how quickly the score of a word saturates if it occurs several times in an article.
+	DefaultK1 = 1.2
+	// DefaultB controls how much the score depends on the length of an article.
+	DefaultB = 0.75
+
+	formatVersion = 1
+)
+
+// Field is a field of an article which is indexed.
+type Field int
+
+// The indexed fields of an article.
+const (
+	Title Field = iota
+	Description
+	Content
+	Author
+	numFields
+)
+
+// Boosts weighs matches in the fields of an article against each other.
+type Boosts struct {
+	Title, Description, Content, Author float64
+}
+
+// DefaultBoosts is used if Index.Boosts isn't set. A match in the title counts the most.
+var DefaultBoosts = Boosts{Title: 3, Description: 1.5, Content: 1, Author: 0.5}
+
+func (b Boosts) of(f Field) float64 {
+	switch f {
+	case Title:
+		return b.Title
+	case Description:
+		return b.Description
+	case Content:
+		return b.Content
+	}
+
+	return b.Author
+}
+
+// Index is an in-memory inverted index of articles. Articles are identified by their canonical URL, so adding
+// an article again replaces the old version. The zero value is an empty index ready to use. It's safe for
+// concurrent use.
+type Index struct {
+	// Boosts weighs the fields. DefaultBoosts is used if it's the zero value.
+	Boosts Boosts
+	// K1 and B are the parameters of BM25. DefaultK1 and DefaultB are used if they're 0.
+	K1, B float64
+
+	mu sync.RWMutex
+	// docs contains nil for the slots of removed documents, which are listed in free
+	docs     []*doc
+	free     []int
+	byKey    map[string]int
+	postings map[string]map[int]*posting
+	totalLen [numFields]int
+	live     int
+}
+
+type doc struct {
+	article newsapi.Article
+	key     string
+	lengths [numFields]int
+	words   []string
+}
+
+// posting contains the positions of a word in the fields of a document.
+type posting struct {
+	positions [numFields][]int
+}
+
+// Opts defines which articles Search returns.
+type Opts struct {
+	// Q is matched against the title, description, content and author of the articles.
+	Q string
+	// QInTitle is only matched against the titles of the articles. It uses the same syntax as Q.
+	QInTitle string
+	// From and To restrict the PublishedAt field of the articles. From is inclusive, To is exclusive.
+	From, To time.Time
+	// Sources contains source IDs or source names.
+	Sources []string
+	// Limit is the maximum number of hits returned. All hits are returned if it's smaller than 1.
+	Limit  int
+	Offset int
+}
+
+// Hit is an article matching the search.
+type Hit struct {
+	Article newsapi.Article `json:"article"`
+	Score   float64         `json:"score"`
+}
+
+// Results contains the hits of a search ordered by their score. Hits with the same score are ordered
+// by their publishing time, newest first.
+type Results struct {
+	// Total is the number of matching articles, regardless of Limit and Offset.
+	Total int   `json:"total"`
+	Hits  []Hit `json:"hits"`
+}
+
+func (idx *Index) init() {
+	if idx.byKey == nil {
+		idx.byKey = make(map[string]int)
+		idx.postings = make(map[string]map[int]*posting)
+	}
+}
+
+func key(a newsapi.Article) string {
+	if a.URL == "" {
+		return "id:" + a.ID()
+	}
+
+	return newsapi.CanonicalURL(a)
+}
+
+func fieldTexts(a newsapi.Article) [numFields]string {
+	return [numFields]string{
+		Title:       a.Title,
+		Description: a.Description,
+		Content:     a.ContentText(),
+		Author:      a.Author,
+	}
+}
+
+// Add adds the articles to the index. An article which is already part of the index is replaced.
+func (idx *Index) Add(articles ...newsapi.Article) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.init()
+
+	for _, a := range articles {
+		k := key(a)
+		if id, ok := idx.byKey[k]; ok {
+			idx.remove(id)
+		}
+
+		d := &doc{article: a, key: k}
+		id := len(idx.docs)
+		if n := len(idx.free); n > 0 {
+			id, idx.free = idx.free[n-1], idx.free[:n-1]
+		}
+
+		for f, text := range fieldTexts(a) {
+			words := tokenize(text)
+			d.lengths[f] = len(words)
+			idx.totalLen[f] += len(words)
+
+			for pos, w := range words {
+				docs := idx.postings[w]
+				if docs == nil {
+					docs = make(map[int]*posting)
+					idx.postings[w] = docs
+				}
+
+				p := docs[id]
+				if p == nil {
+					p = &posting{}
+					docs[id] = p
+					d.words = append(d.words, w)
+				}
+				p.positions[f] = append(p.positions[f], pos)
+			}
+		}
+
+		if id == len(idx.docs) {
+			idx.docs = append(idx.docs, d)
+		} else {
+			idx.docs[id] = d
+		}
+		idx.byKey[k] = id
+		idx.live++
+	}
+}
+
+// Remove removes the articles with the URLs from the index. The URLs are canonicalized first.
+func (idx *Index) Remove(urls ...string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, u := range urls {
+		if id, ok := idx.byKey[newsapi.DefaultURLRules.Canonical(u)]; ok {
+			idx.remove(id)
+		}
+	}
+}
+
+func (idx *Index) remove(id int) {
+	d := idx.docs[id]
+
+	for _, w := range d.words {
+		delete(idx.postings[w], id)
+		if len(idx.postings[w]) == 0 {
+			delete(idx.postings, w)
+		}
+	}
+
+	for f, l := range d.lengths {
+		idx.totalLen[f] -= l
+	}
+
+	delete(idx.byKey, d.key)
+	// the slot is reused by the next document so the IDs of the other documents don't change
+	idx.docs[id] = nil
+	idx.free = append(idx.free, id)
+	idx.live--
+}
+
+// Len returns the number of articles in the index.
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return idx.live
+}
+
+// fieldMask contains the fields a query is matched against.
+type fieldMask [numFields]bool
+
+var (
+	allFields  = fieldMask{true, true, true, true}
+	titleField = fieldMask{Title: true}
+)
+
+// set is a set of document IDs.
+type set map[int]bool
+
+func (idx *Index) all() set {
+	s := make(set, idx.live)
+	for id, d := range idx.docs {
+		if d != nil {
+			s[id] = true
+		}
+	}
+
+	return s
+}
+
+// termFreqs returns the number of occurrences of the term in every field of every document containing it.
+func (idx *Index) termFreqs(t termNode, mask fieldMask) map[int][numFields]int {
+	freqs := make(map[int][numFields]int)
+
+	first := idx.postings[t.words[0]]
+	for id, p := range first {
+		var tf [numFields]int
+		found := false
+
+		for f := Field(0); f < numFields; f++ {
+			if !mask[f] {
+				continue
+			}
+
+			for _, pos := range p.positions[f] {
+				if idx.phraseAt(id, f, pos, t.words[1:]) {
+					tf[f]++
+					found = true
+				}
+			}
+		}
+
+		if found {
+			freqs[id] = tf
+		}
+	}
+
+	return freqs
+}
+
+// phraseAt reports whether the words follow the position pos in the field of the document.
+func (idx *Index) phraseAt(id int, f Field, pos int, words []string) bool {
+	for i, w := range words {
+		p := idx.postings[w][id]
+		if p == nil {
+			return false
+		}
+
+		positions := p.positions[f]
+		j := sort.SearchInts(positions, pos+i+1)
+		if j == len(positions) || positions[j] != pos+i+1 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// eval returns the documents matching the node.
+func (idx *Index) eval(n node, mask fieldMask, freqs map[string]map[int][numFields]int) set {
+	switch n := n.(type) {
+	case termNode:
+		tf := idx.cachedFreqs(n, mask, freqs)
+		s := make(set, len(tf))
+		for id := range tf {
+			s[id] = true
+		}
+		return s
+	case andNode:
+		if len(n.nodes) == 0 {
+			return idx.all()
+		}
+
+		s := idx.eval(n.nodes[0], mask, freqs)
+		for _, c := range n.nodes[1:] {
+			if len(s) == 0 {
+				break
+			}
+
+			other := idx.eval(c, mask, freqs)
+			for id := range s {
+				if !other[id] {
+					delete(s, id)
+				}
+			}
+		}
+		return s
+	case orNode:
+		s := make(set)
+		for _, c := range n.nodes {
+			for id := range idx.eval(c, mask, freqs) {
+				s[id] = true
+			}
+		}
+		return s
+	case notNode:
+		s := idx.all()
+		for id := range idx.eval(n.node, mask, freqs) {
+			delete(s, id)
+		}
+		return s
+	}
+
+	return idx.all()
+}
+
+func termKey(t termNode, mask fieldMask) string {
+	k := ""
+	if mask == titleField {
+		k = "title:"
+	}
+
+	for i, w := range t.words {
+		if i > 0 {
+			k += " "
+		}
+		k += w
+	}
+
+	return k
+}
+
+func (idx *Index) cachedFreqs(t termNode, mask fieldMask, freqs map[string]map[int][numFields]int) map[int][numFields]int {
+	k := termKey(t, mask)
+	if tf, ok := freqs[k]; ok {
+		return tf
+	}
+
+	tf := idx.termFreqs(t, mask)
+	freqs[k] = tf
+	return tf
+}
+
+// Search returns the articles matching opts ranked by their BM25 score. If neither Q nor QInTitle are set,
+// all articles matching the other options are returned with a score of 0, newest first. An error is only
+// returned if one of the queries can't be parsed.
+func (idx *Index) Search(opts Opts) (Results, error) {
+	q, err := ParseQuery(opts.Q)
+	if err != nil {
+		return Results{}, err
+	}
+
+	qInTitle, err := ParseQuery(opts.QInTitle)
+	if err != nil {
+		return Results{}, err
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	freqs := make(map[string]map[int][numFields]int)
+
+	var candidates set
+	switch {
+	case q.root != nil && qInTitle.root != nil:
+		candidates = idx.eval(q.root, allFields, freqs)
+		inTitle := idx.eval(qInTitle.root, titleField, freqs)
+		for id := range candidates {
+			if !inTitle[id] {
+				delete(candidates, id)
+			}
+		}
+	case q.root != nil:
+		candidates = idx.eval(q.root, allFields, freqs)
+	case qInTitle.root != nil:
+		candidates = idx.eval(qInTitle.root, titleField, freqs)
+	default:
+		candidates = idx.all()
+	}
+
+	type scoredTerm struct {
+		freqs map[int][numFields]int
+		idf   float64
+	}
+
+	var terms []scoredTerm
+	for _, t := range positiveTerms(q.root) {
+		tf := idx.cachedFreqs(t, allFields, freqs)
+		terms = append(terms, scoredTerm{tf, idx.idf(len(tf))})
+	}
+	for _, t := range positiveTerms(qInTitle.root) {
+		tf := idx.cachedFreqs(t, titleField, freqs)
+		terms = append(terms, scoredTerm{tf, idx.idf(len(tf))})
+	}
+
+	var hits []Hit
+	for id := range candidates {
+		a := idx.docs[id].article
+		if !matchesFilters(a, opts) {
+			continue
+		}
+
+		score := 0.0
+		for _, t := range terms {
+			if tf, ok := t.freqs[id]; ok {
+				score += t.idf * idx.saturate(id, tf)
+			}
+		}
+
+		hits = append(hits, Hit{Article: a, Score: score})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if !hits[i].Article.PublishedAt.Equal(hits[j].Article.PublishedAt) {
+			return hits[i].Article.PublishedAt.After(hits[j].Article.PublishedAt)
+		}
+		return hits[i].Article.URL < hits[j].Article.URL
+	})
+
+	res := Results{Total: len(hits)}
+
+	if opts.Offset > 0 {
+		if opts.Offset >= len(hits) {
+			return res, nil
+		}
+		hits = hits[opts.Offset:]
+	}
+	if opts.Limit > 0 && len(hits) > opts.Limit {
+		hits = hits[:opts.Limit]
+	}
+	res.Hits = hits
+
+	return res, nil
+}
+
+// idf returns the inverse document frequency of a term which occurs in df documents.
+func (idx *Index) idf(df int) float64 {
+	return math.Log(1 + (float64(idx.live)-float64(df)+0.5)/(float64(df)+0.5))
+}
+
+// saturate combines the term frequencies of the fields of the document like BM25F: every field is weighted
+// by its boost and normalized by its length before the sum saturates.
+func (idx *Index) saturate(id int, tf [numFields]int) float64 {
+	k1, b := idx.K1, idx.B
+	if k1 == 0 {
+		k1 = DefaultK1
+	}
+	if b == 0 {
+		b = DefaultB
+	}
+
+	boosts := idx.Boosts
+	if boosts == (Boosts{}) {
+		boosts = DefaultBoosts
+	}
+
+	d := idx.docs[id]
+	weighted := 0.0
+	for f := Field(0); f < numFields; f++ {
+		if tf[f] == 0 || idx.totalLen[f] == 0 {
+			continue
+		}
+
+		avg := float64(idx.totalLen[f]) / float64(idx.live)
+		weighted += boosts.of(f) * float64(tf[f]) / (1 - b + b*float64(d.lengths[f])/avg)
+	}
+
+	return weighted * (k1 + 1) / (weighted + k1)
+}
+
+func matchesFilters(a newsapi.Article, opts Opts) bool {
+	if !opts.From.IsZero() && a.PublishedAt.Before(opts.From) {
+		return false
+	}
+	if !opts.To.IsZero() && !a.PublishedAt.Before(opts.To) {
+		return false
+	}
+
+	if len(opts.Sources) == 0 {
+		return true
+	}
+
+	for _, src := range opts.Sources {
+		if src == a.Source.ID || src == a.Source.Name {
+			return true
+		}
+	}
+
+	return false
+}
+
+type savedIndex struct {
+	Version  int               `json:"version"`
+	Articles []newsapi.Article `json:"articles"`
+}
+
+// Save writes the articles of the index to w. The index itself is rebuilt by Load, so the saved index
+// doesn't depend on how the index is organized internally.
+func (idx *Index) Save(w io.Writer) error {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	s := savedIndex{Version: formatVersion, Articles: []newsapi.Article{}}
+	for _, d := range idx.docs {
+		if d != nil {
+			s.Articles = append(s.Articles, d.article)
+		}
+	}
+
+	return json.NewEncoder(w).Encode(s)
+}
+
+// SaveFile writes the index to the file at path. An existing file is replaced. The index is written to a
+// temporary file in the same directory first, so the file at path is never left half-written.
+func (idx *Index) SaveFile(path string) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := idx.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// Load reads an index which has been written by Save.
+func Load(r io.Reader) (*Index, error) {
+	var s savedIndex
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+
+	if s.Version != formatVersion {
+		return nil, errors.New("The index has been saved in an unsupported format")
+	}
+
+	idx := &Index{}
+	idx.Add(s.Articles...)
+
+	return idx, nil
+}
+
+// LoadFile reads an index from the file at path.
+func LoadFile(path string) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Load(f)
+}
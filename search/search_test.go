@@ -0,0 +1,185 @@
+package search
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+var day = time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)
+
+var articles = []newsapi.Article{
+	{
+		URL: "https://example.com/fed", Title: "Federal Reserve raises interest rates",
+		Description: "The central bank raised rates again.", Content: "Markets fell after the decision.",
+		Source: newsapi.ArticleSource{ID: "example", Name: "Example"}, PublishedAt: day.Add(1 * time.Hour),
+	},
+	{
+		URL: "https://example.com/bitcoin", Title: "Bitcoin rallies",
+		Description: "Crypto traders shrug off the reserve decision.", Content: "Ethereum and litecoin followed bitcoin higher.",
+		Source: newsapi.ArticleSource{ID: "example", Name: "Example"}, PublishedAt: day.Add(2 * time.Hour),
+	},
+	{
+		URL: "https://other.com/ethereum", Title: "Ethereum upgrade ships",
+		Description: "Developers finished the long awaited upgrade.", Content: "Crypto markets barely moved.",
+		Source: newsapi.ArticleSource{Name: "Other"}, PublishedAt: day.Add(26 * time.Hour),
+	},
+	{
+		URL: "https://other.com/rates", Title: "What higher rates mean for you",
+		Description: "Mortgage rates follow the federal reserve.", Content: "Rates rates rates.", Author: "Federal Reserve Watcher",
+		Source: newsapi.ArticleSource{Name: "Other"}, PublishedAt: day.Add(3 * time.Hour),
+	},
+}
+
+func urls(res Results) []string {
+	var us []string
+	for _, h := range res.Hits {
+		us = append(us, h.Article.URL)
+	}
+	return us
+}
+
+func TestSearch(t *testing.T) {
+	var idx Index
+	idx.Add(articles...)
+
+	cases := []struct {
+		opts     Opts
+		expected []string
+	}{
+		{Opts{Q: "bitcoin"}, []string{"https://example.com/bitcoin"}},
+		// the title match ranks higher than the matches in the other fields
+		{Opts{Q: `"federal reserve"`}, []string{"https://example.com/fed", "https://other.com/rates"}},
+		{Opts{Q: "crypto AND (ethereum OR litecoin) NOT bitcoin"}, []string{"https://other.com/ethereum"}},
+		{Opts{Q: "+crypto -upgrade"}, []string{"https://example.com/bitcoin"}},
+		{Opts{QInTitle: "rates"}, []string{"https://example.com/fed", "https://other.com/rates"}},
+		{Opts{Q: "crypto", Sources: []string{"Other"}}, []string{"https://other.com/ethereum"}},
+		{Opts{From: day.Add(24 * time.Hour)}, []string{"https://other.com/ethereum"}},
+		{Opts{To: day.Add(3 * time.Hour)}, []string{"https://example.com/bitcoin", "https://example.com/fed"}},
+		{Opts{Q: "dogecoin"}, nil},
+	}
+
+	for _, i := range cases {
+		res, err := idx.Search(i.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if us := urls(res); !reflect.DeepEqual(us, i.expected) {
+			t.Errorf("Expected %v but got %v when case=%+v", i.expected, us, i.opts)
+		}
+	}
+
+	res, err := idx.Search(Opts{Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"https://other.com/rates", "https://example.com/bitcoin"}; res.Total != 4 || !reflect.DeepEqual(urls(res), expected) {
+		t.Errorf("Expected %v of 4 hits but got %v of %d", expected, urls(res), res.Total)
+	}
+
+	if _, err := idx.Search(Opts{Q: "(bitcoin"}); err == nil {
+		t.Error("Expected an error for an invalid query")
+	}
+}
+
+func TestSearchBoosts(t *testing.T) {
+	idx := Index{Boosts: Boosts{Author: 10, Title: 0.1, Description: 0.1, Content: 0.1}}
+	idx.Add(articles...)
+
+	res, err := idx.Search(Opts{Q: `"federal reserve"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.Hits) == 0 || res.Hits[0].Article.URL != "https://other.com/rates" {
+		t.Errorf("Expected the author match to rank first but got %v", urls(res))
+	}
+}
+
+func TestIndexReplace(t *testing.T) {
+	var idx Index
+	idx.Add(articles...)
+
+	// the same article with tracking parameters replaces the old version
+	updated := articles[1]
+	updated.URL += "?utm_source=rss"
+	updated.Title = "Bitcoin slumps"
+	idx.Add(updated)
+
+	if idx.Len() != len(articles) {
+		t.Errorf("Expected %d articles but got %d", len(articles), idx.Len())
+	}
+
+	if res, _ := idx.Search(Opts{Q: "rallies"}); res.Total != 0 {
+		t.Errorf("Expected the old version to be gone but got %v", urls(res))
+	}
+	if res, _ := idx.Search(Opts{Q: "slumps"}); res.Total != 1 {
+		t.Errorf("Expected the new version to be found but got %v", urls(res))
+	}
+
+	idx.Remove("https://example.com/bitcoin")
+	if res, _ := idx.Search(Opts{Q: "bitcoin"}); res.Total != 0 || idx.Len() != len(articles)-1 {
+		t.Errorf("Expected the article to be removed but got %v", urls(res))
+	}
+
+	// replaced and removed articles don't leave empty slots behind
+	idx.Add(articles[1])
+	for n := 0; n < 10; n++ {
+		idx.Remove(articles[0].URL)
+		idx.Add(articles[0], updated)
+	}
+	if len(idx.docs) != len(articles) || len(idx.free) != 0 {
+		t.Errorf("Expected %d slots but got %d with %d free slots", len(articles), len(idx.docs), len(idx.free))
+	}
+	if res, _ := idx.Search(Opts{Q: "slumps OR federal"}); res.Total != 3 {
+		t.Errorf("Expected the articles in the reused slots to be found but got %v", urls(res))
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	var idx Index
+	idx.Add(articles...)
+	idx.Remove(articles[0].URL)
+
+	var buf bytes.Buffer
+	if err := idx.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, q := range []string{"crypto", `"federal reserve"`, "rates"} {
+		expected, _ := idx.Search(Opts{Q: q})
+		res, _ := loaded.Search(Opts{Q: q})
+
+		if !reflect.DeepEqual(res, expected) {
+			t.Errorf("Expected %+v but got %+v when case=%q", expected, res, q)
+		}
+	}
+
+	if _, err := Load(bytes.NewBufferString(`{"version":99}`)); err == nil {
+		t.Error("Expected an error for an unsupported version")
+	}
+
+	// the file is replaced without leaving the temporary file behind
+	path := filepath.Join(t.TempDir(), "index.json")
+	for n := 0; n < 2; n++ {
+		if err := idx.SaveFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if names, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*")); !reflect.DeepEqual(names, []string{path}) {
+		t.Errorf("Expected only the saved file but got %v", names)
+	}
+	if loaded, err := LoadFile(path); err != nil || loaded.Len() != idx.Len() {
+		t.Errorf("Expected the saved index to be loaded but got %v", err)
+	}
+}
@@ -0,0 +1,455 @@
+/*
+Package sentiment scores the tone of articles. It comes with a lexicon-based scorer in the spirit of VADER:
+every word of a text is looked up in a lexicon of words with a known valence, and the valences are adjusted
+for negations ("not good"), intensifiers ("very good"), contrasting conjunctions ("good but expensive"),
+capitalized words and exclamation marks.
+
+Lexicons for English, German, French and Spanish are built in. Lexicons for other languages can be loaded
+with LexiconScorer.Load and a completely different scorer can be used by implementing the Scorer interface:
+
+	var s sentiment.LexiconScorer
+
+	scores := sentiment.ScoreArticles(&s, r.Articles)
+	report := sentiment.Aggregate(r.Articles, scores, time.UTC)
+
+	for source, stats := range report.BySource {
+		fmt.Println(source, stats.Mean)
+	}
+*/
+package sentiment
+
+import (
+	"bufio"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+// NeutralThreshold is the compound score below which (in absolute terms) a text is considered neutral.
+const NeutralThreshold = 0.05
+
+// these constants have been taken over from VADER where they have been determined empirically
+const (
+	negationScalar    = -0.74
+	capsIncrement     = 0.733
+	exclamationBoost  = 0.292
+	maxExclamations   = 4
+	normalizationBase = 15
+	lookBehind        = 3
+)
+
+//go:embed lexicons/*.tsv
+var builtinLexicons embed.FS
+
+// Label is the tone of a text.
+type Label string
+
+const (
+	Positive Label = "positive"
+	Negative Label = "negative"
+	Neutral  Label = "neutral"
+)
+
+// Score represents the sentiment of a text.
+type Score struct {
+	// Positive, Negative and Neutral are the shares of the text which are positive, negative and neutral.
+	// They add up to 1. A text without any words and a text in a language without a lexicon are
+	// completely neutral.
+	Positive float64 `json:"positive"`
+	Negative float64 `json:"negative"`
+	Neutral  float64 `json:"neutral"`
+	// Compound is the normalized overall sentiment between -1 (most negative) and 1 (most positive).
+	Compound float64 `json:"compound"`
+}
+
+// Label returns the tone of the score based on its compound value and the NeutralThreshold.
+func (s Score) Label() Label {
+	switch {
+	case s.Compound >= NeutralThreshold:
+		return Positive
+	case s.Compound <= -NeutralThreshold:
+		return Negative
+	}
+
+	return Neutral
+}
+
+// Scorer is implemented by every sentiment scorer. lang is the language of the text as one of the
+// values of the Language option.
+type Scorer interface {
+	Score(text, lang string) Score
+}
+
+// Lexicon contains the words a LexiconScorer knows about.
+type Lexicon struct {
+	// Words maps words to their valence, usually between -4 and 4.
+	Words map[string]float64
+	// Boosters maps intensifiers like "very" or "slightly" to the amount they increase or decrease
+	// the valence of the following words.
+	Boosters map[string]float64
+	// Negations contains the words which flip the valence of the following words.
+	Negations map[string]bool
+}
+
+// ParseLexicon reads a lexicon in the tab-separated format of the built-in lexicons. Every line consists of
+// a kind ("word", "booster" or "negation"), a term and a value. Empty lines and lines starting with "#" are ignored.
+func ParseLexicon(r io.Reader) (*Lexicon, error) {
+	lex := &Lexicon{
+		Words:     make(map[string]float64),
+		Boosters:  make(map[string]float64),
+		Negations: make(map[string]bool),
+	}
+
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, "\t")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("Line %d of the lexicon doesn't have exactly three fields", line)
+		}
+
+		term := strings.ToLower(fields[1])
+		value, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("Line %d of the lexicon has an invalid value: %v", line, err)
+		}
+
+		switch fields[0] {
+		case "word":
+			lex.Words[term] = value
+		case "booster":
+			lex.Boosters[term] = value
+		case "negation":
+			lex.Negations[term] = true
+		default:
+			return nil, fmt.Errorf("Line %d of the lexicon has the unknown kind %q", line, fields[0])
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return lex, nil
+}
+
+// LexiconScorer is a Scorer which uses a lexicon per language. The zero value is ready to use and comes
+// with the built-in lexicons. A LexiconScorer is safe for concurrent use.
+type LexiconScorer struct {
+	// Fallback is the language whose lexicon is used for texts in languages without a lexicon.
+	// Texts in those languages are scored as neutral if it's empty.
+	Fallback string
+
+	mu       sync.RWMutex
+	lexicons map[string]*Lexicon
+}
+
+func (s *LexiconScorer) init() {
+	if s.lexicons != nil {
+		return
+	}
+
+	s.lexicons = make(map[string]*Lexicon)
+	for _, lang := range []string{"de", "en", "es", "fr"} {
+		f, err := builtinLexicons.Open("lexicons/" + lang + ".tsv")
+		if err != nil {
+			panic(err)
+		}
+
+		lex, err := ParseLexicon(f)
+		f.Close()
+		if err != nil {
+			// the built-in lexicons are checked by the tests
+			panic(err)
+		}
+		s.lexicons[lang] = lex
+	}
+}
+
+// Load reads a lexicon in the format described at ParseLexicon and uses it for texts in the language lang.
+// A built-in lexicon of the same language is replaced.
+func (s *LexiconScorer) Load(lang string, r io.Reader) error {
+	if lang == "" {
+		return errors.New("The language of a lexicon cannot be empty")
+	}
+
+	lex, err := ParseLexicon(r)
+	if err != nil {
+		return err
+	}
+
+	s.SetLexicon(lang, lex)
+	return nil
+}
+
+// SetLexicon uses lex for texts in the language lang.
+func (s *LexiconScorer) SetLexicon(lang string, lex *Lexicon) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.init()
+	s.lexicons[lang] = lex
+}
+
+func (s *LexiconScorer) lexicon(lang string) *Lexicon {
+	s.mu.RLock()
+	if s.lexicons != nil {
+		defer s.mu.RUnlock()
+		return s.lookup(lang)
+	}
+	s.mu.RUnlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.init()
+	return s.lookup(lang)
+}
+
+func (s *LexiconScorer) lookup(lang string) *Lexicon {
+	if lex, ok := s.lexicons[lang]; ok {
+		return lex
+	}
+
+	return s.lexicons[s.Fallback]
+}
+
+// Score scores the text with the lexicon of the language. If lang is empty, the language is detected.
+func (s *LexiconScorer) Score(text, lang string) Score {
+	if lang == "" {
+		lang, _ = newsapi.DetectLanguage(text)
+	}
+
+	lex := s.lexicon(lang)
+	if lex == nil {
+		return Score{Neutral: 1}
+	}
+
+	return lex.score(text)
+}
+
+type token struct {
+	word string
+	caps bool
+}
+
+func tokenize(text string) []token {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\'' && r != '’'
+	})
+
+	tokens := make([]token, 0, len(fields))
+	for _, f := range fields {
+		f = strings.Trim(strings.ReplaceAll(f, "’", "'"), "'")
+		if f == "" {
+			continue
+		}
+
+		tokens = append(tokens, token{
+			word: strings.ToLower(f),
+			caps: len([]rune(f)) > 1 && strings.ToUpper(f) == f && strings.ToLower(f) != f,
+		})
+	}
+
+	return tokens
+}
+
+func (lex *Lexicon) score(text string) Score {
+	tokens := tokenize(text)
+	if len(tokens) == 0 {
+		return Score{Neutral: 1}
+	}
+
+	// capitalized words only count as emphasis if not the whole text is capitalized
+	var capsWords int
+	for _, t := range tokens {
+		if t.caps {
+			capsWords++
+		}
+	}
+	emphasis := capsWords > 0 && capsWords < len(tokens)
+
+	valences := make([]float64, len(tokens))
+	butIndex := -1
+
+	for i, t := range tokens {
+		if t.word == "but" || t.word == "aber" || t.word == "mais" || t.word == "pero" {
+			butIndex = i
+		}
+
+		v, ok := lex.Words[t.word]
+		if !ok || v == 0 {
+			continue
+		}
+
+		if emphasis && t.caps {
+			v += math.Copysign(capsIncrement, v)
+		}
+
+		for j := 1; j <= lookBehind && i-j >= 0; j++ {
+			prev := tokens[i-j]
+
+			// boosters further away from the word have less of an effect
+			if b, ok := lex.Boosters[prev.word]; ok {
+				scalar := b * (1 - 0.05*float64(j-1))
+				if emphasis && prev.caps {
+					scalar += math.Copysign(capsIncrement, b)
+				}
+				if v < 0 {
+					scalar = -scalar
+				}
+				v += scalar
+			}
+
+			if lex.Negations[prev.word] || strings.HasSuffix(prev.word, "n't") {
+				v *= negationScalar
+			}
+		}
+
+		valences[i] = v
+	}
+
+	// the part after a contrasting conjunction weighs more than the part before it
+	if butIndex >= 0 {
+		for i := range valences {
+			if i < butIndex {
+				valences[i] *= 0.5
+			} else if i > butIndex {
+				valences[i] *= 1.5
+			}
+		}
+	}
+
+	var sum, pos, neg float64
+	var neutral int
+	for _, v := range valences {
+		sum += v
+		switch {
+		case v > 0:
+			pos += v + 1
+		case v < 0:
+			neg += v - 1
+		default:
+			neutral++
+		}
+	}
+
+	if sum != 0 {
+		excl := math.Min(float64(strings.Count(text, "!")), maxExclamations)
+		sum += math.Copysign(excl*exclamationBoost, sum)
+	}
+
+	total := pos - neg + float64(neutral)
+
+	return Score{
+		Positive: pos / total,
+		Negative: -neg / total,
+		Neutral:  float64(neutral) / total,
+		Compound: sum / math.Sqrt(sum*sum+normalizationBase),
+	}
+}
+
+// ArticleText returns the text of the article which is being scored: its title, description and content
+// without the truncation marker.
+func ArticleText(a newsapi.Article) string {
+	return a.Title + ". " + a.Description + " " + a.ContentText()
+}
+
+// ScoreArticles scores every article with the scorer. The language of every article is detected.
+func ScoreArticles(s Scorer, articles []newsapi.Article) []Score {
+	scores := make([]Score, len(articles))
+
+	for i, a := range articles {
+		text := ArticleText(a)
+
+		lang, _ := newsapi.DetectLanguage(text)
+
+		scores[i] = s.Score(text, lang)
+	}
+
+	return scores
+}
+
+// Stats summarizes the scores of several articles.
+type Stats struct {
+	Count int `json:"count"`
+	// Mean is the mean compound score.
+	Mean float64 `json:"mean"`
+	// Positive, Negative and Neutral are the number of articles with the respective label.
+	Positive int `json:"positive"`
+	Negative int `json:"negative"`
+	Neutral  int `json:"neutral"`
+}
+
+func (s *Stats) add(score Score) {
+	s.Mean = (s.Mean*float64(s.Count) + score.Compound) / float64(s.Count+1)
+	s.Count++
+
+	switch score.Label() {
+	case Positive:
+		s.Positive++
+	case Negative:
+		s.Negative++
+	default:
+		s.Neutral++
+	}
+}
+
+// Report contains the aggregated scores of a set of articles.
+type Report struct {
+	Overall Stats `json:"overall"`
+	// BySource maps the source names of the articles to their stats.
+	BySource map[string]Stats `json:"bySource"`
+	// ByDay maps the days the articles have been published on, formatted as "2006-01-02", to their stats.
+	// Articles without a publishing date aren't counted.
+	ByDay map[string]Stats `json:"byDay"`
+}
+
+// Aggregate aggregates the scores per source and per day. scores[i] has to be the score of articles[i],
+// like the scores returned by ScoreArticles. The days are determined in the location loc, UTC is used if it's nil.
+func Aggregate(articles []newsapi.Article, scores []Score, loc *time.Location) Report {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	r := Report{
+		BySource: make(map[string]Stats),
+		ByDay:    make(map[string]Stats),
+	}
+
+	for i, a := range articles {
+		if i >= len(scores) {
+			break
+		}
+
+		r.Overall.add(scores[i])
+
+		source := r.BySource[a.Source.Name]
+		source.add(scores[i])
+		r.BySource[a.Source.Name] = source
+
+		if a.PublishedAt.IsZero() {
+			continue
+		}
+
+		key := a.PublishedAt.In(loc).Format("2006-01-02")
+		day := r.ByDay[key]
+		day.add(scores[i])
+		r.ByDay[key] = day
+	}
+
+	return r
+}
@@ -0,0 +1,177 @@
+package sentiment
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+func TestBuiltinLexicons(t *testing.T) {
+	for _, lang := range []string{"de", "en", "es", "fr"} {
+		b, err := builtinLexicons.ReadFile("lexicons/" + lang + ".tsv")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		lex, err := ParseLexicon(strings.NewReader(string(b)))
+		if err != nil {
+			t.Errorf("Expected the %s lexicon to be valid but got %v", lang, err)
+			continue
+		}
+
+		if len(lex.Words) == 0 || len(lex.Boosters) == 0 || len(lex.Negations) == 0 {
+			t.Errorf("Expected the %s lexicon to contain words, boosters and negations", lang)
+		}
+
+		for w := range lex.Boosters {
+			if _, ok := lex.Words[w]; ok {
+				t.Errorf("Expected %q not to be both a word and a booster in the %s lexicon", w, lang)
+			}
+		}
+	}
+}
+
+func TestParseLexicon(t *testing.T) {
+	cases := []struct {
+		lexicon string
+		valid   bool
+	}{
+		{"# kind\tterm\tvalue\nword\tGood\t1.9\n\nbooster\tvery\t0.293\nnegation\tnot\t0\n", true},
+		{"word\tgood\n", false},
+		{"word\tgood\tvery\n", false},
+		{"adjective\tgood\t1.9\n", false},
+	}
+
+	for _, i := range cases {
+		lex, err := ParseLexicon(strings.NewReader(i.lexicon))
+		if (err == nil) != i.valid {
+			t.Errorf("Expected %v but got %v when case=%q", i.valid, err == nil, i.lexicon)
+		}
+
+		if i.valid && (lex.Words["good"] != 1.9 || lex.Boosters["very"] != 0.293 || !lex.Negations["not"]) {
+			t.Errorf("Unexpected lexicon %v when case=%q", lex, i.lexicon)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	var s LexiconScorer
+
+	cases := []struct {
+		text  string
+		lang  string
+		label Label
+	}{
+		{"The results were good.", "en", Positive},
+		{"The results were bad.", "en", Negative},
+		{"The meeting took place on Tuesday.", "en", Neutral},
+		{"The results were not good.", "en", Negative},
+		{"The results weren't bad.", "en", Positive},
+		{"The food was good but the service was terrible.", "en", Negative},
+		{"Die Ergebnisse waren gut.", "de", Positive},
+		{"Die Ergebnisse waren nicht gut.", "de", Negative},
+		{"Les résultats étaient mauvais.", "fr", Negative},
+		{"Los resultados fueron buenos y el equipo está muy feliz.", "es", Positive},
+		{"", "en", Neutral},
+	}
+
+	for _, i := range cases {
+		if label := s.Score(i.text, i.lang).Label(); label != i.label {
+			t.Errorf("Expected %v but got %v when case=%q", i.label, label, i.text)
+		}
+	}
+
+	// an empty text and a text without a lexicon get the same neutral score
+	for _, i := range []struct{ text, lang string }{{"", "en"}, {"!!!", "en"}, {"Президент встретился с министрами.", "ru"}} {
+		if score := s.Score(i.text, i.lang); score != (Score{Neutral: 1}) {
+			t.Errorf("Expected a neutral score but got %+v when case=%q", score, i.text)
+		}
+	}
+}
+
+func TestScoreModifiers(t *testing.T) {
+	var s LexiconScorer
+
+	cases := []struct {
+		weaker   string
+		stronger string
+	}{
+		{"The results were good.", "The results were very good."},
+		{"The results were good.", "The results were GOOD."},
+		{"The results were good.", "The results were good!!"},
+		{"The results were slightly good.", "The results were good."},
+		{"The results were bad.", "The results were extremely bad."},
+	}
+
+	for _, i := range cases {
+		weaker, stronger := s.Score(i.weaker, "en"), s.Score(i.stronger, "en")
+		if math.Abs(weaker.Compound) >= math.Abs(stronger.Compound) {
+			t.Errorf("Expected %q to be stronger than %q but got %f and %f", i.stronger, i.weaker, stronger.Compound, weaker.Compound)
+		}
+	}
+
+	score := s.Score("The results were good but the outlook is bad.", "en")
+	if sum := score.Positive + score.Negative + score.Neutral; math.Abs(sum-1) > 1e-9 {
+		t.Errorf("Expected the proportions to add up to 1 but got %f", sum)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	var s LexiconScorer
+
+	if score := s.Score("Hyvä uutinen", "fi"); score.Compound != 0 {
+		t.Errorf("Expected a neutral score without a lexicon but got %f", score.Compound)
+	}
+
+	if err := s.Load("fi", strings.NewReader("word\thyvä\t2\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if label := s.Score("Hyvä uutinen", "fi").Label(); label != Positive {
+		t.Errorf("Expected %v but got %v", Positive, label)
+	}
+
+	if err := s.Load("", strings.NewReader("")); err == nil {
+		t.Error("Expected an error for an empty language")
+	}
+
+	fallback := LexiconScorer{Fallback: "en"}
+	if label := fallback.Score("good", "xx").Label(); label != Positive {
+		t.Errorf("Expected the fallback lexicon to be used but got %v", label)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	day := time.Date(2020, 5, 1, 23, 30, 0, 0, time.UTC)
+
+	articles := []newsapi.Article{
+		{Source: newsapi.ArticleSource{Name: "BBC News"}, Title: "Great win for the team", PublishedAt: day},
+		{Source: newsapi.ArticleSource{Name: "BBC News"}, Title: "Several people killed in crash", PublishedAt: day},
+		{Source: newsapi.ArticleSource{Name: "Reuters"}, Title: "Happy fans celebrate excellent season [+1200 chars]", PublishedAt: day.Add(time.Hour)},
+		{Source: newsapi.ArticleSource{Name: "Reuters"}, Title: "Meeting on Tuesday"},
+	}
+
+	var s LexiconScorer
+	scores := ScoreArticles(&s, articles)
+	r := Aggregate(articles, scores, nil)
+
+	if r.Overall.Count != 4 || r.Overall.Positive != 2 || r.Overall.Negative != 1 || r.Overall.Neutral != 1 {
+		t.Errorf("Unexpected overall stats %+v", r.Overall)
+	}
+
+	if bbc := r.BySource["BBC News"]; bbc.Count != 2 || math.Abs(bbc.Mean-(scores[0].Compound+scores[1].Compound)/2) > 1e-9 {
+		t.Errorf("Unexpected stats for BBC News %+v", bbc)
+	}
+
+	if len(r.ByDay) != 2 || r.ByDay["2020-05-01"].Count != 2 || r.ByDay["2020-05-02"].Count != 1 {
+		t.Errorf("Unexpected stats per day %+v", r.ByDay)
+	}
+
+	berlin := time.FixedZone("CEST", 2*60*60)
+	if local := Aggregate(articles, scores, berlin); len(local.ByDay) != 1 || local.ByDay["2020-05-02"].Count != 3 {
+		t.Errorf("Expected the days to be determined in the given location but got %+v", local.ByDay)
+	}
+}
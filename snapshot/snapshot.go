@@ -0,0 +1,524 @@
+/*
+Package snapshot archives the raw responses of the API together with the options which produced them, e.g.
+to be able to show what the API returned on a given day. An Archiver is set as the Recorder of a client and
+writes one compressed tar archive per day:
+
+	a := &snapshot.Archiver{Dir: "/var/lib/newsapi", Key: key}
+	c := newsapi.Client{APIKey: "your-api-key", Recorder: a}
+
+	// every response is staged in the directory of its day
+	r, err := c.TopHeadlines(ctx, newsapi.TopHeadlinesOpts{Country: "us"})
+
+	// the days before today are sealed into newsapi-2006-01-02.tar.gz archives
+	paths, err := a.SealBefore(time.Now())
+
+Every archive starts with a manifest containing the SHA-256 digests of the responses, the requests with the
+API key redacted, the options and the timestamps of the responses. If the Archiver has a key, the manifest is
+signed with HMAC-SHA256, otherwise the digest of the archive in the .sha256 file next to it should be kept
+somewhere else to detect a manifest which has been rewritten. Verify and VerifyFile check an archive against
+its manifest. The newsapi-snapshot command in cmd/newsapi-snapshot does the same from the command line.
+*/
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+// The names of the manifest and its signature in an archive.
+const (
+	ManifestName  = "manifest.json"
+	SignatureName = "manifest.sig"
+)
+
+const (
+	manifestVersion = 1
+	dayLayout       = "2006-01-02"
+	stagingSuffix   = ".staging"
+)
+
+// Request is the request which produced a response. Credentials are redacted.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Route  string      `json:"route"`
+	Header http.Header `json:"header,omitempty"`
+	// OptsType is the type of the options, e.g. "newsapi.TopHeadlinesOpts".
+	OptsType string          `json:"optsType,omitempty"`
+	Opts     json.RawMessage `json:"opts,omitempty"`
+}
+
+// Entry describes a response in an archive.
+type Entry struct {
+	// Name is the name of the file containing the body of the response.
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+
+	Request     Request     `json:"request"`
+	StatusCode  int         `json:"statusCode"`
+	Header      http.Header `json:"header,omitempty"`
+	RequestedAt time.Time   `json:"requestedAt"`
+	RespondedAt time.Time   `json:"respondedAt"`
+}
+
+// Manifest lists the responses of an archive.
+type Manifest struct {
+	Version   int       `json:"version"`
+	Day       string    `json:"day"`
+	CreatedAt time.Time `json:"createdAt"`
+	Entries   []Entry   `json:"entries"`
+}
+
+// ArchiveName returns the file name of the archive of a day in the format "2006-01-02".
+func ArchiveName(day string) string {
+	return "newsapi-" + day + ".tar.gz"
+}
+
+// Archiver stages the responses it records in a directory per day and seals the days into archives. It
+// implements the newsapi.Recorder interface and is safe for concurrent use. Staged responses survive
+// restarts, so a day can be sealed by another process.
+type Archiver struct {
+	// Dir is the directory the archives are written to.
+	Dir string
+	// Location is the time zone of the days. UTC is used if it's nil.
+	Location *time.Location
+	// Key signs the manifests with HMAC-SHA256 if it's not empty.
+	Key []byte
+	// AutoSeal seals the staged days before the day of a response when it's recorded. A day which can't be
+	// sealed doesn't fail the recording, the error is logged and sealing the day is tried again with the
+	// next response.
+	AutoSeal bool
+	// ErrorLog logs the errors of AutoSeal. The standard logger of the log package is used if it's nil.
+	ErrorLog *log.Logger
+
+	mu  sync.Mutex
+	seq map[string]int
+	now func() time.Time
+}
+
+func (a *Archiver) day(t time.Time) string {
+	loc := a.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	return t.In(loc).Format(dayLayout)
+}
+
+func (a *Archiver) staging(day string) string {
+	return filepath.Join(a.Dir, day+stagingSuffix)
+}
+
+var unsafeChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// Record stages the response in the directory of the day it has been received.
+func (a *Archiver) Record(ctx context.Context, r newsapi.RawResponse) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if r.RespondedAt.IsZero() {
+		r.RespondedAt = a.clock()
+	}
+
+	day := a.day(r.RespondedAt)
+	if _, err := os.Stat(filepath.Join(a.Dir, ArchiveName(day))); err == nil {
+		return fmt.Errorf("The archive of %v has already been sealed", day)
+	}
+
+	dir := a.staging(day)
+	if err := os.MkdirAll(filepath.Join(dir, "meta"), 0755); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "responses"), 0755); err != nil {
+		return err
+	}
+
+	n, err := a.next(day)
+	if err != nil {
+		return err
+	}
+
+	route := unsafeChars.ReplaceAllString(strings.ToLower(path.Base(r.Route)), "-")
+	if route == "" || route == "-" || route == "." {
+		route = "response"
+	}
+	name := fmt.Sprintf("%06d-%s", n, route)
+
+	e, err := entry(r)
+	if err != nil {
+		return err
+	}
+	e.Name = "responses/" + name + ".json"
+
+	if err := writeFile(filepath.Join(dir, "responses", name+".json"), r.Body); err != nil {
+		return err
+	}
+
+	meta, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	// the metadata is written last, so responses without it are left out when the day is sealed
+	if err := writeFile(filepath.Join(dir, "meta", name+".json"), meta); err != nil {
+		return err
+	}
+
+	if a.AutoSeal {
+		// the response has been staged, so a day which can't be sealed doesn't make it fail
+		if _, err := a.sealBefore(day); err != nil {
+			a.logf("snapshot: sealing the days before %v failed: %v", day, err)
+		}
+	}
+
+	return nil
+}
+
+func (a *Archiver) logf(format string, args ...interface{}) {
+	if a.ErrorLog != nil {
+		a.ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
+
+func (a *Archiver) clock() time.Time {
+	if a.now != nil {
+		return a.now()
+	}
+
+	return time.Now()
+}
+
+// next returns the next number of a response of the day. The staged responses are counted when the first
+// response of a day is recorded, so the numbers continue after a restart.
+func (a *Archiver) next(day string) (int, error) {
+	if a.seq == nil {
+		a.seq = make(map[string]int)
+	}
+
+	if _, ok := a.seq[day]; !ok {
+		files, err := os.ReadDir(filepath.Join(a.staging(day), "responses"))
+		if err != nil {
+			return 0, err
+		}
+		for _, f := range files {
+			var n int
+			if _, err := fmt.Sscanf(f.Name(), "%06d-", &n); err == nil && n > a.seq[day] {
+				a.seq[day] = n
+			}
+		}
+	}
+
+	a.seq[day]++
+	return a.seq[day], nil
+}
+
+func entry(r newsapi.RawResponse) (Entry, error) {
+	sum := sha256.Sum256(r.Body)
+
+	e := Entry{
+		Size:   int64(len(r.Body)),
+		SHA256: hex.EncodeToString(sum[:]),
+		Request: Request{
+			Method: r.Method,
+			URL:    redactURL(r.URL),
+			Route:  r.Route,
+			Header: newsapi.RedactHeader(r.RequestHeader),
+		},
+		StatusCode:  r.StatusCode,
+		Header:      r.Header,
+		RequestedAt: r.RequestedAt,
+		RespondedAt: r.RespondedAt,
+	}
+
+	if r.Opts != nil {
+		opts, err := json.Marshal(r.Opts)
+		if err != nil {
+			return Entry{}, err
+		}
+		e.Request.OptsType = fmt.Sprintf("%T", r.Opts)
+		e.Request.Opts = opts
+	}
+
+	return e, nil
+}
+
+// redactURL replaces the API key if it has been passed as a query parameter.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+
+	q := u.Query()
+	redacted := false
+	for name := range q {
+		if strings.EqualFold(name, "apiKey") {
+			q[name] = []string{newsapi.Redacted}
+			redacted = true
+		}
+	}
+	if !redacted {
+		return raw
+	}
+
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func writeFile(name string, data []byte) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// replaceFile writes the data to a temporary file which is renamed to name afterwards, so name is either
+// missing or complete.
+func replaceFile(name string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), name)
+}
+
+// Staged returns the days with staged responses in ascending order.
+func (a *Archiver) Staged() ([]string, error) {
+	files, err := os.ReadDir(a.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var days []string
+	for _, f := range files {
+		day := strings.TrimSuffix(f.Name(), stagingSuffix)
+		if !f.IsDir() || day == f.Name() {
+			continue
+		}
+		if _, err := time.Parse(dayLayout, day); err == nil {
+			days = append(days, day)
+		}
+	}
+	sort.Strings(days)
+
+	return days, nil
+}
+
+// SealBefore seals every staged day before the day of t and returns the paths of the archives. Days which
+// have already been sealed are skipped.
+func (a *Archiver) SealBefore(t time.Time) ([]string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.sealBefore(a.day(t))
+}
+
+func (a *Archiver) sealBefore(day string) ([]string, error) {
+	days, err := a.Staged()
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, d := range days {
+		if d >= day {
+			break
+		}
+
+		// the staged responses of a sealed day are left behind if the process stopped before removing them
+		if _, err := os.Stat(filepath.Join(a.Dir, ArchiveName(d))); err == nil {
+			continue
+		}
+
+		p, err := a.seal(d)
+		if err != nil {
+			return paths, err
+		}
+		paths = append(paths, p)
+	}
+
+	return paths, nil
+}
+
+// Seal writes the staged responses of the day in the format "2006-01-02" into an archive and removes them.
+// It returns the path of the archive. An existing archive is never replaced, so responses of the day can't
+// be recorded anymore afterwards.
+func (a *Archiver) Seal(day string) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.seal(day)
+}
+
+func (a *Archiver) seal(day string) (string, error) {
+	dir := a.staging(day)
+	final := filepath.Join(a.Dir, ArchiveName(day))
+
+	if _, err := os.Stat(final); err == nil {
+		return "", fmt.Errorf("The archive %v already exists", final)
+	}
+
+	metas, err := filepath.Glob(filepath.Join(dir, "meta", "*.json"))
+	if err != nil {
+		return "", err
+	}
+	if len(metas) == 0 {
+		return "", fmt.Errorf("There are no staged responses for %v", day)
+	}
+	sort.Strings(metas)
+
+	m := Manifest{Version: manifestVersion, Day: day, CreatedAt: a.clock().UTC()}
+	for _, name := range metas {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			return "", err
+		}
+
+		var e Entry
+		if err := json.Unmarshal(b, &e); err != nil {
+			return "", fmt.Errorf("The metadata %v is invalid: %v", name, err)
+		}
+
+		body, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(e.Name)))
+		if err != nil {
+			return "", err
+		}
+		if sum := sha256.Sum256(body); hex.EncodeToString(sum[:]) != e.SHA256 || int64(len(body)) != e.Size {
+			return "", fmt.Errorf("The staged response %v has been modified", e.Name)
+		}
+
+		m.Entries = append(m.Entries, e)
+	}
+
+	tmp, err := os.CreateTemp(a.Dir, "."+ArchiveName(day)+".*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	if err := a.writeArchive(io.MultiWriter(tmp, h), dir, m); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+
+	// the digest is written first so there's never an archive without it
+	sum := fmt.Sprintf("%x  %s\n", h.Sum(nil), ArchiveName(day))
+	if err := replaceFile(final+".sha256", []byte(sum)); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), final); err != nil {
+		os.Remove(final + ".sha256")
+		return "", err
+	}
+
+	delete(a.seq, day)
+	return final, os.RemoveAll(dir)
+}
+
+func (a *Archiver) writeArchive(w io.Writer, dir string, m Manifest) error {
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(w)
+	tw := tar.NewWriter(zw)
+
+	add := func(name string, data []byte, mod time.Time) error {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: mod, Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	if err := add(ManifestName, manifest, m.CreatedAt); err != nil {
+		return err
+	}
+	if len(a.Key) > 0 {
+		if err := add(SignatureName, []byte(sign(a.Key, manifest)+"\n"), m.CreatedAt); err != nil {
+			return err
+		}
+	}
+
+	for _, e := range m.Entries {
+		body, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(e.Name)))
+		if err != nil {
+			return err
+		}
+		if err := add(e.Name, body, e.RespondedAt); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func sign(key, manifest []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(manifest)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,235 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+var day1 = time.Date(2020, 5, 1, 8, 30, 0, 0, time.UTC)
+
+func response(at time.Time, country string) newsapi.RawResponse {
+	return newsapi.RawResponse{
+		Route:         "/v2/top-headlines",
+		Method:        "GET",
+		URL:           "https://newsapi.org/v2/top-headlines?country=" + country,
+		RequestHeader: http.Header{"X-Api-Key": {"secret"}},
+		Opts:          newsapi.TopHeadlinesOpts{Country: country},
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          []byte(`{"status":"ok","articles":[]}`),
+		RequestedAt:   at.Add(-time.Second),
+		RespondedAt:   at,
+	}
+}
+
+// readArchive returns the names of the files of an archive in their order and their contents.
+func readArchive(t *testing.T, name string) ([]string, map[string][]byte) {
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	files := make(map[string][]byte)
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+		files[hdr.Name] = b
+	}
+
+	return names, files
+}
+
+func TestArchiver(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	a := &Archiver{Dir: dir, Key: []byte("key"), now: func() time.Time { return day1.Add(24 * time.Hour) }}
+	for _, r := range []newsapi.RawResponse{response(day1, "us"), response(day1.Add(time.Hour), "gb")} {
+		if err := a.Record(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the numbers continue after a restart
+	a = &Archiver{Dir: dir, Key: []byte("key"), AutoSeal: true, now: a.now}
+	if err := a.Record(ctx, response(day1.Add(2*time.Hour), "de")); err != nil {
+		t.Fatal(err)
+	}
+
+	if days, err := a.Staged(); err != nil || !reflect.DeepEqual(days, []string{"2020-05-01"}) {
+		t.Fatalf("Expected a staged day but got %v: %v", days, err)
+	}
+
+	// a response of the next day seals the first one
+	if err := a.Record(ctx, response(day1.Add(24*time.Hour), "fr")); err != nil {
+		t.Fatal(err)
+	}
+
+	if days, _ := a.Staged(); !reflect.DeepEqual(days, []string{"2020-05-02"}) {
+		t.Errorf("Expected the first day to be sealed but got %v", days)
+	}
+
+	archive := filepath.Join(dir, "newsapi-2020-05-01.tar.gz")
+	names, files := readArchive(t, archive)
+
+	expected := []string{
+		ManifestName,
+		SignatureName,
+		"responses/000001-top-headlines.json",
+		"responses/000002-top-headlines.json",
+		"responses/000003-top-headlines.json",
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("Expected %v but got %v", expected, names)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(files[ManifestName], &m); err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Version != 1 || m.Day != "2020-05-01" || len(m.Entries) != 3 {
+		t.Fatalf("Expected a manifest with 3 entries but got %+v", m)
+	}
+
+	e := m.Entries[2]
+	if e.Request.Header.Get("X-Api-Key") != newsapi.Redacted {
+		t.Errorf("Expected the API key to be redacted but got %v", e.Request.Header)
+	}
+	var opts newsapi.TopHeadlinesOpts
+	if err := json.Unmarshal(e.Request.Opts, &opts); err != nil || e.Request.OptsType != "newsapi.TopHeadlinesOpts" || opts.Country != "de" {
+		t.Errorf("Expected the options but got %v %s", e.Request.OptsType, e.Request.Opts)
+	}
+	if !e.RespondedAt.Equal(day1.Add(2*time.Hour)) || !e.RequestedAt.Equal(day1.Add(2*time.Hour-time.Second)) || e.StatusCode != 200 {
+		t.Errorf("Expected the timestamps and the status but got %v %v %v", e.RequestedAt, e.RespondedAt, e.StatusCode)
+	}
+	if sum := sha256.Sum256(files[e.Name]); e.SHA256 != hex.EncodeToString(sum[:]) || e.Size != int64(len(files[e.Name])) {
+		t.Errorf("Expected the digest and the size of the response but got %v and %v", e.SHA256, e.Size)
+	}
+
+	if _, err := os.Stat(archive + ".sha256"); err != nil {
+		t.Errorf("Expected a .sha256 file next to the archive: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2020-05-01"+stagingSuffix)); !os.IsNotExist(err) {
+		t.Errorf("Expected the staged responses to be removed but got %v", err)
+	}
+
+	// an existing archive is never replaced
+	if err := a.Record(ctx, response(day1, "it")); err == nil {
+		t.Error("Expected an error when recording a response of a sealed day")
+	}
+	if _, err := a.Seal("2020-05-03"); err == nil {
+		t.Error("Expected an error when sealing a day without responses")
+	}
+}
+
+func TestAutoSealErrors(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	var logged bytes.Buffer
+	a := &Archiver{Dir: dir, AutoSeal: true, ErrorLog: log.New(&logged, "", 0), now: func() time.Time { return day1 }}
+	if err := a.Record(ctx, response(day1, "us")); err != nil {
+		t.Fatal(err)
+	}
+
+	meta := filepath.Join(dir, "2020-05-01"+stagingSuffix, "meta", "000001-top-headlines.json")
+	valid, err := os.ReadFile(meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(meta, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the response is recorded although the previous day can't be sealed
+	day2 := day1.Add(24 * time.Hour)
+	if err := a.Record(ctx, response(day2, "us")); err != nil {
+		t.Fatalf("Expected the response to be recorded but got %v", err)
+	}
+	if !strings.Contains(logged.String(), "is invalid") {
+		t.Errorf("Expected the seal error to be logged but got %q", logged.String())
+	}
+	if days, _ := a.Staged(); !reflect.DeepEqual(days, []string{"2020-05-01", "2020-05-02"}) {
+		t.Errorf("Expected both days to be staged but got %v", days)
+	}
+
+	// the next response seals the day again
+	if err := os.WriteFile(meta, valid, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Record(ctx, response(day2.Add(time.Hour), "us")); err != nil {
+		t.Fatal(err)
+	}
+	if days, _ := a.Staged(); !reflect.DeepEqual(days, []string{"2020-05-02"}) {
+		t.Errorf("Expected the first day to be sealed but got %v", days)
+	}
+
+	// staged responses left behind next to an archive are skipped
+	if err := os.MkdirAll(filepath.Dir(meta), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(meta, valid, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	logged.Reset()
+	if err := a.Record(ctx, response(day2.Add(2*time.Hour), "us")); err != nil {
+		t.Fatal(err)
+	}
+	if logged.Len() != 0 {
+		t.Errorf("Expected the sealed day to be skipped but got %q", logged.String())
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	cases := []struct {
+		url      string
+		expected string
+	}{
+		{"https://newsapi.org/v2/everything?q=bitcoin", "https://newsapi.org/v2/everything?q=bitcoin"},
+		{"https://newsapi.org/v2/everything?q=bitcoin&apiKey=secret", "https://newsapi.org/v2/everything?apiKey=REDACTED&q=bitcoin"},
+		{"https://newsapi.org/v2/sources?apikey=secret", "https://newsapi.org/v2/sources?apikey=REDACTED"},
+	}
+
+	for _, i := range cases {
+		if got := redactURL(i.url); got != i.expected {
+			t.Errorf("Expected %v but got %v when case=%v", i.expected, got, i.url)
+		}
+	}
+}
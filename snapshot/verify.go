@@ -0,0 +1,210 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Problem is a discrepancy between an archive and its manifest.
+type Problem struct {
+	// Name is the name of the file in the archive or the name of the archive if the problem concerns the
+	// whole archive.
+	Name   string
+	Reason string
+}
+
+func (p Problem) String() string {
+	return p.Name + ": " + p.Reason
+}
+
+// Report is the result of the verification of an archive.
+type Report struct {
+	Manifest Manifest
+	// Signed reports whether the archive contains a signature of the manifest.
+	Signed   bool
+	Problems []Problem
+}
+
+// OK reports whether the archive matches its manifest.
+func (r Report) OK() bool {
+	return len(r.Problems) == 0
+}
+
+func (r *Report) problem(name, format string, args ...interface{}) {
+	r.Problems = append(r.Problems, Problem{Name: name, Reason: fmt.Sprintf(format, args...)})
+}
+
+type file struct {
+	size   int64
+	sha256 string
+	data   []byte
+}
+
+// Verify reads the archive from r and checks every file against the manifest. Files which are missing,
+// have been modified or aren't part of the manifest are reported as problems. If key isn't empty, the
+// signature of the manifest is checked as well. An error is only returned if the archive can't be read.
+func Verify(r io.Reader, key []byte) (Report, error) {
+	var rep Report
+
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return rep, err
+	}
+
+	files := make(map[string]file)
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return rep, err
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			rep.problem(hdr.Name, "unexpected entry of type %c", hdr.Typeflag)
+			continue
+		}
+		if _, ok := files[hdr.Name]; ok {
+			rep.problem(hdr.Name, "duplicate file")
+			continue
+		}
+
+		h := sha256.New()
+		f := file{}
+
+		w := io.Writer(h)
+		// the manifest and its signature are kept to check them afterwards
+		var buf strings.Builder
+		if hdr.Name == ManifestName || hdr.Name == SignatureName {
+			w = io.MultiWriter(h, &buf)
+		}
+
+		if f.size, err = io.Copy(w, tr); err != nil {
+			return rep, err
+		}
+		f.sha256 = hex.EncodeToString(h.Sum(nil))
+		f.data = []byte(buf.String())
+		files[hdr.Name] = f
+	}
+
+	// read up to the end of the gzip stream so its checksum is verified
+	if _, err := io.Copy(io.Discard, zr); err != nil {
+		return rep, err
+	}
+
+	manifest, ok := files[ManifestName]
+	if !ok {
+		rep.problem(ManifestName, "missing manifest")
+		return rep, nil
+	}
+	if err := json.Unmarshal(manifest.data, &rep.Manifest); err != nil {
+		rep.problem(ManifestName, "invalid manifest: %v", err)
+		return rep, nil
+	}
+	if rep.Manifest.Version != manifestVersion {
+		rep.problem(ManifestName, "unsupported version %d", rep.Manifest.Version)
+		return rep, nil
+	}
+
+	sig, signed := files[SignatureName]
+	rep.Signed = signed
+	if len(key) > 0 {
+		switch {
+		case !signed:
+			rep.problem(SignatureName, "missing signature")
+		case !hmac.Equal([]byte(strings.TrimSpace(string(sig.data))), []byte(sign(key, manifest.data))):
+			rep.problem(SignatureName, "invalid signature")
+		}
+	}
+
+	listed := map[string]bool{ManifestName: true, SignatureName: true}
+	for _, e := range rep.Manifest.Entries {
+		if listed[e.Name] {
+			rep.problem(e.Name, "listed more than once")
+			continue
+		}
+		listed[e.Name] = true
+
+		f, ok := files[e.Name]
+		switch {
+		case !ok:
+			rep.problem(e.Name, "missing")
+		case f.size != e.Size:
+			rep.problem(e.Name, "size is %d instead of %d bytes", f.size, e.Size)
+		case f.sha256 != e.SHA256:
+			rep.problem(e.Name, "SHA-256 digest is %v instead of %v", f.sha256, e.SHA256)
+		}
+	}
+
+	var unlisted []string
+	for name := range files {
+		if !listed[name] {
+			unlisted = append(unlisted, name)
+		}
+	}
+	sort.Strings(unlisted)
+	for _, name := range unlisted {
+		rep.problem(name, "not listed in the manifest")
+	}
+
+	return rep, nil
+}
+
+// VerifyFile verifies the archive at the path. If there's a .sha256 file next to it, the digest of the
+// archive is checked as well, and the day of the manifest has to match the name of the archive. A missing
+// .sha256 file is only reported as a problem if the manifest isn't signed since nothing else would detect
+// a rewritten manifest then.
+func VerifyFile(name string, key []byte) (Report, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return Report{}, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	br := bufio.NewReader(f)
+	rep, err := Verify(io.TeeReader(br, h), key)
+	if err != nil {
+		return rep, err
+	}
+	// read the rest of the file for the digest in case something has been appended to the archive
+	if _, err := io.Copy(h, br); err != nil {
+		return rep, err
+	}
+
+	base := filepath.Base(name)
+	if rep.Manifest.Day != "" && base != ArchiveName(rep.Manifest.Day) {
+		rep.problem(base, "manifest is for %v", rep.Manifest.Day)
+	}
+
+	sum, err := os.ReadFile(name + ".sha256")
+	if os.IsNotExist(err) {
+		if !rep.Signed {
+			rep.problem(base, "missing %v", base+".sha256")
+		}
+		return rep, nil
+	}
+	if err != nil {
+		return rep, err
+	}
+
+	fields := strings.Fields(string(sum))
+	if len(fields) == 0 || !strings.EqualFold(fields[0], hex.EncodeToString(h.Sum(nil))) {
+		rep.problem(base, "SHA-256 digest doesn't match %v", base+".sha256")
+	}
+
+	return rep, nil
+}
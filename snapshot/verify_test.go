@@ -0,0 +1,189 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sealed returns the path of an archive with two responses.
+func sealed(t *testing.T, key []byte) string {
+	dir := t.TempDir()
+
+	a := &Archiver{Dir: dir, Key: key}
+	for _, country := range []string{"us", "gb"} {
+		if err := a.Record(context.Background(), response(day1, country)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	name, err := a.Seal("2020-05-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return name
+}
+
+// rewrite writes the files of an archive to a new archive after passing them to modify. The .sha256 file
+// isn't updated.
+func rewrite(t *testing.T, name string, modify func(names []string, files map[string][]byte) []string) {
+	names, files := readArchive(t, name)
+	names = modify(names, files)
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for _, n := range names {
+		if err := tw.WriteHeader(&tar.Header{Name: n, Mode: 0644, Size: int64(len(files[n])), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write(files[n])
+	}
+	tw.Close()
+	zw.Close()
+
+	if err := os.WriteFile(name, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	const (
+		first  = "responses/000001-top-headlines.json"
+		second = "responses/000002-top-headlines.json"
+	)
+
+	cases := []struct {
+		name     string
+		key      []byte
+		modify   func(names []string, files map[string][]byte) []string
+		problems []string
+	}{
+		{"unchanged", []byte("key"), nil, nil},
+		{
+			"modified response",
+			nil,
+			func(names []string, files map[string][]byte) []string {
+				files[first] = bytes.Replace(files[first], []byte("ok"), []byte("no"), 1)
+				return names
+			},
+			[]string{first + ": SHA-256 digest is", "newsapi-2020-05-01.tar.gz: SHA-256 digest doesn't match"},
+		},
+		{
+			"missing response",
+			nil,
+			func(names []string, files map[string][]byte) []string {
+				return names[:len(names)-1]
+			},
+			[]string{second + ": missing", "newsapi-2020-05-01.tar.gz: SHA-256"},
+		},
+		{
+			"added file",
+			nil,
+			func(names []string, files map[string][]byte) []string {
+				files["responses/000003-top-headlines.json"] = []byte("{}")
+				return append(names, "responses/000003-top-headlines.json")
+			},
+			[]string{"responses/000003-top-headlines.json: not listed in the manifest", "newsapi-2020-05-01.tar.gz: SHA-256"},
+		},
+		{
+			"rewritten manifest",
+			[]byte("key"),
+			func(names []string, files map[string][]byte) []string {
+				files[ManifestName] = bytes.Replace(files[ManifestName], []byte("country=gb"), []byte("country=de"), 1)
+				return names
+			},
+			[]string{SignatureName + ": invalid signature", "newsapi-2020-05-01.tar.gz: SHA-256"},
+		},
+		{
+			"wrong key",
+			[]byte("other key"),
+			nil,
+			[]string{SignatureName + ": invalid signature"},
+		},
+		{
+			"missing manifest",
+			nil,
+			func(names []string, files map[string][]byte) []string {
+				return names[1:]
+			},
+			[]string{ManifestName + ": missing manifest", "newsapi-2020-05-01.tar.gz: SHA-256"},
+		},
+	}
+
+	for _, i := range cases {
+		name := sealed(t, []byte("key"))
+		if i.modify != nil {
+			rewrite(t, name, i.modify)
+		}
+
+		rep, err := VerifyFile(name, i.key)
+		if err != nil {
+			t.Fatalf("Expected no error but got %v when case=%v", err, i.name)
+		}
+
+		if len(rep.Problems) != len(i.problems) {
+			t.Errorf("Expected %d problems but got %v when case=%v", len(i.problems), rep.Problems, i.name)
+			continue
+		}
+		for j, p := range rep.Problems {
+			if !strings.HasPrefix(p.String(), i.problems[j]) {
+				t.Errorf("Expected %v but got %v when case=%v", i.problems[j], p, i.name)
+			}
+		}
+	}
+}
+
+func TestVerifyFile(t *testing.T) {
+	// a missing .sha256 file is only a problem if the manifest isn't signed
+	signed := sealed(t, []byte("key"))
+	if err := os.Remove(signed + ".sha256"); err != nil {
+		t.Fatal(err)
+	}
+	if rep, err := VerifyFile(signed, []byte("key")); err != nil || !rep.OK() || !rep.Signed {
+		t.Errorf("Expected no problems but got %v: %v", rep.Problems, err)
+	}
+
+	name := sealed(t, nil)
+	if rep, err := VerifyFile(name, nil); err != nil || !rep.OK() || rep.Signed {
+		t.Errorf("Expected no problems but got %v: %v", rep.Problems, err)
+	}
+
+	if err := os.Remove(name + ".sha256"); err != nil {
+		t.Fatal(err)
+	}
+	rep, err := VerifyFile(name, nil)
+	if err != nil || len(rep.Problems) != 1 || rep.Problems[0].String() != ArchiveName("2020-05-01")+": missing "+ArchiveName("2020-05-01")+".sha256" {
+		t.Errorf("Expected a problem for the missing .sha256 file but got %v: %v", rep.Problems, err)
+	}
+
+	// the signature is required if a key is given
+	if rep, _ := VerifyFile(name, []byte("key")); rep.OK() {
+		t.Error("Expected a problem for a missing signature")
+	}
+
+	renamed := filepath.Join(filepath.Dir(name), ArchiveName("2020-05-02"))
+	if err := os.Rename(name, renamed); err != nil {
+		t.Fatal(err)
+	}
+	if rep, _ := VerifyFile(renamed, nil); rep.OK() {
+		t.Error("Expected a problem for an archive with the name of another day")
+	}
+
+	// something other than a gzip stream appended to the archive
+	f, err := os.OpenFile(renamed, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("garbage")
+	f.Close()
+	if _, err := VerifyFile(renamed, nil); err == nil {
+		t.Error("Expected an error for a corrupted archive")
+	}
+}
@@ -11,7 +11,8 @@ type SourcesOpts struct {
 	Category, Country, Language string
 }
 
-type source struct {
+// Source represents a single news source returned in the "sources" field of the /sources route.
+type Source struct {
 	ID          string `json:"id"`
 	Name        string `json:"name"`
 	Description string `json:"description"`
@@ -24,7 +25,7 @@ type source struct {
 // SourcesResp represents what's being returned by the /source route.
 type SourcesResp struct {
 	Status  string   `json:"status"`
-	Sources []source `json:"sources"`
+	Sources []Source `json:"sources"`
 }
 
 func checkSourcesParams(opts SourcesOpts) error {
@@ -52,7 +53,7 @@ func (c *Client) Sources(ctx context.Context, opts SourcesOpts) (SourcesResp, er
 		}
 	}
 
-	body, err := fetchGetRoute(ctx, "https://newsapi.org/v2/sources", c.APIKey, opts)
+	body, err := fetchGetRoute(ctx, "https://newsapi.org/v2/sources", c.APIKey, opts, c.Recorder)
 	if err != nil {
 		return SourcesResp{}, err
 	}
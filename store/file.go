@@ -0,0 +1,628 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+// DefaultMaxSegmentSize is the size in bytes after which a FileStore starts a new segment if
+// FileOptions.MaxSegmentSize isn't set.
+const DefaultMaxSegmentSize = 64 << 20
+
+// FileOptions configures a FileStore.
+type FileOptions struct {
+	// MaxSegmentSize is the size in bytes after which a new segment is started. DefaultMaxSegmentSize is used if it's 0.
+	MaxSegmentSize int64
+	// NoSync disables the fsync after every Upsert. It makes writes a lot faster, but articles written
+	// shortly before a crash of the machine can get lost.
+	NoSync bool
+}
+
+// FileStore is an ArticleStore which keeps the articles in a directory without any dependencies. Every
+// Upsert appends the articles as JSON lines to the newest segment file, so an article which has been
+// updated is contained in several segments and only its newest line is used. Compact removes the outdated
+// lines. The index of the store only contains the key, the position of the newest line and the publishing
+// time of every article and is rebuilt from the segments when the store is opened.
+//
+// A directory must only be opened by one FileStore at a time.
+type FileStore struct {
+	dir  string
+	opts FileOptions
+	now  func() time.Time
+
+	mu       sync.RWMutex
+	segments map[int]*segment
+	active   *segment
+	index    map[string]fileEntry
+	// byTime contains the keys of all articles, newest first, if sorted is true
+	byTime []string
+	sorted bool
+	closed bool
+	// failed is set if a failed write couldn't be removed from the active segment again
+	failed error
+}
+
+// segmentFile is the part of *os.File used by the segments.
+type segmentFile interface {
+	io.Writer
+	io.ReaderAt
+	io.Closer
+	Sync() error
+	Truncate(size int64) error
+	Stat() (os.FileInfo, error)
+}
+
+type segment struct {
+	id   int
+	file segmentFile
+	size int64
+	// live is the number of bytes of lines which haven't been replaced by newer lines
+	live int64
+}
+
+// fileEntry is the position of the newest line of an article.
+type fileEntry struct {
+	segment   int
+	offset    int64
+	length    int64
+	published int64
+	firstSeen int64
+}
+
+// OpenFileStore opens the store in the directory dir and creates the directory if it doesn't exist. A line
+// which has only been written partially because of a crash is removed from the end of the newest segment.
+func OpenFileStore(dir string, opts FileOptions) (*FileStore, error) {
+	if opts.MaxSegmentSize <= 0 {
+		opts.MaxSegmentSize = DefaultMaxSegmentSize
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	s := &FileStore{
+		dir:      dir,
+		opts:     opts,
+		now:      time.Now,
+		segments: make(map[int]*segment),
+		index:    make(map[string]fileEntry),
+	}
+
+	ids, err := s.segmentIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, id := range ids {
+		if err := s.load(id, i == len(ids)-1); err != nil {
+			s.closeFiles()
+			return nil, err
+		}
+	}
+
+	if len(ids) == 0 {
+		if err := s.rotate(); err != nil {
+			return nil, err
+		}
+	} else {
+		s.active = s.segments[ids[len(ids)-1]]
+	}
+
+	return s, nil
+}
+
+func segmentName(id int) string {
+	return fmt.Sprintf("segment-%06d.jsonl", id)
+}
+
+// segmentIDs returns the IDs of the segments in the directory in ascending order.
+func (s *FileStore) segmentIDs() ([]int, error) {
+	names, err := filepath.Glob(filepath.Join(s.dir, "segment-*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int
+	for _, name := range names {
+		var id int
+		if _, err := fmt.Sscanf(filepath.Base(name), "segment-%d.jsonl", &id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	return ids, nil
+}
+
+// load opens the segment and adds its lines to the index. An incomplete line at the end of the last
+// segment is truncated, any other incomplete or broken line is an error.
+func (s *FileStore) load(id int, last bool) error {
+	name := filepath.Join(s.dir, segmentName(id))
+
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	seg := &segment{id: id, file: f}
+	s.segments[id] = seg
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 && !last {
+				return fmt.Errorf("The segment %s ends with an incomplete line", name)
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		var a StoredArticle
+		if err := json.Unmarshal(line, &a); err != nil {
+			return fmt.Errorf("The segment %s contains a broken line at offset %d: %v", name, seg.size, err)
+		}
+
+		s.add(a, fileEntry{segment: id, offset: seg.size, length: int64(len(line))})
+		seg.size += int64(len(line))
+	}
+
+	// remove whatever a crash has left behind after the last complete line
+	if fi, err := f.Stat(); err != nil {
+		return err
+	} else if fi.Size() != seg.size {
+		if err := f.Truncate(seg.size); err != nil {
+			return err
+		}
+		if err := f.Sync(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// add puts the position of the line of a into the index and replaces the position of an older line.
+func (s *FileStore) add(a StoredArticle, e fileEntry) {
+	if old, ok := s.index[a.Key]; ok {
+		if seg := s.segments[old.segment]; seg != nil {
+			seg.live -= old.length
+		}
+	} else {
+		s.byTime = append(s.byTime, a.Key)
+	}
+
+	e.published = millis(a.PublishedAt)
+	e.firstSeen = millis(a.FirstSeen)
+	s.index[a.Key] = e
+	s.segments[e.segment].live += e.length
+	s.sorted = false
+}
+
+// rotate starts a new segment.
+func (s *FileStore) rotate() error {
+	id := 1
+	if s.active != nil {
+		id = s.active.id + 1
+	}
+	// compaction can create segments after the active one
+	for s.segments[id] != nil {
+		id++
+	}
+
+	f, err := os.OpenFile(filepath.Join(s.dir, segmentName(id)), os.O_CREATE|os.O_EXCL|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	if !s.opts.NoSync {
+		if err := syncDir(s.dir); err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	s.active = &segment{id: id, file: f}
+	s.segments[id] = s.active
+
+	return nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
+
+// Upsert implements the ArticleStore interface. The articles are written with a single write and
+// synced to the disk before Upsert returns unless FileOptions.NoSync is set. If the write or the sync
+// fails, the lines are removed from the segment again. If even that fails, every following Upsert
+// returns an error until the store has been compacted or reopened.
+func (s *FileStore) Upsert(ctx context.Context, articles ...newsapi.Article) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	if s.failed != nil {
+		return s.failed
+	}
+
+	if len(articles) == 0 {
+		return nil
+	}
+
+	if s.active.size >= s.opts.MaxSegmentSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	var (
+		buf     bytes.Buffer
+		stored  = make([]StoredArticle, len(articles))
+		lengths = make([]int64, len(articles))
+		now     = fromMillis(millis(s.now()))
+	)
+
+	for i, a := range articles {
+		sa := StoredArticle{Article: a, Key: key(a), FirstSeen: now, LastSeen: now}
+		if e, ok := s.index[sa.Key]; ok {
+			sa.FirstSeen = fromMillis(e.firstSeen)
+		}
+
+		b, err := json.Marshal(sa)
+		if err != nil {
+			return err
+		}
+
+		buf.Write(b)
+		buf.WriteByte('\n')
+		stored[i], lengths[i] = sa, int64(len(b)+1)
+	}
+
+	if _, err := s.active.file.Write(buf.Bytes()); err != nil {
+		return s.undo(err)
+	}
+
+	if !s.opts.NoSync {
+		if err := s.active.file.Sync(); err != nil {
+			return s.undo(err)
+		}
+	}
+
+	offset := s.active.size
+	for i, sa := range stored {
+		s.add(sa, fileEntry{segment: s.active.id, offset: offset, length: lengths[i]})
+		offset += lengths[i]
+	}
+	s.active.size = offset
+
+	return nil
+}
+
+// undo removes whatever a failed Upsert has written from the end of the active segment so the following
+// lines start at the size known to the index. If the segment can't be truncated, the store stops
+// accepting writes since it doesn't know where the lines in the segment end.
+func (s *FileStore) undo(err error) error {
+	if terr := s.active.file.Truncate(s.active.size); terr != nil {
+		s.failed = fmt.Errorf("The segment %s couldn't be restored after a failed write: %v", segmentName(s.active.id), terr)
+	}
+
+	return err
+}
+
+// read reads the line at the position of e.
+func (s *FileStore) read(e fileEntry) (StoredArticle, error) {
+	var a StoredArticle
+
+	seg := s.segments[e.segment]
+	if seg == nil {
+		return a, fmt.Errorf("The segment %d doesn't exist", e.segment)
+	}
+
+	b := make([]byte, e.length)
+	if _, err := seg.file.ReadAt(b, e.offset); err != nil {
+		return a, err
+	}
+
+	err := json.Unmarshal(b, &a)
+	return a, err
+}
+
+// Get implements the ArticleStore interface.
+func (s *FileStore) Get(ctx context.Context, url string) (StoredArticle, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return StoredArticle{}, ErrClosed
+	}
+
+	k := lookupKey(url)
+	e, ok := s.index[k]
+	if !ok {
+		return StoredArticle{}, ErrNotFound
+	}
+
+	return s.read(e)
+}
+
+// sortIndex sorts byTime if it has changed since it has been sorted the last time.
+func (s *FileStore) sortIndex() {
+	if s.sorted {
+		return
+	}
+
+	sort.Slice(s.byTime, func(i, j int) bool {
+		a, b := s.index[s.byTime[i]], s.index[s.byTime[j]]
+		if a.published != b.published {
+			return a.published > b.published
+		}
+		return s.byTime[i] < s.byTime[j]
+	})
+	s.sorted = true
+}
+
+// Query implements the ArticleStore interface. Only the lines of the articles published in the range
+// of the query are read.
+func (s *FileStore) Query(ctx context.Context, q Query) ([]StoredArticle, error) {
+	// sorting modifies the index so it needs the write lock
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, ErrClosed
+	}
+
+	s.sortIndex()
+
+	// byTime is sorted in descending order
+	start := 0
+	if !q.To.IsZero() {
+		to := millis(q.To)
+		start = sort.Search(len(s.byTime), func(i int) bool {
+			return s.index[s.byTime[i]].published < to
+		})
+	}
+
+	var (
+		articles []StoredArticle
+		skipped  int
+		from     = millis(q.From)
+	)
+
+	for _, k := range s.byTime[start:] {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		e := s.index[k]
+		if !q.From.IsZero() && e.published < from {
+			break
+		}
+
+		a, err := s.read(e)
+		if err != nil {
+			return nil, err
+		}
+
+		if !q.matches(a) {
+			continue
+		}
+
+		if skipped < q.Offset {
+			skipped++
+			continue
+		}
+
+		articles = append(articles, a)
+		if q.Limit > 0 && len(articles) == q.Limit {
+			break
+		}
+	}
+
+	return articles, nil
+}
+
+// Compact rewrites the newest lines of all articles into new segments and removes the old segments.
+// The old segments are only removed after the new ones have been synced, so a crash during the
+// compaction doesn't lose any articles.
+func (s *FileStore) Compact(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	old := make(map[int]*segment, len(s.segments))
+	for id, seg := range s.segments {
+		old[id] = seg
+	}
+
+	s.sortIndex()
+	// the articles are written oldest first so the segments roughly follow the publishing times
+	keys := make([]string, len(s.byTime))
+	for i, k := range s.byTime {
+		keys[len(keys)-1-i] = k
+	}
+
+	entries := make(map[string]fileEntry, len(keys))
+	if err := s.compactInto(ctx, old, keys, entries); err != nil {
+		return err
+	}
+
+	s.index = entries
+	// the segment which couldn't be restored is removed as well
+	s.failed = nil
+	for id, seg := range old {
+		seg.file.Close()
+		delete(s.segments, id)
+		if err := os.Remove(filepath.Join(s.dir, segmentName(id))); err != nil {
+			return err
+		}
+	}
+
+	return syncDir(s.dir)
+}
+
+// compactInto writes the lines of the articles with the keys into new segments and stores their new
+// positions in entries. The new segments are removed again if it fails.
+func (s *FileStore) compactInto(ctx context.Context, old map[int]*segment, keys []string, entries map[string]fileEntry) (err error) {
+	prev := s.active
+
+	var written []*segment
+	defer func() {
+		if err == nil {
+			return
+		}
+		for _, seg := range written {
+			seg.file.Close()
+			os.Remove(filepath.Join(s.dir, segmentName(seg.id)))
+			delete(s.segments, seg.id)
+		}
+		s.active = prev
+	}()
+
+	next := func() error {
+		// a full segment is synced right away since only the last segment may end with a broken line
+		if len(written) > 0 {
+			if err := s.active.file.Sync(); err != nil {
+				return err
+			}
+		}
+		if err := s.rotate(); err != nil {
+			return err
+		}
+		written = append(written, s.active)
+		return nil
+	}
+
+	if err := next(); err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		e := s.index[k]
+		b := make([]byte, e.length)
+		if _, err := old[e.segment].file.ReadAt(b, e.offset); err != nil {
+			return err
+		}
+
+		if s.active.size > 0 && s.active.size+e.length > s.opts.MaxSegmentSize {
+			if err := next(); err != nil {
+				return err
+			}
+		}
+
+		if _, err := s.active.file.Write(b); err != nil {
+			return err
+		}
+
+		e.segment, e.offset = s.active.id, s.active.size
+		entries[k] = e
+		s.active.size += e.length
+		s.active.live += e.length
+	}
+
+	return s.active.file.Sync()
+}
+
+// Garbage returns the share of the bytes of the segments which are taken up by outdated lines. It can
+// be used to decide when Compact should be called.
+func (s *FileStore) Garbage() float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var size, live int64
+	for _, seg := range s.segments {
+		size += seg.size
+		live += seg.live
+	}
+
+	if size == 0 {
+		return 0
+	}
+
+	return float64(size-live) / float64(size)
+}
+
+// Close implements the ArticleStore interface.
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	return s.closeFiles()
+}
+
+func (s *FileStore) closeFiles() error {
+	var err error
+	for _, seg := range s.segments {
+		if e := seg.file.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	return err
+}
+
+// matches reports whether a matches the source and text restrictions of the query.
+func (q Query) matches(a StoredArticle) bool {
+	if len(q.Sources) > 0 {
+		found := false
+		for _, src := range q.Sources {
+			if src == a.Source.ID || src == a.Source.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if q.Text != "" {
+		text := strings.ToLower(q.Text)
+		if !strings.Contains(strings.ToLower(a.Title), text) &&
+			!strings.Contains(strings.ToLower(a.Description), text) &&
+			!strings.Contains(strings.ToLower(a.Content), text) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,332 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+var (
+	_ ArticleStore = (*SQLStore)(nil)
+	_ ArticleStore = (*FileStore)(nil)
+)
+
+func testArticles() []newsapi.Article {
+	day := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	return []newsapi.Article{
+		{URL: "https://example.com/a", Title: "Markets rally", Source: newsapi.ArticleSource{ID: "example", Name: "Example"}, PublishedAt: day.Add(1 * time.Hour)},
+		{URL: "https://example.com/b", Title: "Storm warning", Description: "Heavy RAIN expected", Source: newsapi.ArticleSource{ID: "example", Name: "Example"}, PublishedAt: day.Add(2 * time.Hour)},
+		{URL: "https://other.com/c", Title: "Rain stops play", Source: newsapi.ArticleSource{Name: "Other"}, PublishedAt: day.Add(26 * time.Hour)},
+		{Title: "No URL", Source: newsapi.ArticleSource{Name: "Other"}, PublishedAt: day.Add(3 * time.Hour)},
+	}
+}
+
+func openFileStore(t *testing.T, dir string, opts FileOptions, now time.Time) *FileStore {
+	s, err := OpenFileStore(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func titles(articles []StoredArticle) []string {
+	var ts []string
+	for _, a := range articles {
+		ts = append(ts, a.Title)
+	}
+	return ts
+}
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	first := time.Date(2020, 5, 2, 0, 0, 0, 0, time.UTC)
+
+	s := openFileStore(t, dir, FileOptions{}, first)
+	if err := s.Upsert(ctx, testArticles()...); err != nil {
+		t.Fatal(err)
+	}
+
+	// an update with tracking parameters replaces the same article
+	second := first.Add(time.Hour)
+	s.now = func() time.Time { return second }
+	if err := s.Upsert(ctx, newsapi.Article{URL: "https://example.com/a?utm_source=rss", Title: "Markets rally again", PublishedAt: testArticles()[0].PublishedAt, Source: newsapi.ArticleSource{ID: "example"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := s.Get(ctx, "https://example.com/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Title != "Markets rally again" || !a.FirstSeen.Equal(first) || !a.LastSeen.Equal(second) {
+		t.Errorf("Expected an updated article which keeps its first sighting but got %+v", a)
+	}
+
+	if _, err := s.Get(ctx, "https://example.com/missing"); err != ErrNotFound {
+		t.Errorf("Expected %v but got %v", ErrNotFound, err)
+	}
+
+	cases := []struct {
+		q        Query
+		expected []string
+	}{
+		{Query{}, []string{"Rain stops play", "No URL", "Storm warning", "Markets rally again"}},
+		{Query{From: first.Add(-24 * time.Hour), To: first}, []string{"No URL", "Storm warning", "Markets rally again"}},
+		{Query{Sources: []string{"Other"}}, []string{"Rain stops play", "No URL"}},
+		{Query{Text: "rain"}, []string{"Rain stops play", "Storm warning"}},
+		{Query{Limit: 2, Offset: 1}, []string{"No URL", "Storm warning"}},
+	}
+
+	check := func(s *FileStore) {
+		for _, i := range cases {
+			articles, err := s.Query(ctx, i.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ts := titles(articles); !reflect.DeepEqual(ts, i.expected) {
+				t.Errorf("Expected %v but got %v when case=%+v", i.expected, ts, i.q)
+			}
+		}
+	}
+	check(s)
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, "https://example.com/a"); err != ErrClosed {
+		t.Errorf("Expected %v but got %v", ErrClosed, err)
+	}
+
+	// the index is rebuilt when the store is opened again
+	s = openFileStore(t, dir, FileOptions{}, second)
+	defer s.Close()
+	check(s)
+}
+
+func TestFileStoreRecovery(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	s := openFileStore(t, dir, FileOptions{}, time.Now())
+	if err := s.Upsert(ctx, testArticles()[:2]...); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// simulate a crash in the middle of a write
+	name := filepath.Join(dir, segmentName(1))
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"source":{"id":"example","na`)
+	f.Close()
+
+	s = openFileStore(t, dir, FileOptions{}, time.Now())
+	defer s.Close()
+
+	articles, err := s.Query(ctx, Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(articles) != 2 {
+		t.Errorf("Expected 2 articles after the recovery but got %d", len(articles))
+	}
+
+	// the broken line has been removed, so new lines can be appended
+	if err := s.Upsert(ctx, testArticles()[2]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, testArticles()[2].URL); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFileStoreBrokenLine(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	s := openFileStore(t, dir, FileOptions{}, time.Now())
+	if err := s.Upsert(ctx, testArticles()[0]); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// a broken line followed by a complete line isn't a torn write, even in the last segment
+	name := filepath.Join(dir, segmentName(1))
+	line, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("{\"source\":{\"id\":\"exa\n")
+	f.Write(line)
+	f.Close()
+
+	if _, err := OpenFileStore(dir, FileOptions{}); err == nil {
+		t.Error("Expected an error for the broken line in the middle of the segment")
+	}
+}
+
+// failingFile makes the writes, syncs or truncations of a segment fail.
+type failingFile struct {
+	segmentFile
+	write, sync, truncate bool
+}
+
+var errInjected = errors.New("injected")
+
+func (f *failingFile) Write(b []byte) (int, error) {
+	if f.write {
+		// a part of the lines ends up in the file
+		n, _ := f.segmentFile.Write(b[:len(b)/2])
+		return n, errInjected
+	}
+	return f.segmentFile.Write(b)
+}
+
+func (f *failingFile) Sync() error {
+	if f.sync {
+		return errInjected
+	}
+	return f.segmentFile.Sync()
+}
+
+func (f *failingFile) Truncate(size int64) error {
+	if f.truncate {
+		return errInjected
+	}
+	return f.segmentFile.Truncate(size)
+}
+
+func TestFileStoreFailedWrite(t *testing.T) {
+	ctx := context.Background()
+
+	cases := []struct {
+		file   failingFile
+		sticky bool
+	}{
+		{failingFile{write: true}, false},
+		{failingFile{sync: true}, false},
+		{failingFile{write: true, truncate: true}, true},
+		{failingFile{sync: true, truncate: true}, true},
+	}
+
+	for _, c := range cases {
+		dir := t.TempDir()
+
+		s := openFileStore(t, dir, FileOptions{}, time.Now())
+		if err := s.Upsert(ctx, testArticles()[0]); err != nil {
+			t.Fatal(err)
+		}
+
+		f := c.file
+		f.segmentFile = s.active.file
+		s.active.file = &f
+
+		if err := s.Upsert(ctx, testArticles()[1:3]...); !errors.Is(err, errInjected) {
+			t.Errorf("Expected the injected error but got %v when case=%+v", err, c.file)
+		}
+
+		f.write, f.sync = false, false
+		err := s.Upsert(ctx, testArticles()[2])
+		if c.sticky != (err != nil) {
+			t.Errorf("Expected the store to be failed=%v but got %v when case=%+v", c.sticky, err, c.file)
+		}
+
+		if c.sticky {
+			// the compaction replaces the segment which couldn't be restored
+			if err := s.Compact(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Upsert(ctx, testArticles()[2]); err != nil {
+				t.Errorf("Expected the compaction to restore the store but got %v when case=%+v", err, c.file)
+			}
+		}
+
+		if _, err := s.Get(ctx, testArticles()[1].URL); err != ErrNotFound {
+			t.Errorf("Expected the failed article to be missing but got %v when case=%+v", err, c.file)
+		}
+		s.Close()
+
+		s = openFileStore(t, dir, FileOptions{}, time.Now())
+		articles, err := s.Query(ctx, Query{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := titles(articles); !reflect.DeepEqual(got, []string{"Rain stops play", "Markets rally"}) {
+			t.Errorf("Expected the articles of the successful writes but got %v when case=%+v", got, c.file)
+		}
+		s.Close()
+	}
+}
+
+func TestFileStoreCompact(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	s := openFileStore(t, dir, FileOptions{MaxSegmentSize: 512, NoSync: true}, time.Now())
+	defer s.Close()
+
+	for n := 0; n < 5; n++ {
+		if err := s.Upsert(ctx, testArticles()...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids, _ := s.segmentIDs()
+	if len(ids) < 2 {
+		t.Fatalf("Expected the segments to be rotated but got %d segments", len(ids))
+	}
+
+	if g := s.Garbage(); g < 0.7 {
+		t.Errorf("Expected mostly outdated lines but got a garbage ratio of %v", g)
+	}
+
+	if err := s.Compact(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if g := s.Garbage(); g != 0 {
+		t.Errorf("Expected no outdated lines after the compaction but got a garbage ratio of %v", g)
+	}
+
+	after, _ := s.segmentIDs()
+	if after[0] <= ids[len(ids)-1] {
+		t.Errorf("Expected the old segments %v to be replaced but got %v", ids, after)
+	}
+
+	articles, err := s.Query(ctx, Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(articles) != 4 {
+		t.Errorf("Expected 4 articles after the compaction but got %d", len(articles))
+	}
+
+	// the store can still be written to and reopened
+	if err := s.Upsert(ctx, testArticles()[0]); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	s = openFileStore(t, dir, FileOptions{}, time.Now())
+	defer s.Close()
+
+	if articles, _ := s.Query(ctx, Query{}); len(articles) != 4 {
+		t.Errorf("Expected 4 articles after reopening but got %d", len(articles))
+	}
+}
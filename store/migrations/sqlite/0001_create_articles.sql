@@ -0,0 +1,15 @@
+CREATE TABLE newsapi_articles (
+	canonical_url TEXT PRIMARY KEY,
+	url           TEXT NOT NULL,
+	source_id     TEXT NOT NULL DEFAULT '',
+	source_name   TEXT NOT NULL DEFAULT '',
+	author        TEXT NOT NULL DEFAULT '',
+	title         TEXT NOT NULL DEFAULT '',
+	description   TEXT NOT NULL DEFAULT '',
+	url_to_image  TEXT NOT NULL DEFAULT '',
+	published_at  INTEGER NOT NULL DEFAULT 0,
+	content       TEXT NOT NULL DEFAULT '',
+	search_text   TEXT NOT NULL DEFAULT '',
+	first_seen    INTEGER NOT NULL,
+	last_seen     INTEGER NOT NULL
+);
@@ -0,0 +1,3 @@
+CREATE INDEX newsapi_articles_published_at ON newsapi_articles (published_at);
+CREATE INDEX newsapi_articles_source_id ON newsapi_articles (source_id);
+CREATE INDEX newsapi_articles_url ON newsapi_articles (url);
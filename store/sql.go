@@ -0,0 +1,368 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+//go:embed migrations
+var migrations embed.FS
+
+// Dialect contains the differences between the SQL databases supported by SQLStore.
+type Dialect struct {
+	// Name is the name of the directory of the dialect's migrations.
+	Name string
+	// Placeholder returns the placeholder of the n-th argument of a statement, starting at 1.
+	Placeholder func(n int) string
+}
+
+var (
+	// Postgres is the dialect of PostgreSQL, e.g. for the github.com/lib/pq or github.com/jackc/pgx drivers.
+	Postgres = Dialect{
+		Name:        "postgres",
+		Placeholder: func(n int) string { return "$" + strconv.Itoa(n) },
+	}
+
+	// SQLite is the dialect of SQLite 3.24 or newer, e.g. for the github.com/mattn/go-sqlite3 or modernc.org/sqlite drivers.
+	SQLite = Dialect{
+		Name:        "sqlite",
+		Placeholder: func(n int) string { return "?" },
+	}
+)
+
+const articleColumns = "canonical_url, url, source_id, source_name, author, title, description, url_to_image, published_at, content, first_seen, last_seen"
+
+// searchText returns the value of the search_text column, which Query.Text is matched against. It's lower
+// cased in Go since the LOWER function of SQLite only folds ASCII letters.
+func searchText(a newsapi.Article) string {
+	return strings.ToLower(a.Title + "\n" + a.Description + "\n" + a.Content)
+}
+
+// SQLStore is an ArticleStore backed by a PostgreSQL or SQLite database. The articles are stored in the
+// newsapi_articles table, the applied migrations in the newsapi_schema_migrations table. Times are stored
+// as unix milliseconds so they compare the same way in every database.
+//
+// Query.Text is matched against the search_text column, a lower cased copy of the title, description and
+// content which Upsert writes, so the case of every letter is ignored in both databases.
+type SQLStore struct {
+	db      *sql.DB
+	dialect Dialect
+	now     func() time.Time
+}
+
+// NewSQLStore returns a store which uses the database db with the given dialect. Migrate has to be
+// called before the store is used for the first time.
+func NewSQLStore(db *sql.DB, dialect Dialect) *SQLStore {
+	return &SQLStore{db: db, dialect: dialect, now: time.Now}
+}
+
+type migration struct {
+	version    int
+	name       string
+	statements []string
+}
+
+// loadMigrations returns the embedded migrations of the dialect ordered by their version.
+func loadMigrations(dialect string) ([]migration, error) {
+	dir := path.Join("migrations", dialect)
+
+	entries, err := migrations.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("The dialect %s isn't supported", dialect)
+	}
+
+	var ms []migration
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasSuffix(name, ".sql") {
+			continue
+		}
+
+		i := strings.Index(name, "_")
+		if i < 0 {
+			return nil, fmt.Errorf("The migration %s doesn't start with a version", name)
+		}
+
+		version, err := strconv.Atoi(name[:i])
+		if err != nil {
+			return nil, fmt.Errorf("The migration %s doesn't start with a version", name)
+		}
+
+		b, err := migrations.ReadFile(path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		m := migration{version: version, name: name}
+		// not every driver supports several statements at once
+		for _, stmt := range strings.Split(string(b), ";") {
+			if stmt = strings.TrimSpace(stmt); stmt != "" {
+				m.statements = append(m.statements, stmt)
+			}
+		}
+		ms = append(ms, m)
+	}
+
+	sort.Slice(ms, func(i, j int) bool {
+		return ms[i].version < ms[j].version
+	})
+
+	return ms, nil
+}
+
+// Migrate creates the tables of the store or updates them to the latest version. Every migration runs in
+// its own transaction and migrations which have already been applied are skipped.
+func (s *SQLStore) Migrate(ctx context.Context) error {
+	ms, err := loadMigrations(s.dialect.Name)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS newsapi_schema_migrations (version INTEGER PRIMARY KEY, applied_at BIGINT NOT NULL)")
+	if err != nil {
+		return err
+	}
+
+	applied := make(map[int]bool)
+	rows, err := s.db.QueryContext(ctx, "SELECT version FROM newsapi_schema_migrations")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			rows.Close()
+			return err
+		}
+		applied[v] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, m := range ms {
+		if applied[m.version] {
+			continue
+		}
+
+		if err := s.apply(ctx, m); err != nil {
+			return fmt.Errorf("The migration %s failed: %v", m.name, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *SQLStore) apply(ctx context.Context, m migration) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, stmt := range m.statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	insert := fmt.Sprintf("INSERT INTO newsapi_schema_migrations (version, applied_at) VALUES (%s, %s)", s.dialect.Placeholder(1), s.dialect.Placeholder(2))
+	if _, err := tx.ExecContext(ctx, insert, m.version, millis(s.now())); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Upsert implements the ArticleStore interface. All articles are written in a single transaction.
+func (s *SQLStore) Upsert(ctx context.Context, articles ...newsapi.Article) error {
+	if len(articles) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, 13)
+	for i := range placeholders {
+		placeholders[i] = s.dialect.Placeholder(i + 1)
+	}
+
+	// both dialects support the same upsert syntax, the first_seen column is never overwritten
+	stmt := "INSERT INTO newsapi_articles (" + articleColumns + ", search_text) VALUES (" + strings.Join(placeholders, ", ") + ") " +
+		"ON CONFLICT (canonical_url) DO UPDATE SET url = excluded.url, source_id = excluded.source_id, " +
+		"source_name = excluded.source_name, author = excluded.author, title = excluded.title, " +
+		"description = excluded.description, url_to_image = excluded.url_to_image, published_at = excluded.published_at, " +
+		"content = excluded.content, last_seen = excluded.last_seen, search_text = excluded.search_text"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	now := millis(s.now())
+	for _, a := range articles {
+		_, err := tx.ExecContext(ctx, stmt,
+			key(a), a.URL, a.Source.ID, a.Source.Name, a.Author, a.Title, a.Description,
+			a.URLToImage, millis(a.PublishedAt), a.Content, now, now, searchText(a),
+		)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Get implements the ArticleStore interface.
+func (s *SQLStore) Get(ctx context.Context, url string) (StoredArticle, error) {
+	q := "SELECT " + articleColumns + " FROM newsapi_articles WHERE canonical_url = " + s.dialect.Placeholder(1)
+
+	rows, err := s.db.QueryContext(ctx, q, lookupKey(url))
+	if err != nil {
+		return StoredArticle{}, err
+	}
+
+	articles, err := scanArticles(rows)
+	if err != nil {
+		return StoredArticle{}, err
+	}
+
+	if len(articles) == 0 {
+		return StoredArticle{}, ErrNotFound
+	}
+
+	return articles[0], nil
+}
+
+// Query implements the ArticleStore interface.
+func (s *SQLStore) Query(ctx context.Context, q Query) ([]StoredArticle, error) {
+	stmt, args := s.buildQuery(q)
+
+	rows, err := s.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanArticles(rows)
+}
+
+func (s *SQLStore) buildQuery(q Query) (string, []interface{}) {
+	var (
+		conds []string
+		args  []interface{}
+	)
+
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return s.dialect.Placeholder(len(args))
+	}
+
+	if !q.From.IsZero() {
+		conds = append(conds, "published_at >= "+arg(millis(q.From)))
+	}
+	if !q.To.IsZero() {
+		conds = append(conds, "published_at < "+arg(millis(q.To)))
+	}
+
+	if len(q.Sources) > 0 {
+		var in []string
+		for _, src := range q.Sources {
+			in = append(in, arg(src))
+		}
+		list := strings.Join(in, ", ")
+
+		// the sources are passed twice so that the placeholders of both dialects work
+		var names []string
+		for _, src := range q.Sources {
+			names = append(names, arg(src))
+		}
+		conds = append(conds, "(source_id IN ("+list+") OR source_name IN ("+strings.Join(names, ", ")+"))")
+	}
+
+	if q.Text != "" {
+		pattern := "%" + escapeLike(strings.ToLower(q.Text)) + "%"
+		conds = append(conds, "search_text LIKE "+arg(pattern)+" ESCAPE '\\'")
+	}
+
+	stmt := "SELECT " + articleColumns + " FROM newsapi_articles"
+	if len(conds) > 0 {
+		stmt += " WHERE " + strings.Join(conds, " AND ")
+	}
+	stmt += " ORDER BY published_at DESC, canonical_url"
+
+	if q.Limit > 0 {
+		stmt += " LIMIT " + arg(q.Limit)
+		if q.Offset > 0 {
+			stmt += " OFFSET " + arg(q.Offset)
+		}
+	} else if q.Offset > 0 {
+		// both dialects need a limit for an offset, -1 means no limit in SQLite and NULL in Postgres
+		if s.dialect.Name == "postgres" {
+			stmt += " LIMIT ALL OFFSET " + arg(q.Offset)
+		} else {
+			stmt += " LIMIT -1 OFFSET " + arg(q.Offset)
+		}
+	}
+
+	return stmt, args
+}
+
+// Close closes the underlying database.
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+func scanArticles(rows *sql.Rows) ([]StoredArticle, error) {
+	defer rows.Close()
+
+	var articles []StoredArticle
+	for rows.Next() {
+		var (
+			a                              StoredArticle
+			published, firstSeen, lastSeen int64
+		)
+
+		err := rows.Scan(
+			&a.Key, &a.URL, &a.Source.ID, &a.Source.Name, &a.Author, &a.Title, &a.Description,
+			&a.URLToImage, &published, &a.Content, &firstSeen, &lastSeen,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		a.PublishedAt, a.FirstSeen, a.LastSeen = fromMillis(published), fromMillis(firstSeen), fromMillis(lastSeen)
+		articles = append(articles, a)
+	}
+
+	return articles, rows.Err()
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func millis(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func fromMillis(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, ms*int64(time.Millisecond)).UTC()
+}
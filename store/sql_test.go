@@ -0,0 +1,508 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+// fakeDriver records the statements executed on it and answers queries with canned rows.
+type fakeDriver struct {
+	mu      sync.Mutex
+	execs   []string
+	args    [][]driver.Value
+	commits int
+	// rows returns the columns and rows of a query
+	rows func(query string, args []driver.Value) ([]string, [][]driver.Value)
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) { return &fakeConn{d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{c.d, query}, nil }
+func (c *fakeConn) Close() error                              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)                 { return c, nil }
+
+func (c *fakeConn) Commit() error {
+	c.d.mu.Lock()
+	c.d.commits++
+	c.d.mu.Unlock()
+	return nil
+}
+
+func (c *fakeConn) Rollback() error { return nil }
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	if strings.Contains(s.query, "fail") {
+		return nil, errors.New("failed")
+	}
+
+	s.d.execs = append(s.d.execs, s.query)
+	s.d.args = append(s.d.args, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	var (
+		cols []string
+		rows [][]driver.Value
+	)
+	if s.d.rows != nil {
+		cols, rows = s.d.rows(s.query, args)
+	}
+	return &fakeRows{cols: cols, rows: rows}, nil
+}
+
+type fakeRows struct {
+	cols []string
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.cols }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+var (
+	fakeMu      sync.Mutex
+	fakeDrivers int
+)
+
+// openFake registers a new fake driver and returns a store which uses it.
+func openFake(t *testing.T, d *fakeDriver, dialect Dialect) *SQLStore {
+	fakeMu.Lock()
+	fakeDrivers++
+	name := "fake" + strings.Repeat("x", fakeDrivers)
+	fakeMu.Unlock()
+
+	sql.Register(name, d)
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewSQLStore(db, dialect)
+	s.now = func() time.Time { return time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC) }
+	return s
+}
+
+func TestLoadMigrations(t *testing.T) {
+	for _, dialect := range []string{"postgres", "sqlite"} {
+		ms, err := loadMigrations(dialect)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(ms) != 2 || ms[0].version != 1 || ms[1].version != 2 {
+			t.Errorf("Expected the migrations 1 and 2 but got %+v when case=%v", ms, dialect)
+		}
+
+		for _, m := range ms {
+			for _, stmt := range m.statements {
+				if strings.Contains(stmt, ";") || stmt == "" {
+					t.Errorf("Expected single statements but got %q when case=%v", stmt, dialect)
+				}
+			}
+		}
+	}
+
+	if _, err := loadMigrations("oracle"); err == nil {
+		t.Error("Expected an error for an unsupported dialect")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	d := &fakeDriver{}
+	s := openFake(t, d, Postgres)
+	defer s.Close()
+
+	if err := s.Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(d.execs[0], "CREATE TABLE IF NOT EXISTS newsapi_schema_migrations") {
+		t.Errorf("Expected the migrations table to be created first but got %q", d.execs[0])
+	}
+
+	var versions []interface{}
+	for i, stmt := range d.execs {
+		if strings.HasPrefix(stmt, "INSERT INTO newsapi_schema_migrations") {
+			versions = append(versions, d.args[i][0])
+		}
+	}
+	if expected := []interface{}{int64(1), int64(2)}; !reflect.DeepEqual(versions, expected) {
+		t.Errorf("Expected the versions %v to be recorded but got %v", expected, versions)
+	}
+	if d.commits != 2 {
+		t.Errorf("Expected every migration to be committed separately but got %d commits", d.commits)
+	}
+
+	// the first migration has already been applied
+	d = &fakeDriver{rows: func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		return []string{"version"}, [][]driver.Value{{int64(1)}}
+	}}
+	s = openFake(t, d, SQLite)
+	defer s.Close()
+
+	if err := s.Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, stmt := range d.execs {
+		if strings.Contains(stmt, "CREATE TABLE IF NOT EXISTS newsapi_articles") {
+			t.Error("Expected an applied migration to be skipped")
+		}
+	}
+	if d.commits != 1 {
+		t.Errorf("Expected 1 commit but got %d", d.commits)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	d := &fakeDriver{}
+	s := openFake(t, d, SQLite)
+	defer s.Close()
+
+	published := time.Date(2020, 4, 30, 8, 0, 0, 0, time.UTC)
+	articles := []newsapi.Article{
+		{URL: "https://example.com/a?utm_source=feed", Title: "A", Source: newsapi.ArticleSource{ID: "example"}, PublishedAt: published},
+		{Title: "No URL"},
+	}
+
+	if err := s.Upsert(context.Background(), articles...); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.execs) != 2 || d.commits != 1 {
+		t.Fatalf("Expected 2 statements in 1 transaction but got %d statements and %d commits", len(d.execs), d.commits)
+	}
+
+	if !strings.Contains(d.execs[0], "ON CONFLICT (canonical_url) DO UPDATE") || strings.Contains(d.execs[0], "first_seen = excluded.first_seen") {
+		t.Errorf("Expected an upsert which keeps first_seen but got %q", d.execs[0])
+	}
+
+	args := d.args[0]
+	if args[0] != "https://example.com/a" || args[1] != articles[0].URL || args[2] != "example" || args[8] != published.UnixNano()/int64(time.Millisecond) || args[12] != "a\n\n" {
+		t.Errorf("Unexpected arguments %v", args)
+	}
+
+	if k := d.args[1][0].(string); !strings.HasPrefix(k, "id:") {
+		t.Errorf("Expected an article without a URL to be stored under its ID but got %q", k)
+	}
+
+	if err := s.Upsert(context.Background()); err != nil || len(d.execs) != 2 {
+		t.Error("Expected an empty upsert to do nothing")
+	}
+}
+
+func TestGet(t *testing.T) {
+	published := time.Date(2020, 4, 30, 8, 0, 0, 0, time.UTC)
+
+	d := &fakeDriver{rows: func(query string, args []driver.Value) ([]string, [][]driver.Value) {
+		cols := strings.Split(articleColumns, ", ")
+		if args[0] != "https://example.com/a" {
+			return cols, nil
+		}
+
+		ms := published.UnixNano() / int64(time.Millisecond)
+		return cols, [][]driver.Value{{"https://example.com/a", "https://example.com/a?ref=x", "example", "Example", "Jane Doe", "A", "", "", ms, "", ms, ms + 1000}}
+	}}
+	s := openFake(t, d, Postgres)
+	defer s.Close()
+
+	a, err := s.Get(context.Background(), "https://example.com/a?utm_medium=social")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a.Key != "https://example.com/a" || a.Title != "A" || !a.PublishedAt.Equal(published) || !a.LastSeen.Equal(published.Add(time.Second)) {
+		t.Errorf("Unexpected article %+v", a)
+	}
+
+	if _, err := s.Get(context.Background(), "https://example.com/b"); err != ErrNotFound {
+		t.Errorf("Expected %v but got %v", ErrNotFound, err)
+	}
+}
+
+func TestBuildQuery(t *testing.T) {
+	from := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		dialect Dialect
+		q       Query
+		where   string
+		tail    string
+		args    int
+	}{
+		{Postgres, Query{}, "", " ORDER BY published_at DESC, canonical_url", 0},
+		{Postgres, Query{From: from, Limit: 10, Offset: 20}, " WHERE published_at >= $1", " LIMIT $2 OFFSET $3", 3},
+		{SQLite, Query{From: from, To: from.AddDate(0, 0, 1)}, " WHERE published_at >= ? AND published_at < ?", "", 2},
+		{Postgres, Query{Sources: []string{"bbc-news", "CNN"}}, " WHERE (source_id IN ($1, $2) OR source_name IN ($3, $4))", "", 4},
+		{SQLite, Query{Text: "50%"}, " WHERE search_text LIKE ? ESCAPE '\\'", "", 1},
+		{SQLite, Query{Offset: 5}, "", " LIMIT -1 OFFSET ?", 1},
+		{Postgres, Query{Offset: 5}, "", " LIMIT ALL OFFSET $1", 1},
+	}
+
+	s := &SQLStore{}
+	for _, i := range cases {
+		s.dialect = i.dialect
+		stmt, args := s.buildQuery(i.q)
+
+		if !strings.Contains(stmt, "FROM newsapi_articles"+i.where+" ORDER BY") || !strings.HasSuffix(stmt, i.tail) || len(args) != i.args {
+			t.Errorf("Unexpected statement %q with %d arguments when case=%+v", stmt, len(args), i.q)
+		}
+	}
+
+	_, args := s.buildQuery(Query{Text: "50%_off"})
+	if args[0] != `%50\%\_off%` {
+		t.Errorf("Expected the wildcards to be escaped but got %v", args[0])
+	}
+}
+
+// cliDriver runs the statements with the sqlite3 command line shell, so the SQL of the store can be tested
+// against a real SQLite database without a cgo driver. The arguments are inlined as literals. Every
+// statement commits on its own, the transactions only exist for the interface.
+type cliDriver struct{ bin string }
+
+func (d cliDriver) Open(name string) (driver.Conn, error) { return &cliConn{d.bin, name}, nil }
+
+type cliConn struct{ bin, path string }
+
+func (c *cliConn) Prepare(query string) (driver.Stmt, error) { return &cliStmt{c, query}, nil }
+func (c *cliConn) Close() error                              { return nil }
+func (c *cliConn) Begin() (driver.Tx, error)                 { return c, nil }
+func (c *cliConn) Commit() error                             { return nil }
+func (c *cliConn) Rollback() error                           { return nil }
+
+type cliStmt struct {
+	c     *cliConn
+	query string
+}
+
+func (s *cliStmt) Close() error  { return nil }
+func (s *cliStmt) NumInput() int { return -1 }
+
+func (s *cliStmt) run(args []driver.Value) ([]byte, error) {
+	var (
+		stmt   strings.Builder
+		quoted bool
+	)
+	for _, r := range s.query {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == '?' && !quoted:
+			if len(args) == 0 {
+				return nil, errors.New("missing argument")
+			}
+			stmt.WriteString(literal(args[0]))
+			args = args[1:]
+			continue
+		}
+		stmt.WriteRune(r)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(s.c.bin, "-bail", "-batch", "-json", s.c.path, stmt.String())
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%v: %s", err, stderr.String())
+	}
+
+	return out, nil
+}
+
+func literal(v driver.Value) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	}
+
+	panic(fmt.Sprintf("unsupported argument %T", v))
+}
+
+func (s *cliStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if _, err := s.run(args); err != nil {
+		return nil, err
+	}
+
+	return driver.RowsAffected(0), nil
+}
+
+func (s *cliStmt) Query(args []driver.Value) (driver.Rows, error) {
+	out, err := s.run(args)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := &fakeRows{}
+	if len(bytes.TrimSpace(out)) == 0 {
+		return rows, nil
+	}
+
+	// the objects are read token by token to keep the order of the columns
+	dec := json.NewDecoder(bytes.NewReader(out))
+	dec.UseNumber()
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	for dec.More() {
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+
+		var (
+			cols []string
+			row  []driver.Value
+		)
+		for dec.More() {
+			name, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			var v interface{}
+			if err := dec.Decode(&v); err != nil {
+				return nil, err
+			}
+			if n, ok := v.(json.Number); ok {
+				if i, err := n.Int64(); err == nil {
+					v = i
+				}
+			}
+			cols = append(cols, name.(string))
+			row = append(row, v)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+
+		rows.cols = cols
+		rows.rows = append(rows.rows, row)
+	}
+
+	return rows, nil
+}
+
+func TestSQLite(t *testing.T) {
+	bin, err := exec.LookPath("sqlite3")
+	if err != nil {
+		t.Skip("The sqlite3 command isn't installed")
+	}
+
+	fakeMu.Lock()
+	fakeDrivers++
+	name := "sqlite3-cli" + strconv.Itoa(fakeDrivers)
+	fakeMu.Unlock()
+
+	sql.Register(name, cliDriver{bin})
+	db, err := sql.Open(name, filepath.Join(t.TempDir(), "articles.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewSQLStore(db, SQLite)
+	s.now = func() time.Time { return time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC) }
+	defer s.Close()
+
+	ctx := context.Background()
+	if err := s.Migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// the applied migrations are skipped
+	if err := s.Migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	published := time.Date(2020, 4, 30, 8, 0, 0, 0, time.UTC)
+	articles := []newsapi.Article{
+		{URL: "https://example.com/a", Title: "Ärzte warnen vor Hitze", Source: newsapi.ArticleSource{ID: "example"}, PublishedAt: published},
+		{URL: "https://example.com/b", Title: "ÉLECTIONS: le résultat", Description: "It's 50% done", PublishedAt: published.Add(time.Hour)},
+		{URL: "https://example.com/c", Title: "Storm hits coast", Source: newsapi.ArticleSource{Name: "CNN"}, PublishedAt: published.Add(2 * time.Hour)},
+	}
+	if err := s.Upsert(ctx, articles...); err != nil {
+		t.Fatal(err)
+	}
+
+	// an update keeps first_seen
+	s.now = func() time.Time { return published.Add(48 * time.Hour) }
+	if err := s.Upsert(ctx, newsapi.Article{URL: "https://example.com/c?utm_source=feed", Title: "STORM batters coast", PublishedAt: published.Add(2 * time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := s.Get(ctx, "https://example.com/c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Title != "STORM batters coast" || !a.LastSeen.Equal(published.Add(48*time.Hour)) || !a.FirstSeen.Before(a.LastSeen) {
+		t.Errorf("Expected the article to be updated but got %+v", a)
+	}
+
+	cases := []struct {
+		q        Query
+		expected []string
+	}{
+		{Query{}, []string{"Ärzte warnen vor Hitze", "ÉLECTIONS: le résultat", "STORM batters coast"}},
+		{Query{Text: "ärzte"}, []string{"Ärzte warnen vor Hitze"}},
+		{Query{Text: "élections"}, []string{"ÉLECTIONS: le résultat"}},
+		{Query{Text: "Storm"}, []string{"STORM batters coast"}},
+		{Query{Text: "50%"}, []string{"ÉLECTIONS: le résultat"}},
+		{Query{Text: "5_%"}, nil},
+		{Query{Sources: []string{"example"}}, []string{"Ärzte warnen vor Hitze"}},
+		{Query{From: published.Add(time.Hour), Limit: 1}, []string{"STORM batters coast"}},
+		{Query{Offset: 2}, []string{"Ärzte warnen vor Hitze"}},
+	}
+
+	for _, i := range cases {
+		got, err := s.Query(ctx, i.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var titles []string
+		for j := len(got) - 1; j >= 0; j-- {
+			titles = append(titles, got[j].Title)
+		}
+		if !reflect.DeepEqual(titles, i.expected) {
+			t.Errorf("Expected %v but got %v when case=%+v", i.expected, titles, i.q)
+		}
+	}
+}
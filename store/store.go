@@ -0,0 +1,102 @@
+/*
+Package store keeps articles around after they have been fetched. ArticleStore is implemented by every backend,
+so a tool can start with one backend and switch to another one later. Articles are identified by their
+canonical URL (see newsapi.CanonicalURL), so the same article fetched with different tracking parameters is
+only stored once, and every store remembers when an article has been seen for the first and for the last time.
+
+SQLStore stores the articles in a PostgreSQL or SQLite database. The schema is created and updated by its
+Migrate method, the driver has to be imported by the caller:
+
+	db, err := sql.Open("postgres", "user=user password=password host=localhost port=5432")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s := store.NewSQLStore(db, store.Postgres)
+	if err := s.Migrate(ctx); err != nil {
+		log.Fatal(err)
+	}
+
+	r, err := c.TopHeadlines(ctx, newsapi.TopHeadlinesOpts{Country: "gb"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := s.Upsert(ctx, r.Articles...); err != nil {
+		log.Fatal(err)
+	}
+
+FileStore keeps the articles in append-only files in a directory and doesn't need a database at all, which
+makes it a good fit for small tools:
+
+	s, err := store.OpenFileStore("articles", store.FileOptions{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer s.Close()
+*/
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+// ErrNotFound is returned by ArticleStore.Get if the store doesn't contain the article.
+var ErrNotFound = errors.New("The article hasn't been found in the store")
+
+// ErrClosed is returned by the methods of a store which has already been closed.
+var ErrClosed = errors.New("The store has been closed")
+
+// StoredArticle is an article together with the bookkeeping of the store.
+type StoredArticle struct {
+	newsapi.Article
+	// Key is the canonical URL of the article which identifies it in the store.
+	Key       string    `json:"key"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// Query defines which articles ArticleStore.Query returns. Empty fields don't restrict the result.
+type Query struct {
+	// From and To restrict the PublishedAt field of the articles. From is inclusive, To is exclusive.
+	From, To time.Time
+	// Sources contains source IDs or source names.
+	Sources []string
+	// Text has to be contained in the title, description or content of the articles, regardless of the case.
+	Text string
+	// Limit is the maximum number of articles returned. All articles are returned if it's smaller than 1.
+	Limit  int
+	Offset int
+}
+
+// ArticleStore is implemented by every backend of this package.
+type ArticleStore interface {
+	// Upsert adds the articles to the store. Articles which are already part of the store are updated
+	// and keep their FirstSeen time.
+	Upsert(ctx context.Context, articles ...newsapi.Article) error
+	// Get returns the article with the URL. The URL is canonicalized before it's looked up, so any URL which
+	// has the same canonical URL can be used. ErrNotFound is returned if the store doesn't contain the article.
+	Get(ctx context.Context, url string) (StoredArticle, error)
+	// Query returns the matching articles, newest first.
+	Query(ctx context.Context, q Query) ([]StoredArticle, error)
+	Close() error
+}
+
+// key returns the key an article is stored under. Articles without a URL fall back to their ID so they
+// can still be stored.
+func key(a newsapi.Article) string {
+	if a.URL == "" {
+		return "id:" + a.ID()
+	}
+
+	return newsapi.CanonicalURL(a)
+}
+
+// lookupKey returns the key of the article with the URL.
+func lookupKey(url string) string {
+	return newsapi.DefaultURLRules.Canonical(url)
+}
@@ -0,0 +1,415 @@
+/*
+Package summarize creates extractive summaries of articles. The sentences of a text are ranked with TextRank,
+i.e. by how central they are in a graph whose edges are weighted by the similarity of the sentences, and the
+highest ranked sentences are picked until the length budget is used up.
+
+A summary can be created for a single article, either from the content returned by the API or from the full
+text of the article which can be fetched with the extract package, or for a whole response:
+
+	r, err := c.TopHeadlines(ctx, newsapi.TopHeadlinesOpts{Country: "gb"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	d := summarize.Digest(r.Articles, nil, summarize.Opts{MaxSentences: 5})
+	for _, s := range d.Sentences {
+		fmt.Printf("%s (%s)\n", s.Text, s.Source)
+	}
+*/
+package summarize
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+	"github.com/richarddes/newsapi-golang/keyphrase"
+)
+
+const (
+	// DefaultMaxSentences is the number of sentences of a summary if neither Opts.MaxSentences
+	// nor Opts.MaxChars is set.
+	DefaultMaxSentences = 3
+
+	damping    = 0.85
+	iterations = 50
+	tolerance  = 1e-6
+
+	// sentences of a digest which are more similar than this to an already selected sentence are skipped
+	// since news articles about the same story tend to repeat each other
+	redundancy = 0.5
+)
+
+// Opts defines the length budget of a summary. If both fields are set, sentences are added
+// until one of the limits would be exceeded.
+type Opts struct {
+	MaxSentences int
+	// MaxChars is the maximum length of the summary in characters, not counting the spaces between sentences.
+	MaxChars int
+}
+
+// Sentence represents a sentence of a summary.
+type Sentence struct {
+	Text  string  `json:"text"`
+	Score float64 `json:"score"`
+	// Article is the index of the article the sentence has been taken from.
+	Article int `json:"article"`
+	// Source and URL are the source name and the URL of the article the sentence has been taken from.
+	Source string `json:"source,omitempty"`
+	URL    string `json:"url,omitempty"`
+	// Position is the index of the sentence in the text of its article.
+	Position int `json:"position"`
+}
+
+// Summary represents the selected sentences in the order they should be read.
+type Summary struct {
+	Sentences []Sentence `json:"sentences"`
+}
+
+// Text returns the sentences of the summary joined by spaces.
+func (s Summary) Text() string {
+	texts := make([]string, len(s.Sentences))
+	for i, sent := range s.Sentences {
+		texts[i] = sent.Text
+	}
+
+	return strings.Join(texts, " ")
+}
+
+// Summarize creates a summary of the text. lang is the language of the text as one of the values of
+// the Language option and is used to ignore stopwords when comparing sentences. It's detected if it's empty.
+func Summarize(text, lang string, opts Opts) Summary {
+	if lang == "" {
+		lang, _ = newsapi.DetectLanguage(text)
+	}
+
+	sentences := sentencesOf(text, 0, newsapi.Article{})
+	rank(sentences, keyphrase.Stopwords(lang))
+
+	return selectSentences(sentences, opts, false)
+}
+
+// Article creates a summary of the article. If fullText is empty, the description and the content of the
+// article are summarized. Since the content returned by the API is cut off after ~200 characters, it's
+// recommended to pass the full text of the article, e.g. extracted with the extract package.
+func Article(a newsapi.Article, fullText string, opts Opts) Summary {
+	sentences := sentencesOf(articleText(a, fullText), 0, a)
+	rank(sentences, stopwordsOf(a, fullText))
+
+	return selectSentences(sentences, opts, false)
+}
+
+// Digest creates a single summary of several articles, e.g. of all articles of a TopHeadlinesResp. fullTexts
+// may contain the full text of the article with the same index and can be nil or shorter than the articles.
+// Every sentence of the digest is attributed to the article it has been taken from. The sentences are ordered
+// by their score and sentences which repeat an already selected sentence are skipped.
+func Digest(articles []newsapi.Article, fullTexts []string, opts Opts) Summary {
+	var sentences []*sentence
+
+	for i, a := range articles {
+		var full string
+		if i < len(fullTexts) {
+			full = fullTexts[i]
+		}
+
+		ss := sentencesOf(articleText(a, full), i, a)
+		// every article is compared with its own stopwords since the articles may be written in different languages
+		sw := stopwordsOf(a, full)
+		for _, s := range ss {
+			s.words = words(s.Text, sw)
+		}
+		sentences = append(sentences, ss...)
+	}
+
+	rank(sentences, nil)
+
+	return selectSentences(sentences, opts, true)
+}
+
+type sentence struct {
+	Sentence
+	words map[string]int
+	norm  float64
+}
+
+func articleText(a newsapi.Article, fullText string) string {
+	if fullText != "" {
+		return fullText
+	}
+
+	content := a.ContentText()
+	if a.ContentTruncated() {
+		// drop the sentence which has been cut off unless it's the only one
+		if i := strings.LastIndexAny(content, ".!?。！？"); i >= 0 {
+			_, size := utf8.DecodeRuneInString(content[i:])
+			content = content[:i+size]
+		}
+	}
+
+	return a.Description + "\n\n" + content
+}
+
+func stopwordsOf(a newsapi.Article, fullText string) map[string]bool {
+	lang, _ := newsapi.DetectLanguage(articleText(a, fullText))
+
+	return keyphrase.Stopwords(lang)
+}
+
+// abbreviations which are commonly followed by a period without ending the sentence
+var abbreviations = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true, "st": true, "jr": true, "sr": true,
+	"gen": true, "gov": true, "sen": true, "rep": true, "lt": true, "col": true, "sgt": true, "inc": true,
+	"ltd": true, "co": true, "corp": true, "vs": true, "etc": true, "no": true, "jan": true, "feb": true,
+	"mar": true, "apr": true, "jun": true, "jul": true, "aug": true, "sep": true, "sept": true, "oct": true,
+	"nov": true, "dec": true, "bzw": true, "usw": true, "ca": true, "nr": true, "z.b": true, "u.s": true,
+}
+
+// splitSentences splits the text at blank lines and at sentence terminators followed by whitespace or the end of the text.
+// A period doesn't end the sentence if it follows an abbreviation or a single letter, e.g. in "J. Smith".
+func splitSentences(text string) []string {
+	var (
+		sentences []string
+		start     int
+		runes     = []rune(text)
+	)
+
+	add := func(end int) {
+		s := strings.Join(strings.Fields(string(runes[start:end])), " ")
+		if s != "" {
+			sentences = append(sentences, s)
+		}
+		start = end
+	}
+
+	for i, r := range runes {
+		switch r {
+		case '。', '！', '？':
+			add(i + 1)
+			continue
+		case '.', '!', '?', '\n':
+		default:
+			continue
+		}
+
+		// a blank line always ends a sentence, e.g. after a heading
+		if r == '\n' {
+			if i+1 < len(runes) && runes[i+1] == '\n' {
+				add(i + 1)
+			}
+			continue
+		}
+
+		// the sentence has to be followed by whitespace, a closing quote is part of the sentence
+		end := i + 1
+		for end < len(runes) && strings.ContainsRune("\"”'’)", runes[end]) {
+			end++
+		}
+		if end < len(runes) && !unicode.IsSpace(runes[end]) {
+			continue
+		}
+
+		if r == '.' {
+			word := lastWord(runes[start:i])
+			if abbreviations[strings.ToLower(word)] || utf8.RuneCountInString(word) == 1 && unicode.IsUpper([]rune(word)[0]) {
+				continue
+			}
+		}
+
+		add(end)
+	}
+	add(len(runes))
+
+	return sentences
+}
+
+func lastWord(runes []rune) string {
+	i := len(runes)
+	for i > 0 && !unicode.IsSpace(runes[i-1]) {
+		i--
+	}
+
+	return strings.TrimLeft(string(runes[i:]), "(\"“'‘")
+}
+
+func sentencesOf(text string, article int, a newsapi.Article) []*sentence {
+	var sentences []*sentence
+
+	for i, s := range splitSentences(text) {
+		sentences = append(sentences, &sentence{Sentence: Sentence{
+			Text:     s,
+			Article:  article,
+			Source:   a.Source.Name,
+			URL:      a.URL,
+			Position: i,
+		}})
+	}
+
+	return sentences
+}
+
+// words returns how often every word which isn't a stopword occurs in the text. Chinese characters are
+// counted on their own since there are no spaces between Chinese words.
+func words(text string, stopwords map[string]bool) map[string]int {
+	counts := make(map[string]int)
+
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for _, f := range fields {
+		if unicode.Is(unicode.Han, []rune(f)[0]) {
+			for _, r := range f {
+				if !stopwords[string(r)] {
+					counts[string(r)]++
+				}
+			}
+			continue
+		}
+
+		if !stopwords[f] {
+			counts[f]++
+		}
+	}
+
+	return counts
+}
+
+func similarity(a, b *sentence) float64 {
+	if a.norm == 0 || b.norm == 0 {
+		return 0
+	}
+
+	var dot float64
+	for w, n := range a.words {
+		dot += float64(n * b.words[w])
+	}
+
+	return dot / (a.norm * b.norm)
+}
+
+// rank scores the sentences with TextRank. If stopwords is nil, the words of the sentences
+// have to be set already.
+func rank(sentences []*sentence, stopwords map[string]bool) {
+	for _, s := range sentences {
+		if stopwords != nil || s.words == nil {
+			s.words = words(s.Text, stopwords)
+		}
+
+		var sum float64
+		for _, n := range s.words {
+			sum += float64(n * n)
+		}
+		s.norm = math.Sqrt(sum)
+	}
+
+	n := len(sentences)
+	if n == 0 {
+		return
+	}
+
+	weights := make([][]float64, n)
+	totals := make([]float64, n)
+	for i := range sentences {
+		weights[i] = make([]float64, n)
+		for j := range sentences {
+			if i != j {
+				weights[i][j] = similarity(sentences[i], sentences[j])
+				totals[i] += weights[i][j]
+			}
+		}
+	}
+
+	scores := make([]float64, n)
+	for i := range scores {
+		scores[i] = 1
+	}
+
+	for it := 0; it < iterations; it++ {
+		next := make([]float64, n)
+		var delta float64
+
+		for i := range sentences {
+			var sum float64
+			for j := range sentences {
+				if totals[j] > 0 {
+					sum += weights[j][i] / totals[j] * scores[j]
+				}
+			}
+			next[i] = 1 - damping + damping*sum
+			delta += math.Abs(next[i] - scores[i])
+		}
+
+		scores = next
+		if delta < tolerance {
+			break
+		}
+	}
+
+	for i, s := range sentences {
+		s.Score = scores[i]
+	}
+}
+
+// selectSentences picks the highest scoring sentences within the budget. The sentences of a single text
+// are returned in their original order, those of a digest by their score.
+func selectSentences(sentences []*sentence, opts Opts, digest bool) Summary {
+	maxSentences := opts.MaxSentences
+	if maxSentences < 1 && opts.MaxChars < 1 {
+		maxSentences = DefaultMaxSentences
+	}
+
+	byScore := append([]*sentence(nil), sentences...)
+	sort.SliceStable(byScore, func(i, j int) bool {
+		return byScore[i].Score > byScore[j].Score
+	})
+
+	var (
+		selected []*sentence
+		chars    int
+	)
+
+	for _, s := range byScore {
+		if maxSentences > 0 && len(selected) >= maxSentences {
+			break
+		}
+
+		n := utf8.RuneCountInString(s.Text)
+		if opts.MaxChars > 0 && chars+n > opts.MaxChars {
+			// a shorter sentence might still fit
+			continue
+		}
+
+		if digest && redundant(s, selected) {
+			continue
+		}
+
+		selected = append(selected, s)
+		chars += n
+	}
+
+	if !digest {
+		sort.Slice(selected, func(i, j int) bool {
+			return selected[i].Position < selected[j].Position
+		})
+	}
+
+	summary := Summary{Sentences: make([]Sentence, len(selected))}
+	for i, s := range selected {
+		summary.Sentences[i] = s.Sentence
+	}
+
+	return summary
+}
+
+func redundant(s *sentence, selected []*sentence) bool {
+	for _, other := range selected {
+		if similarity(s, other) > redundancy {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,154 @@
+package summarize
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+const fullText = `The central bank raised its benchmark interest rate by a quarter point on Wednesday. It was the third interest rate increase this year.
+Policymakers said inflation remained too high and that further interest rate increases were possible. Mr. Smith, a member of the committee, voted against the increase.
+The weather was sunny in the capital. Analysts said the increase in the interest rate had been widely expected by markets.
+
+Mortgage rates are expected to follow the central bank rate higher in the coming weeks.`
+
+func TestSplitSentences(t *testing.T) {
+	cases := []struct {
+		text      string
+		sentences []string
+	}{
+		{"One. Two! Three?", []string{"One.", "Two!", "Three?"}},
+		{"Mr. Smith met J. Doe in the U.S. on Monday. They talked.", []string{"Mr. Smith met J. Doe in the U.S. on Monday.", "They talked."}},
+		{"He said: \"It's over.\" Then he left.", []string{"He said: \"It's over.\"", "Then he left."}},
+		{"Version 2.5 was released. It works.", []string{"Version 2.5 was released.", "It works."}},
+		{"Heading\n\nFirst paragraph.", []string{"Heading", "First paragraph."}},
+		{"第一句。第二句！", []string{"第一句。", "第二句！"}},
+		{"", nil},
+	}
+
+	for _, i := range cases {
+		sentences := splitSentences(i.text)
+		if strings.Join(sentences, "|") != strings.Join(i.sentences, "|") {
+			t.Errorf("Expected %q but got %q when case=%q", i.sentences, sentences, i.text)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	s := Summarize(fullText, "en", Opts{MaxSentences: 2})
+	if len(s.Sentences) != 2 {
+		t.Fatalf("Expected 2 sentences but got %d", len(s.Sentences))
+	}
+
+	if s.Sentences[0].Position > s.Sentences[1].Position {
+		t.Error("Expected the sentences to be in their original order")
+	}
+
+	if strings.Contains(s.Text(), "weather") {
+		t.Errorf("Expected the unrelated sentence not to be part of the summary but got %q", s.Text())
+	}
+
+	budget := Summarize(fullText, "", Opts{MaxChars: 100})
+	if n := utf8.RuneCountInString(strings.Join(strings.Fields(budget.Text()), "")); n > 100 {
+		t.Errorf("Expected at most 100 characters but got %d", n)
+	}
+	if len(budget.Sentences) == 0 {
+		t.Error("Expected at least one sentence to fit into the budget")
+	}
+
+	if s := Summarize(fullText, "en", Opts{}); len(s.Sentences) != DefaultMaxSentences {
+		t.Errorf("Expected %d sentences but got %d", DefaultMaxSentences, len(s.Sentences))
+	}
+}
+
+func TestArticle(t *testing.T) {
+	a := newsapi.Article{
+		Description: "The central bank raised interest rates again. Markets had expected the move.",
+		Content:     "The central bank raised interest rates for the third time this year. Policymakers voted… [+2300 chars]",
+	}
+
+	s := Article(a, "", Opts{MaxSentences: 10})
+	for _, sent := range s.Sentences {
+		if strings.Contains(sent.Text, "Policymakers voted") {
+			t.Errorf("Expected the cut off sentence to be dropped but got %q", sent.Text)
+		}
+	}
+
+	if len(s.Sentences) != 3 {
+		t.Errorf("Expected 3 sentences but got %d", len(s.Sentences))
+	}
+
+	if full := Article(a, fullText, Opts{MaxSentences: 10}); len(full.Sentences) != 7 {
+		t.Errorf("Expected the full text to be summarized but got %d sentences", len(full.Sentences))
+	}
+}
+
+func TestArticleText(t *testing.T) {
+	cases := []struct {
+		content  string
+		expected string
+	}{
+		{"The bank raised rates. Policymakers voted… [+2300 chars]", "The bank raised rates."},
+		{"央行再次加息。市场预期… [+2300 chars]", "央行再次加息。"},
+		{"Policymakers voted to raise… [+2300 chars]", "Policymakers voted to raise"},
+		{"The bank raised rates. Policymakers voted", "The bank raised rates. Policymakers voted"},
+	}
+
+	for _, i := range cases {
+		got := articleText(newsapi.Article{Content: i.content}, "")
+		if got != "\n\n"+i.expected || !utf8.ValidString(got) {
+			t.Errorf("Expected %q but got %q when case=%v", i.expected, got, i.content)
+		}
+	}
+}
+
+func TestDigest(t *testing.T) {
+	articles := []newsapi.Article{
+		{
+			Source:      newsapi.ArticleSource{Name: "Reuters"},
+			URL:         "https://reuters.com/a",
+			Description: "The central bank raised interest rates on Wednesday.",
+		},
+		{
+			Source:      newsapi.ArticleSource{Name: "BBC News"},
+			URL:         "https://bbc.co.uk/a",
+			Description: "The central bank raised interest rates on Wednesday. Mortgage costs are expected to rise.",
+		},
+		{
+			Source:      newsapi.ArticleSource{Name: "The Guardian"},
+			URL:         "https://theguardian.com/a",
+			Description: "Interest rates went up again as the central bank fights inflation.",
+		},
+	}
+
+	d := Digest(articles, nil, Opts{MaxSentences: 3})
+
+	var repeated int
+	for _, s := range d.Sentences {
+		if s.Text == "The central bank raised interest rates on Wednesday." {
+			repeated++
+		}
+
+		a := articles[s.Article]
+		if s.Source != a.Source.Name || s.URL != a.URL {
+			t.Errorf("Expected the sentence %q to be attributed to article %d", s.Text, s.Article)
+		}
+	}
+
+	if repeated > 1 {
+		t.Error("Expected repeated sentences to only be selected once")
+	}
+
+	for i := 1; i < len(d.Sentences); i++ {
+		if d.Sentences[i].Score > d.Sentences[i-1].Score {
+			t.Error("Expected the sentences of a digest to be sorted by their score")
+		}
+	}
+
+	full := Digest(articles[:1], []string{fullText}, Opts{MaxSentences: 1})
+	if len(full.Sentences) != 1 || !strings.Contains(fullText, full.Sentences[0].Text) {
+		t.Errorf("Expected the full text to be used but got %v", full.Sentences)
+	}
+}
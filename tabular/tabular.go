@@ -0,0 +1,388 @@
+/*
+Package tabular writes articles and sources as CSV or TSV, e.g. to open the results of a query in a
+spreadsheet. The columns can be selected and reordered by name, and custom columns can be computed
+from an article:
+
+	cols, err := tabular.ArticleColumns("publishedAt", "source.name", "title", "host", "wordCount")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := tabular.WriteArticles(os.Stdout, r.Articles, cols, tabular.Options{}); err != nil {
+		log.Fatal(err)
+	}
+
+Large archives can be written in chunks with an ArticleWriter, so they never have to be held in memory
+at once. Fields are quoted as described in RFC 4180, and fields which a spreadsheet would run as a
+formula are prefixed with a single quote unless Options.AllowFormulas is set.
+*/
+package tabular
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+// Options configures how a table is written. The zero value writes CSV with a header.
+type Options struct {
+	// Comma is the field delimiter. It's ',' if it's 0, '\t' writes TSV.
+	Comma rune
+	// UseCRLF ends the rows with \r\n as RFC 4180 demands instead of \n.
+	UseCRLF bool
+	// NoHeader leaves out the row with the headers of the columns.
+	NoHeader bool
+	// AllowFormulas disables the protection against formula injection. Without it, a field starting with
+	// =, +, -, @, a tab or a carriage return is prefixed with a single quote so spreadsheet programs
+	// like Excel show it as text instead of running it. Numbers aren't changed.
+	AllowFormulas bool
+}
+
+// TSV are the options to write tab-separated values.
+var TSV = Options{Comma: '\t'}
+
+// ArticleColumn is a column of a table of articles.
+type ArticleColumn struct {
+	Header string
+	Value  func(a newsapi.Article) string
+}
+
+// SourceColumn is a column of a table of sources.
+type SourceColumn struct {
+	Header string
+	Value  func(s newsapi.Source) string
+}
+
+var articleColumns = map[string]func(a newsapi.Article) string{
+	"source.id":        func(a newsapi.Article) string { return a.Source.ID },
+	"source.name":      func(a newsapi.Article) string { return a.Source.Name },
+	"author":           func(a newsapi.Article) string { return a.Author },
+	"title":            func(a newsapi.Article) string { return a.Title },
+	"description":      func(a newsapi.Article) string { return a.Description },
+	"url":              func(a newsapi.Article) string { return a.URL },
+	"urlToImage":       func(a newsapi.Article) string { return a.URLToImage },
+	"publishedAt":      formatTime(time.RFC3339, nil),
+	"content":          func(a newsapi.Article) string { return a.Content },
+	"id":               func(a newsapi.Article) string { return a.ID() },
+	"canonicalURL":     newsapi.CanonicalURL,
+	"host":             func(a newsapi.Article) string { return a.Host() },
+	"wordCount":        func(a newsapi.Article) string { return strconv.Itoa(WordCount(a)) },
+	"contentTruncated": func(a newsapi.Article) string { return strconv.FormatBool(a.ContentTruncated()) },
+	"remainingChars":   func(a newsapi.Article) string { return strconv.Itoa(a.RemainingChars()) },
+	"authors": func(a newsapi.Article) string {
+		var names []string
+		for _, author := range a.Authors() {
+			names = append(names, author.Name)
+		}
+		return strings.Join(names, "; ")
+	},
+}
+
+// languageColumns are computed from the detected language of the article. The language is detected once
+// per article for all of them.
+var languageColumns = map[string]func(lang string, confidence float64) string{
+	"detectedLanguage":   func(lang string, confidence float64) string { return lang },
+	"languageConfidence": func(lang string, confidence float64) string { return strconv.FormatFloat(confidence, 'f', -1, 64) },
+}
+
+var detectLanguage = newsapi.DetectArticleLanguage
+
+// languageCache remembers the language of the last article, so the language columns of a row share
+// a single detection.
+type languageCache struct {
+	mu         sync.Mutex
+	article    newsapi.Article
+	lang       string
+	confidence float64
+	detected   bool
+}
+
+func (c *languageCache) detect(a newsapi.Article) (string, float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.detected || c.article != a {
+		c.article, c.detected = a, true
+		c.lang, c.confidence = detectLanguage(a)
+	}
+
+	return c.lang, c.confidence
+}
+
+var sourceColumns = map[string]func(s newsapi.Source) string{
+	"id":          func(s newsapi.Source) string { return s.ID },
+	"name":        func(s newsapi.Source) string { return s.Name },
+	"description": func(s newsapi.Source) string { return s.Description },
+	"url":         func(s newsapi.Source) string { return s.URL },
+	"category":    func(s newsapi.Source) string { return s.Category },
+	"language":    func(s newsapi.Source) string { return s.Language },
+	"country":     func(s newsapi.Source) string { return s.Country },
+}
+
+// DefaultArticleColumns are the columns used if no article columns are given.
+var DefaultArticleColumns = []string{"publishedAt", "source.name", "author", "title", "description", "url"}
+
+// DefaultSourceColumns are the columns used if no source columns are given.
+var DefaultSourceColumns = []string{"id", "name", "description", "url", "category", "language", "country"}
+
+// ArticleColumns returns the built-in columns with the names. The headers of the columns are their names.
+// The fields of an article are named like their JSON fields, e.g. "title" or "source.name". The computed
+// columns are "id", "canonicalURL", "host", "wordCount", "contentTruncated", "remainingChars",
+// "detectedLanguage" and "languageConfidence", which share one newsapi.DetectArticleLanguage call per row,
+// and "authors", which contains the names of the authors parsed with Article.Authors. "publishedAt" is
+// formatted as RFC 3339, PublishedAtColumn creates a column with a different format.
+func ArticleColumns(names ...string) ([]ArticleColumn, error) {
+	var (
+		cols  = make([]ArticleColumn, len(names))
+		cache *languageCache
+	)
+
+	for i, name := range names {
+		if format, ok := languageColumns[name]; ok {
+			if cache == nil {
+				cache = &languageCache{}
+			}
+			c := cache
+			cols[i] = ArticleColumn{Header: name, Value: func(a newsapi.Article) string { return format(c.detect(a)) }}
+			continue
+		}
+
+		value, ok := articleColumns[name]
+		if !ok {
+			return nil, fmt.Errorf("The article column %s doesn't exist", name)
+		}
+		cols[i] = ArticleColumn{Header: name, Value: value}
+	}
+
+	return cols, nil
+}
+
+// SourceColumns returns the built-in columns with the names, which are the names of the JSON fields of a source.
+func SourceColumns(names ...string) ([]SourceColumn, error) {
+	cols := make([]SourceColumn, len(names))
+	for i, name := range names {
+		value, ok := sourceColumns[name]
+		if !ok {
+			return nil, fmt.Errorf("The source column %s doesn't exist", name)
+		}
+		cols[i] = SourceColumn{Header: name, Value: value}
+	}
+
+	return cols, nil
+}
+
+// PublishedAtColumn returns a column which contains the publishing time of the article formatted with
+// layout in the location loc. The time isn't converted if loc is nil. Articles without a publishing time
+// have an empty field.
+func PublishedAtColumn(header, layout string, loc *time.Location) ArticleColumn {
+	return ArticleColumn{Header: header, Value: formatTime(layout, loc)}
+}
+
+func formatTime(layout string, loc *time.Location) func(a newsapi.Article) string {
+	return func(a newsapi.Article) string {
+		if a.PublishedAt.IsZero() {
+			return ""
+		}
+
+		t := a.PublishedAt
+		if loc != nil {
+			t = t.In(loc)
+		}
+		return t.Format(layout)
+	}
+}
+
+// WordCount returns the number of words of the title, description and content of the article. The
+// content returned by the API is cut off, so the count is a lower bound for long articles.
+func WordCount(a newsapi.Article) int {
+	return len(strings.Fields(a.Title)) + len(strings.Fields(a.Description)) + len(strings.Fields(a.ContentText()))
+}
+
+// table writes rows with the options.
+type table struct {
+	w       *csv.Writer
+	opts    Options
+	headers []string
+	started bool
+}
+
+func newTable(w io.Writer, headers []string, opts Options) *table {
+	cw := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		cw.Comma = opts.Comma
+	}
+	cw.UseCRLF = opts.UseCRLF
+
+	return &table{w: cw, opts: opts, headers: headers}
+}
+
+// header writes the header before the first row.
+func (t *table) header() error {
+	if t.started || t.opts.NoHeader {
+		t.started = true
+		return nil
+	}
+	t.started = true
+
+	return t.w.Write(t.escape(t.headers))
+}
+
+func (t *table) write(row []string) error {
+	if err := t.header(); err != nil {
+		return err
+	}
+
+	return t.w.Write(t.escape(row))
+}
+
+func (t *table) escape(row []string) []string {
+	if t.opts.AllowFormulas {
+		return row
+	}
+
+	for i, f := range row {
+		row[i] = escapeFormula(f)
+	}
+
+	return row
+}
+
+// flush writes the header if no row has been written yet and flushes the writer.
+func (t *table) flush() error {
+	if err := t.header(); err != nil {
+		return err
+	}
+
+	t.w.Flush()
+	return t.w.Error()
+}
+
+// escapeFormula prefixes a field which a spreadsheet would treat as a formula with a single quote.
+// Spreadsheets skip leading spaces, so they're skipped as well.
+func escapeFormula(f string) string {
+	trimmed := strings.TrimLeft(f, " ")
+	if trimmed == "" || !strings.ContainsAny(trimmed[:1], "=+-@\t\r") {
+		return f
+	}
+
+	// negative numbers are safe
+	if _, err := strconv.ParseFloat(trimmed, 64); err == nil {
+		return f
+	}
+
+	return "'" + f
+}
+
+// ArticleWriter writes articles as rows of a table. Rows are buffered, so Flush has to be called after
+// the last article has been written.
+type ArticleWriter struct {
+	t    *table
+	cols []ArticleColumn
+}
+
+// NewArticleWriter returns a writer which writes the columns of articles to w. The columns in
+// DefaultArticleColumns are used if cols is empty. The header is written together with the first row.
+func NewArticleWriter(w io.Writer, cols []ArticleColumn, opts Options) *ArticleWriter {
+	if len(cols) == 0 {
+		cols, _ = ArticleColumns(DefaultArticleColumns...)
+	}
+
+	headers := make([]string, len(cols))
+	for i, c := range cols {
+		headers[i] = c.Header
+	}
+
+	return &ArticleWriter{t: newTable(w, headers, opts), cols: cols}
+}
+
+// Write writes a row for every article.
+func (w *ArticleWriter) Write(articles ...newsapi.Article) error {
+	for _, a := range articles {
+		row := make([]string, len(w.cols))
+		for i, c := range w.cols {
+			row[i] = c.Value(a)
+		}
+
+		if err := w.t.write(row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Flush writes the buffered rows to the underlying writer. The header is written as well if no
+// article has been written, so an empty table still has its columns.
+func (w *ArticleWriter) Flush() error {
+	return w.t.flush()
+}
+
+// WriteArticles writes the articles to w. The columns in DefaultArticleColumns are used if cols is empty.
+func WriteArticles(w io.Writer, articles []newsapi.Article, cols []ArticleColumn, opts Options) error {
+	aw := NewArticleWriter(w, cols, opts)
+	if err := aw.Write(articles...); err != nil {
+		return err
+	}
+
+	return aw.Flush()
+}
+
+// SourceWriter writes sources as rows of a table. Rows are buffered, so Flush has to be called after
+// the last source has been written.
+type SourceWriter struct {
+	t    *table
+	cols []SourceColumn
+}
+
+// NewSourceWriter returns a writer which writes the columns of sources to w. The columns in
+// DefaultSourceColumns are used if cols is empty.
+func NewSourceWriter(w io.Writer, cols []SourceColumn, opts Options) *SourceWriter {
+	if len(cols) == 0 {
+		cols, _ = SourceColumns(DefaultSourceColumns...)
+	}
+
+	headers := make([]string, len(cols))
+	for i, c := range cols {
+		headers[i] = c.Header
+	}
+
+	return &SourceWriter{t: newTable(w, headers, opts), cols: cols}
+}
+
+// Write writes a row for every source.
+func (w *SourceWriter) Write(sources ...newsapi.Source) error {
+	for _, s := range sources {
+		row := make([]string, len(w.cols))
+		for i, c := range w.cols {
+			row[i] = c.Value(s)
+		}
+
+		if err := w.t.write(row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Flush writes the buffered rows to the underlying writer.
+func (w *SourceWriter) Flush() error {
+	return w.t.flush()
+}
+
+// WriteSources writes the sources, e.g. those of a SourcesResp, to w. The columns in DefaultSourceColumns
+// are used if cols is empty.
+func WriteSources(w io.Writer, sources []newsapi.Source, cols []SourceColumn, opts Options) error {
+	sw := NewSourceWriter(w, cols, opts)
+	if err := sw.Write(sources...); err != nil {
+		return err
+	}
+
+	return sw.Flush()
+}
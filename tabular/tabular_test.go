@@ -0,0 +1,199 @@
+package tabular
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	newsapi "github.com/richarddes/newsapi-golang"
+)
+
+var articles = []newsapi.Article{
+	{
+		Source:      newsapi.ArticleSource{ID: "example", Name: "Example News"},
+		Author:      "Jane Doe",
+		Title:       `Markets rally, "bonds" fall`,
+		Description: "Stocks rose\non Friday.",
+		URL:         "https://www.example.com/markets",
+		PublishedAt: time.Date(2020, 5, 1, 8, 30, 0, 0, time.UTC),
+		Content:     "Stocks rose on Friday after… [+1200 chars]",
+	},
+	{
+		Title:  "=HYPERLINK(\"https://evil.example\", \"click\")",
+		Author: "@handle",
+	},
+}
+
+func TestWriteArticles(t *testing.T) {
+	cols, err := ArticleColumns("source.name", "title", "description", "host", "wordCount", "author")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cols = append([]ArticleColumn{PublishedAtColumn("date", "2006-01-02 15:04", time.FixedZone("CEST", 2*60*60))}, cols...)
+
+	var buf bytes.Buffer
+	if err := WriteArticles(&buf, articles, cols, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Expected valid CSV but got %v", err)
+	}
+
+	expected := [][]string{
+		{"date", "source.name", "title", "description", "host", "wordCount", "author"},
+		{"2020-05-01 10:30", "Example News", `Markets rally, "bonds" fall`, "Stocks rose\non Friday.", "www.example.com", "13", "Jane Doe"},
+		{"", "", "'=HYPERLINK(\"https://evil.example\", \"click\")", "", "", "2", "'@handle"},
+	}
+
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expected %q but got %q", expected, rows)
+	}
+
+	if _, err := ArticleColumns("title", "nope"); err == nil {
+		t.Error("Expected an error for an unknown column")
+	}
+}
+
+func TestOptions(t *testing.T) {
+	cols, _ := ArticleColumns("title")
+
+	cases := []struct {
+		opts     Options
+		expected string
+	}{
+		{Options{}, "title\n'=1+1\n"},
+		{Options{NoHeader: true, UseCRLF: true}, "'=1+1\r\n"},
+		{Options{AllowFormulas: true}, "title\n=1+1\n"},
+		{TSV, "title\n'=1+1\n"},
+	}
+
+	for _, i := range cases {
+		var buf bytes.Buffer
+		if err := WriteArticles(&buf, []newsapi.Article{{Title: "=1+1"}}, cols, i.opts); err != nil {
+			t.Fatal(err)
+		}
+
+		if buf.String() != i.expected {
+			t.Errorf("Expected %q but got %q when case=%+v", i.expected, buf.String(), i.opts)
+		}
+	}
+
+	var buf bytes.Buffer
+	cols, _ = ArticleColumns("title", "author")
+	WriteArticles(&buf, []newsapi.Article{{Title: "a\tb", Author: "-5"}}, cols, TSV)
+	if expected := "title\tauthor\n\"a\tb\"\t-5\n"; buf.String() != expected {
+		t.Errorf("Expected %q but got %q", expected, buf.String())
+	}
+}
+
+func TestEscapeFormula(t *testing.T) {
+	cases := []struct {
+		field, expected string
+	}{
+		{"", ""},
+		{"Title", "Title"},
+		{"=1+1", "'=1+1"},
+		{"  =HYPERLINK(\"https://evil.example\")", "'  =HYPERLINK(\"https://evil.example\")"},
+		{" @SUM(A1)", "' @SUM(A1)"},
+		{"   ", "   "},
+		{"-5", "-5"},
+		{" -5", " -5"},
+		{"\t=1", "'\t=1"},
+	}
+
+	for _, i := range cases {
+		if got := escapeFormula(i.field); got != i.expected {
+			t.Errorf("Expected %q but got %q when case=%q", i.expected, got, i.field)
+		}
+	}
+}
+
+func TestArticleWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewArticleWriter(&buf, nil, Options{})
+
+	// an empty table still has a header
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if expected := strings.Join(DefaultArticleColumns, ",") + "\n"; buf.String() != expected {
+		t.Errorf("Expected %q but got %q", expected, buf.String())
+	}
+
+	for i := 0; i < 1000; i++ {
+		if err := w.Write(articles[0]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1001 {
+		t.Errorf("Expected 1001 rows but got %d", len(rows))
+	}
+}
+
+func TestLanguageColumns(t *testing.T) {
+	var calls int
+	detectLanguage = func(a newsapi.Article) (string, float64) {
+		calls++
+		return newsapi.DetectArticleLanguage(a)
+	}
+	defer func() { detectLanguage = newsapi.DetectArticleLanguage }()
+
+	mixed := []newsapi.Article{
+		{Title: "The government announced new measures on Tuesday"},
+		{Title: "上海证券交易所周三收盘时，主要股指小幅上涨，银行股和能源股领涨。"},
+	}
+
+	cols, _ := ArticleColumns("detectedLanguage", "title", "languageConfidence")
+
+	var buf bytes.Buffer
+	if err := WriteArticles(&buf, mixed, cols, Options{NoHeader: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != len(mixed) {
+		t.Errorf("Expected the language to be detected once per row but got %d detections", calls)
+	}
+
+	rows := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(rows) != 2 || !strings.HasPrefix(rows[0], "en,") || !strings.HasPrefix(rows[1], "zh,") || !strings.HasSuffix(rows[1], ",1") {
+		t.Errorf("Expected the detected languages but got %q", rows)
+	}
+}
+
+func TestWriteSources(t *testing.T) {
+	sources := []newsapi.Source{{ID: "bbc-news", Name: "BBC News", URL: "https://www.bbc.co.uk/news", Category: "general", Language: "en", Country: "gb"}}
+
+	cols, err := SourceColumns("country", "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSources(&buf, sources, cols, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "country,name\ngb,BBC News\n"; buf.String() != expected {
+		t.Errorf("Expected %q but got %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteSources(&buf, sources, nil, Options{NoHeader: true}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "bbc-news,BBC News,,https://www.bbc.co.uk/news,general,en,gb\n"; buf.String() != expected {
+		t.Errorf("Expected %q but got %q", expected, buf.String())
+	}
+}
@@ -52,12 +52,21 @@ func (c *Client) TopHeadlines(ctx context.Context, opts TopHeadlinesOpts) (TopHe
 		}
 	}
 
-	body, err := fetchGetRoute(ctx, "https://newsapi.org/v2/top-headlines", c.APIKey, opts)
+	body, err := fetchGetRoute(ctx, "https://newsapi.org/v2/top-headlines", c.APIKey, opts, c.Recorder)
 	if err != nil {
 		return TopHeadlinesResp{}, err
 	}
 
 	// we need this kind of type conversation because fetchGetRoute can only be parsed to the articleResp type
 	// otherwise there will be an error
-	return TopHeadlinesResp(body.(articleResp)), nil
+	r := TopHeadlinesResp(body.(articleResp))
+	if c.Normalizer != nil {
+		c.Normalizer.NormalizeAll(r.Articles)
+	}
+
+	if err := c.enrich(ctx, (*articleResp)(&r)); err != nil {
+		return TopHeadlinesResp{}, err
+	}
+
+	return r, nil
 }